@@ -124,6 +124,29 @@ func TestPreCommitInstall(t *testing.T) {
 							Stages:                  []string{"pre-commit"},
 							Args:                    []string{"hooks", "pre-commit", "secrets-scan"},
 							Language:                "system",
+							PassFilenames:           true,
+							RequireSerial:           true,
+							MinimumPreCommitVersion: "3.2.0",
+						},
+						{
+							ID:                      "cx-secret-detection-commit-msg",
+							Name:                    "Cx Secret Detection (commit-msg)",
+							Entry:                   "cx",
+							Description:             "Run Cx CLI secret detection on the commit message",
+							Stages:                  []string{"commit-msg"},
+							Args:                    []string{"hooks", "pre-commit", "secrets-scan", "--commit-msg"},
+							Language:                "system",
+							PassFilenames:           true,
+							MinimumPreCommitVersion: "3.2.0",
+						},
+						{
+							ID:                      "cx-secret-detection-pre-push",
+							Name:                    "Cx Secret Detection (pre-push)",
+							Entry:                   "cx",
+							Description:             "Run Cx CLI secret detection on the commits being pushed",
+							Stages:                  []string{"pre-push"},
+							Args:                    []string{"hooks", "pre-push", "secrets-scan"},
+							Language:                "system",
 							PassFilenames:           false,
 							MinimumPreCommitVersion: "3.2.0",
 						},
@@ -248,6 +271,7 @@ func TestPreCommitUninstall(t *testing.T) {
 							Args:                    []string{"pre-commit", "scan"},
 							Language:                "system",
 							PassFilenames:           true,
+							RequireSerial:           true,
 							MinimumPreCommitVersion: "3.2.0",
 						},
 					},
@@ -389,6 +413,7 @@ func TestPreCommitUpdate(t *testing.T) {
 							Args:                    []string{"pre-commit", "dummy"},
 							Language:                "system",
 							PassFilenames:           true,
+							RequireSerial:           true,
 							MinimumPreCommitVersion: "0.0.0",
 						},
 					},
@@ -0,0 +1,230 @@
+package baseline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/fingerprint"
+	"github.com/Checkmarx/secret-detection/pkg/ignore"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+)
+
+const (
+	// FileName is the default baseline file, at the root of the repository.
+	FileName = ".checkmarx_baseline.json"
+	version  = 1
+)
+
+// Finding identifies a secret by its rule, file and value fingerprint. Unlike result IDs, which are
+// tied to commit SHAs in the pre-receive hook, these are the same whichever hook finds the secret.
+type Finding struct {
+	RuleID      string `json:"rule_id"`
+	Path        string `json:"path"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// FindingOf returns the baseline finding of a scan result, with the fingerprint of its value keyed by
+// the salt of the baseline.
+func FindingOf(secret *secrets.Secret, salt string) Finding {
+	return Finding{
+		RuleID:      secret.RuleID,
+		Path:        ignore.SourceFile(secret.Source),
+		Fingerprint: fingerprint.Of(salt, secret.Value),
+	}
+}
+
+// key normalizes the finding for comparisons: rule IDs are case-insensitive, as in the ignore file.
+func (f Finding) key() Finding {
+	f.RuleID = strings.ToLower(f.RuleID)
+	f.Path = strings.TrimPrefix(strings.ReplaceAll(f.Path, `\`, "/"), "./")
+	return f
+}
+
+// String describes the finding on one line.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s in %s (%s)", f.RuleID, f.Path, f.Fingerprint)
+}
+
+// Baseline is the set of findings accepted when the hooks were introduced. The hooks fail only on
+// findings that are not in the baseline.
+type Baseline struct {
+	Version  int       `json:"version"`
+	Findings []Finding `json:"findings"`
+
+	index map[Finding]bool
+	// salts are the distinct salts of the fingerprints, in order of appearance.
+	salts []string
+}
+
+// New returns a baseline of the findings, sorted and without duplicates.
+func New(findings []Finding) *Baseline {
+	b := &Baseline{Version: version, Findings: []Finding{}, index: map[Finding]bool{}}
+	for _, finding := range findings {
+		if !b.index[finding.key()] {
+			b.index[finding.key()] = true
+			b.Findings = append(b.Findings, finding)
+		}
+		if salt, ok := fingerprint.Salt(finding.Fingerprint); ok && !slices.Contains(b.salts, salt) {
+			b.salts = append(b.salts, salt)
+		}
+	}
+	sort.Slice(b.Findings, func(i, j int) bool {
+		fi, fj := b.Findings[i], b.Findings[j]
+		if fi.Path != fj.Path {
+			return fi.Path < fj.Path
+		}
+		if fi.RuleID != fj.RuleID {
+			return fi.RuleID < fj.RuleID
+		}
+		return fi.Fingerprint < fj.Fingerprint
+	})
+	return b
+}
+
+// FromReport returns the baseline of the findings of a scan, fingerprinted with the salt. The salt of
+// the repository is the one of its existing baseline; see Salt.
+func FromReport(report *reporting.Report, salt string) *Baseline {
+	var findings []Finding
+	for _, results := range report.Results {
+		for _, secret := range results {
+			findings = append(findings, FindingOf(secret, salt))
+		}
+	}
+	return New(findings)
+}
+
+// Salt returns the salt of the fingerprints of the baseline, or a new one when it has none, so that a
+// baseline written over this one can be compared with it.
+func (b *Baseline) Salt() string {
+	if b == nil || len(b.salts) == 0 {
+		return fingerprint.NewSalt()
+	}
+	return b.salts[0]
+}
+
+// Load reads the baseline file. A missing file results in a nil baseline, which contains nothing.
+func Load(filePath string) (*Baseline, error) {
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline %s: %w", filePath, err)
+	}
+	var file Baseline
+	if err = json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("baseline %s is malformed: %w", filePath, err)
+	}
+	if file.Version != version {
+		return nil, fmt.Errorf("baseline %s has unsupported version %d", filePath, file.Version)
+	}
+	for i, finding := range file.Findings {
+		if finding.RuleID == "" || finding.Path == "" {
+			return nil, fmt.Errorf("baseline %s is malformed: finding %d needs a rule_id and a path", filePath, i+1)
+		}
+		if err = fingerprint.Validate(finding.Fingerprint); err != nil {
+			return nil, fmt.Errorf("baseline %s is malformed: finding %d: %w", filePath, i+1, err)
+		}
+	}
+	return New(file.Findings), nil
+}
+
+// Marshal returns the content of the baseline file.
+func (b *Baseline) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal baseline: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Write saves the baseline to the file.
+func (b *Baseline) Write(filePath string) error {
+	data, err := b.Marshal()
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write baseline %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// Len returns the number of findings in the baseline.
+func (b *Baseline) Len() int {
+	if b == nil {
+		return 0
+	}
+	return len(b.Findings)
+}
+
+// Contains reports whether the finding is in the baseline.
+func (b *Baseline) Contains(finding Finding) bool {
+	return b != nil && b.index[finding.key()]
+}
+
+// containsSecret reports whether the scan result is in the baseline, with the fingerprint of its value
+// keyed by any salt of the baseline.
+func (b *Baseline) containsSecret(secret *secrets.Secret) bool {
+	for _, salt := range b.salts {
+		if b.Contains(FindingOf(secret, salt)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply removes the findings of the baseline from the report and returns how many were removed.
+func (b *Baseline) Apply(report *reporting.Report) int {
+	if b.Len() == 0 {
+		return 0
+	}
+	removed := 0
+	for id, list := range report.Results {
+		kept := list[:0]
+		for _, secret := range list {
+			if b.containsSecret(secret) {
+				removed++
+				continue
+			}
+			kept = append(kept, secret)
+		}
+		if len(kept) == 0 {
+			delete(report.Results, id)
+			continue
+		}
+		report.Results[id] = kept
+	}
+	report.TotalSecretsFound -= removed
+	return removed
+}
+
+// Diff returns the findings of next that are not in previous, and those of previous that are no
+// longer in next. Findings are compared by fingerprint, so the baselines must share their salt, as a
+// baseline and the one written over it do.
+func Diff(previous, next *Baseline) (added, resolved []Finding, err error) {
+	if previous.Len() > 0 && next.Len() > 0 && previous.Salt() != next.Salt() {
+		return nil, nil, errors.New("the baselines fingerprint their findings with different salts and cannot be compared")
+	}
+	if next != nil {
+		for _, finding := range next.Findings {
+			if !previous.Contains(finding) {
+				added = append(added, finding)
+			}
+		}
+	}
+	if previous != nil {
+		for _, finding := range previous.Findings {
+			if !next.Contains(finding) {
+				resolved = append(resolved, finding)
+			}
+		}
+	}
+	return added, resolved, nil
+}
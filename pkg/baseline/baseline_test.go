@@ -0,0 +1,127 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/fingerprint"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindingOf(t *testing.T) {
+	secret := &secrets.Secret{ID: "abc", RuleID: "github-pat", Source: "Added:0123abc:config/app.yaml", Value: "ghp_token"}
+	assert.Equal(t, Finding{RuleID: "github-pat", Path: "config/app.yaml", Fingerprint: fingerprint.Of("salt", "ghp_token")}, FindingOf(secret, "salt"))
+
+	secret.Source = "config/app.yaml"
+	assert.Equal(t, "config/app.yaml", FindingOf(secret, "salt").Path)
+}
+
+func TestNew(t *testing.T) {
+	b := New([]Finding{
+		{RuleID: "jwt", Path: "src/b.go", Fingerprint: "sha256:2"},
+		{RuleID: "github-pat", Path: "src/a.go", Fingerprint: "sha256:1"},
+		{RuleID: "GitHub-PAT", Path: "./src/a.go", Fingerprint: "sha256:1"},
+	})
+	assert.Equal(t, []Finding{
+		{RuleID: "github-pat", Path: "src/a.go", Fingerprint: "sha256:1"},
+		{RuleID: "jwt", Path: "src/b.go", Fingerprint: "sha256:2"},
+	}, b.Findings)
+	assert.True(t, b.Contains(Finding{RuleID: "JWT", Path: "src/b.go", Fingerprint: "sha256:2"}))
+	assert.False(t, b.Contains(Finding{RuleID: "jwt", Path: "src/a.go", Fingerprint: "sha256:2"}))
+
+	var missing *Baseline
+	assert.False(t, missing.Contains(b.Findings[0]))
+	assert.Zero(t, missing.Len())
+}
+
+func TestApply(t *testing.T) {
+	report := &reporting.Report{
+		TotalSecretsFound: 3,
+		Results: map[string][]*secrets.Secret{
+			"1": {{ID: "1", RuleID: "github-pat", Source: "Added:aaa:src/main.go", Value: "old"}},
+			"2": {{ID: "2", RuleID: "github-pat", Source: "Added:bbb:src/main.go", Value: "new"}},
+			"3": {{ID: "3", RuleID: "github-pat", Source: "src/other.go", Value: "old"}},
+		},
+	}
+	b := New([]Finding{
+		{RuleID: "github-pat", Path: "src/main.go", Fingerprint: fingerprint.Of("salt", "old")},
+		{RuleID: "github-pat", Path: "src/other.go", Fingerprint: fingerprint.Of("pepper", "new")},
+	})
+
+	assert.Equal(t, 1, b.Apply(report))
+	assert.Equal(t, 2, report.TotalSecretsFound)
+	assert.NotContains(t, report.Results, "1")
+	assert.Contains(t, report.Results, "2", "a new value in the same file is not in the baseline")
+	assert.Contains(t, report.Results, "3", "the same value in another file is not in the baseline")
+
+	report.Results["4"] = []*secrets.Secret{{ID: "4", RuleID: "github-pat", Source: "src/other.go", Value: "new"}}
+	report.TotalSecretsFound++
+	assert.Equal(t, 1, b.Apply(report), "each fingerprint is keyed by its own salt")
+	assert.NotContains(t, report.Results, "4")
+}
+
+func TestLoadAndWrite(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, FileName)
+
+	b, err := Load(filePath)
+	assert.NoError(t, err)
+	assert.Nil(t, b)
+
+	report := &reporting.Report{Results: map[string][]*secrets.Secret{
+		"1": {{ID: "1", RuleID: "jwt", Source: "a.json", Value: "eyJtoken"}},
+	}}
+	assert.NoError(t, FromReport(report, "salt").Write(filePath))
+	b, err = Load(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, []Finding{{RuleID: "jwt", Path: "a.json", Fingerprint: fingerprint.Of("salt", "eyJtoken")}}, b.Findings)
+	assert.Equal(t, "salt", b.Salt())
+	assert.NotContains(t, readFile(t, filePath), "eyJtoken")
+
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"malformed", "{", "malformed"},
+		{"unsupported version", `{"version": 2, "findings": []}`, "unsupported version"},
+		{"incomplete finding", `{"version": 1, "findings": [{"path": "a.json", "fingerprint": "` + fingerprint.Of("salt", "x") + `"}]}`, "finding 1"},
+		{"unsalted fingerprint", `{"version": 1, "findings": [{"rule_id": "jwt", "path": "a.json", "fingerprint": "sha256:abcd"}]}`, "finding 1: fingerprint"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.NoError(t, os.WriteFile(filePath, []byte(tc.content), 0644))
+			_, err := Load(filePath)
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	kept := Finding{RuleID: "jwt", Path: "a.json", Fingerprint: fingerprint.Of("salt", "1")}
+	resolved := Finding{RuleID: "jwt", Path: "b.json", Fingerprint: fingerprint.Of("salt", "2")}
+	added := Finding{RuleID: "github-pat", Path: "c.go", Fingerprint: fingerprint.Of("salt", "3")}
+
+	gotAdded, gotResolved, err := Diff(New([]Finding{kept, resolved}), New([]Finding{kept, added}))
+	assert.NoError(t, err)
+	assert.Equal(t, []Finding{added}, gotAdded)
+	assert.Equal(t, []Finding{resolved}, gotResolved)
+
+	gotAdded, gotResolved, err = Diff(nil, New([]Finding{kept}))
+	assert.NoError(t, err)
+	assert.Equal(t, []Finding{kept}, gotAdded)
+	assert.Empty(t, gotResolved)
+
+	resalted := Finding{RuleID: "jwt", Path: "a.json", Fingerprint: fingerprint.Of("pepper", "1")}
+	_, _, err = Diff(New([]Finding{kept}), New([]Finding{resalted}))
+	assert.ErrorContains(t, err, "different salts")
+}
+
+func readFile(t *testing.T, filePath string) string {
+	data, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	return string(data)
+}
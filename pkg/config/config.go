@@ -28,6 +28,7 @@ type Hook struct {
 	Args                    []string `yaml:"args"`
 	Language                string   `yaml:"language"`
 	PassFilenames           bool     `yaml:"pass_filenames"`
+	RequireSerial           bool     `yaml:"require_serial"`
 	MinimumPreCommitVersion string   `yaml:"minimum_pre_commit_version"`
 }
 
@@ -45,6 +46,29 @@ var PreloadedConfig = PreCommitConfig{
 					Stages:                  []string{"pre-commit"},
 					Args:                    []string{"hooks", "pre-commit", "secrets-scan"},
 					Language:                "system",
+					PassFilenames:           true,
+					RequireSerial:           true,
+					MinimumPreCommitVersion: "3.2.0",
+				},
+				{
+					ID:                      "cx-secret-detection-commit-msg",
+					Name:                    "Cx Secret Detection (commit-msg)",
+					Entry:                   "cx",
+					Description:             "Run Cx CLI secret detection on the commit message",
+					Stages:                  []string{"commit-msg"},
+					Args:                    []string{"hooks", "pre-commit", "secrets-scan", "--commit-msg"},
+					Language:                "system",
+					PassFilenames:           true,
+					MinimumPreCommitVersion: "3.2.0",
+				},
+				{
+					ID:                      "cx-secret-detection-pre-push",
+					Name:                    "Cx Secret Detection (pre-push)",
+					Entry:                   "cx",
+					Description:             "Run Cx CLI secret detection on the commits being pushed",
+					Stages:                  []string{"pre-push"},
+					Args:                    []string{"hooks", "pre-push", "secrets-scan"},
+					Language:                "system",
 					PassFilenames:           false,
 					MinimumPreCommitVersion: "3.2.0",
 				},
@@ -53,6 +77,16 @@ var PreloadedConfig = PreCommitConfig{
 	},
 }
 
+// IsCxHook reports whether the hook ID belongs to one of the preloaded Cx hooks.
+func IsCxHook(id string) bool {
+	for _, hook := range PreloadedConfig.Repos[0].Hooks {
+		if hook.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
 // WritePreloadedConfig writes the pre-loaded configuration to a specified file
 func WritePreloadedConfig(filePath string) error {
 	data, err := yaml.Marshal(PreloadedConfig)
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestWritePreloadedConfig(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), ".pre-commit-config.yaml")
+	assert.NoError(t, WritePreloadedConfig(filePath))
+	data, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+
+	var written PreCommitConfig
+	assert.NoError(t, yaml.Unmarshal(data, &written))
+	assert.Equal(t, PreloadedConfig, written)
+	hooks := make(map[string]Hook)
+	for _, hook := range written.Repos[0].Hooks {
+		hooks[hook.ID] = hook
+	}
+	// pre-commit must not split the file scan into batches run in parallel.
+	assert.True(t, hooks["cx-secret-detection"].RequireSerial)
+	assert.True(t, hooks["cx-secret-detection"].PassFilenames)
+	assert.Contains(t, string(data), "require_serial: true")
+}
+
+func TestIsCxHook(t *testing.T) {
+	assert.True(t, IsCxHook("cx-secret-detection"))
+	assert.True(t, IsCxHook("cx-secret-detection-pre-push"))
+	assert.False(t, IsCxHook("other-hook"))
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ExcludesToGitPathspecs converts the exclude_path patterns of a hook configuration into git
+// negative pathspecs. The patterns are relative to the top of the repository, wherever git runs.
+func ExcludesToGitPathspecs(patterns []string) []string {
+	var specs []string
+	for _, pattern := range patterns {
+		if p := normalizeExclude(pattern); p != "" {
+			// Wrap in Git negative pathspec
+			specs = append(specs, fmt.Sprintf(`:(top,exclude)%s`, p))
+		}
+	}
+	return specs
+}
+
+// IsExcluded reports whether the repository-relative path of a file matches one of the exclude_path
+// patterns, with the semantics of the git pathspecs they are converted to: wildcards match slashes,
+// and a pattern matching a directory excludes the files below it.
+func IsExcluded(file string, patterns []string) bool {
+	file = strings.TrimPrefix(strings.ReplaceAll(file, `\`, "/"), "./")
+	for _, pattern := range patterns {
+		p := normalizeExclude(pattern)
+		if p == "" {
+			continue
+		}
+		var builder strings.Builder
+		builder.WriteString("^")
+		for _, r := range p {
+			switch r {
+			case '*':
+				builder.WriteString(".*")
+			case '?':
+				builder.WriteString(".")
+			default:
+				builder.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		builder.WriteString("(/.*)?$")
+		if regexp.MustCompile(builder.String()).MatchString(file) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeExclude returns the pattern relative to the repository root, or "" for an empty pattern.
+func normalizeExclude(pattern string) string {
+	// Trim spaces and surrounding quotes
+	p := strings.Trim(strings.TrimSpace(pattern), `"`)
+	// Normalize Windows backslashes to forward slashes
+	p = strings.ReplaceAll(p, `\`, "/")
+	// Strip any leading slashes
+	return strings.TrimLeft(p, "/")
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestExcludesToGitPathspecs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected []string
+	}{
+		{"empty", []string{}, nil},
+		{"single filename", []string{"a.txt"}, []string{":(top,exclude)a.txt"}},
+		{"single path", []string{"dir/a.txt"}, []string{":(top,exclude)dir/a.txt"}},
+		{"windows backslash", []string{`"\folder\file.txt"`}, []string{":(top,exclude)folder/file.txt"}},
+		{"leading slash", []string{"/root.txt"}, []string{":(top,exclude)root.txt"}},
+		{"multiple patterns", []string{"a.txt", "dir/b.log"}, []string{":(top,exclude)a.txt", ":(top,exclude)dir/b.log"}},
+		{"trims spaces", []string{" a.txt", "dir/c.md "}, []string{":(top,exclude)a.txt", ":(top,exclude)dir/c.md"}},
+		{"filename with space", []string{"my file.txt"}, []string{":(top,exclude)my file.txt"}},
+		{"path with spaces", []string{"dir name/file name.txt"}, []string{":(top,exclude)dir name/file name.txt"}},
+
+		// Glob‐pattern cases
+		{"simple glob", []string{"*.log"}, []string{":(top,exclude)*.log"}},
+		{"glob path", []string{"dir/*.js"}, []string{":(top,exclude)dir/*.js"}},
+		{"recursive glob", []string{"src/**/*.go"}, []string{":(top,exclude)src/**/*.go"}},
+		{"glob with quotes", []string{`"*.tmp"`}, []string{":(top,exclude)*.tmp"}},
+		{"leading slash glob", []string{"/cache/*.cache"}, []string{":(top,exclude)cache/*.cache"}},
+		{"trimmed glob", []string{" *.md "}, []string{":(top,exclude)*.md"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := ExcludesToGitPathspecs(tc.input)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestIsExcluded(t *testing.T) {
+	tests := []struct {
+		file     string
+		patterns []string
+		excluded bool
+	}{
+		{"a.txt", nil, false},
+		{"a.txt", []string{"a.txt"}, true},
+		{"./a.txt", []string{"/a.txt"}, true},
+		{"dir/a.txt", []string{"a.txt"}, false},
+		{"docs/a/b.md", []string{"docs/*"}, true},
+		{"docs/b.md", []string{"docs"}, true},
+		{"docsite/b.md", []string{"docs"}, false},
+		{"src/app/main.go", []string{"*.go"}, true},
+		{"src/app/main.go", []string{"src/**/*.go"}, true},
+		{`dir\a.log`, []string{`"dir\*.log"`}, true},
+		{"a.log", []string{"", "*.txt", "?.log"}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			assert.Equal(t, tc.excluded, IsExcluded(tc.file, tc.patterns))
+		})
+	}
+}
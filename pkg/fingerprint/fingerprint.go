@@ -0,0 +1,80 @@
+package fingerprint
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Prefix starts every fingerprint.
+const Prefix = "hmac-sha256:"
+
+const (
+	// macLength is the number of hex characters of the HMAC kept in a fingerprint.
+	macLength = 32
+	saltBytes = 8
+)
+
+var (
+	saltRegex = regexp.MustCompile(`^[A-Za-z0-9_-]*$`)
+	macRegex  = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// Of returns the fingerprint of a secret value: "hmac-sha256:<salt>:<mac>", where mac is the
+// HMAC-SHA256 of the value keyed by the salt. Unlike a plain hash, it cannot be looked up in
+// precomputed tables, and the fingerprints of the same value differ between repositories. The salt is
+// recorded in the fingerprint, so that it can be checked on its own.
+func Of(salt, value string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(value)) // nolint:errcheck
+	return Prefix + salt + ":" + hex.EncodeToString(mac.Sum(nil))[:macLength]
+}
+
+// NewSalt returns a random salt, generated once per repository and reused by the fingerprints it
+// records.
+func NewSalt() string {
+	salt := make([]byte, saltBytes)
+	if _, err := rand.Read(salt); err != nil {
+		panic(fmt.Sprintf("failed to generate a fingerprint salt: %v", err))
+	}
+	return hex.EncodeToString(salt)
+}
+
+// ValidateSalt checks that the salt can be recorded in a fingerprint.
+func ValidateSalt(salt string) error {
+	if !saltRegex.MatchString(salt) {
+		return fmt.Errorf("fingerprint salt %q may only contain letters, digits, '-' and '_'", salt)
+	}
+	return nil
+}
+
+// Salt returns the salt recorded in the fingerprint, and whether the fingerprint is well-formed.
+func Salt(fingerprint string) (string, bool) {
+	rest, ok := strings.CutPrefix(fingerprint, Prefix)
+	if !ok {
+		return "", false
+	}
+	salt, mac, ok := strings.Cut(rest, ":")
+	if !ok || ValidateSalt(salt) != nil || !macRegex.MatchString(mac) {
+		return "", false
+	}
+	return salt, true
+}
+
+// Validate checks that the fingerprint is one returned by Of.
+func Validate(fingerprint string) error {
+	if _, ok := Salt(fingerprint); !ok {
+		return fmt.Errorf("fingerprint %q must be %s<salt>:<%d hex characters>", fingerprint, Prefix, macLength)
+	}
+	return nil
+}
+
+// Matches reports whether the fingerprint is the one of the value.
+func Matches(fingerprint, value string) bool {
+	salt, ok := Salt(fingerprint)
+	return ok && hmac.Equal([]byte(Of(salt, value)), []byte(fingerprint))
+}
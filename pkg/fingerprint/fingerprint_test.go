@@ -0,0 +1,61 @@
+package fingerprint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOf(t *testing.T) {
+	fp := Of("0123abcd", "ghp_token")
+	assert.True(t, strings.HasPrefix(fp, Prefix+"0123abcd:"))
+	assert.Len(t, fp, len(Prefix+"0123abcd:")+macLength)
+	assert.Equal(t, fp, Of("0123abcd", "ghp_token"))
+	assert.NotEqual(t, fp, Of("4567cdef", "ghp_token"), "the salt keys the fingerprint")
+	assert.NotEqual(t, fp, Of("0123abcd", "ghp_other"))
+	assert.NotContains(t, fp, "ghp_token")
+}
+
+func TestNewSalt(t *testing.T) {
+	salt := NewSalt()
+	assert.Len(t, salt, 2*saltBytes)
+	assert.NoError(t, ValidateSalt(salt))
+	assert.NotEqual(t, salt, NewSalt())
+}
+
+func TestSaltAndValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		fingerprint string
+		salt        string
+		valid       bool
+	}{
+		{"generated", Of("0123abcd", "value"), "0123abcd", true},
+		{"empty salt", Of("", "value"), "", true},
+		{"plain hash", "sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", "", false},
+		{"no salt separator", Prefix + "0123456789abcdef0123456789abcdef", "", false},
+		{"short mac", Prefix + "salt:0123", "", false},
+		{"invalid salt", Prefix + "a b:0123456789abcdef0123456789abcdef", "", false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			salt, ok := Salt(tc.fingerprint)
+			assert.Equal(t, tc.valid, ok)
+			assert.Equal(t, tc.salt, salt)
+			if tc.valid {
+				assert.NoError(t, Validate(tc.fingerprint))
+			} else {
+				assert.ErrorContains(t, Validate(tc.fingerprint), "must be "+Prefix)
+			}
+		})
+	}
+	assert.ErrorContains(t, ValidateSalt("pepper:1"), "may only contain")
+}
+
+func TestMatches(t *testing.T) {
+	fp := Of(NewSalt(), "ghp_token")
+	assert.True(t, Matches(fp, "ghp_token"))
+	assert.False(t, Matches(fp, "ghp_other"))
+	assert.False(t, Matches("sha256:abcd", "ghp_token"))
+}
@@ -0,0 +1,84 @@
+package pre_commit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Checkmarx/secret-detection/pkg/baseline"
+	secretscanner "github.com/Checkmarx/secret-detection/pkg/scanner"
+	"github.com/fatih/color"
+)
+
+// BaselineCreate scans the tracked files of the working tree and writes their findings to the baseline
+// file, .checkmarx_baseline.json at the root of the repository when filePath is empty. The hooks then
+// fail only on new findings.
+func BaselineCreate(ctx context.Context, filePath string) error {
+	ctx, stop := secretscanner.WithSignals(ctx)
+	defer stop()
+	if filePath == "" {
+		filePath = repositoryFile(baseline.FileName)
+	}
+	// Keep the salt of the existing baseline, so that the new one can be compared with it.
+	existing, err := baseline.Load(filePath)
+	if err != nil {
+		return err
+	}
+	findings, err := scanWorkingTree(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to scan the working tree: %w", err)
+	}
+	created := baseline.FromReport(findings, existing.Salt())
+	if err = created.Write(filePath); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %d findings to %s\n", created.Len(), filePath)
+	return nil
+}
+
+// BaselineDiff prints the findings that are in the next baseline but not in the previous one, and
+// those of the previous baseline that were resolved.
+func BaselineDiff(previousPath, nextPath string) error {
+	previous, err := loadBaselineFile(previousPath)
+	if err != nil {
+		return err
+	}
+	next, err := loadBaselineFile(nextPath)
+	if err != nil {
+		return err
+	}
+	return printBaselineDiff(os.Stdout, previousPath, nextPath, previous, next)
+}
+
+// loadBaselineFile reads a baseline that must exist.
+func loadBaselineFile(filePath string) (*baseline.Baseline, error) {
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, fmt.Errorf("baseline %s does not exist", filePath)
+	}
+	return baseline.Load(filePath)
+}
+
+func printBaselineDiff(out io.Writer, previousPath, nextPath string, previous, next *baseline.Baseline) error {
+	added, resolved, err := baseline.Diff(previous, next)
+	if err != nil {
+		return fmt.Errorf("%s and %s: %w", previousPath, nextPath, err)
+	}
+	if len(added) == 0 && len(resolved) == 0 {
+		fmt.Fprintf(out, "No differences between %s and %s\n", previousPath, nextPath) // nolint:errcheck
+		return nil
+	}
+	if len(added) > 0 {
+		fmt.Fprintf(out, "New findings (%d):\n", len(added)) // nolint:errcheck
+		for _, finding := range added {
+			color.New(color.FgRed).Fprintf(out, "  + %s\n", finding) // nolint:errcheck
+		}
+	}
+	if len(resolved) > 0 {
+		fmt.Fprintf(out, "Resolved findings (%d):\n", len(resolved)) // nolint:errcheck
+		for _, finding := range resolved {
+			color.New(color.FgGreen).Fprintf(out, "  - %s\n", finding) // nolint:errcheck
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package pre_commit
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/baseline"
+	"github.com/Checkmarx/secret-detection/pkg/fingerprint"
+	"github.com/fatih/color"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintBaselineDiff(t *testing.T) {
+	color.NoColor = true
+	kept := baseline.Finding{RuleID: "jwt", Path: "a.json", Fingerprint: fingerprint.Of("salt", "1")}
+	resolved := baseline.Finding{RuleID: "jwt", Path: "b.json", Fingerprint: fingerprint.Of("salt", "2")}
+	added := baseline.Finding{RuleID: "github-pat", Path: "c.go", Fingerprint: fingerprint.Of("salt", "3")}
+
+	var out bytes.Buffer
+	assert.NoError(t, printBaselineDiff(&out, "old.json", "new.json", baseline.New([]baseline.Finding{kept, resolved}), baseline.New([]baseline.Finding{kept, added})))
+	assert.Equal(t, "New findings (1):\n  + github-pat in c.go ("+added.Fingerprint+")\nResolved findings (1):\n  - jwt in b.json ("+resolved.Fingerprint+")\n", out.String())
+
+	out.Reset()
+	assert.NoError(t, printBaselineDiff(&out, "old.json", "new.json", baseline.New([]baseline.Finding{kept}), baseline.New([]baseline.Finding{kept})))
+	assert.Equal(t, "No differences between old.json and new.json\n", out.String())
+
+	resalted := baseline.Finding{RuleID: "jwt", Path: "a.json", Fingerprint: fingerprint.Of("pepper", "1")}
+	err := printBaselineDiff(&out, "old.json", "new.json", baseline.New([]baseline.Finding{kept}), baseline.New([]baseline.Finding{resalted}))
+	assert.ErrorContains(t, err, "old.json and new.json")
+}
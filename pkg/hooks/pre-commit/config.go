@@ -0,0 +1,138 @@
+package pre_commit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Checkmarx/secret-detection/pkg/baseline"
+	"github.com/Checkmarx/secret-detection/pkg/parser"
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	"github.com/Checkmarx/secret-detection/pkg/rules"
+	"github.com/Checkmarx/secret-detection/pkg/scanner"
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/Checkmarx/secret-detection/pkg/verify"
+	"gopkg.in/yaml.v2"
+)
+
+// configFileName is the repository-level configuration file read by the pre-commit hook.
+const configFileName = ".checkmarx.yaml"
+
+// PreCommitScanConfig represents the structure of the .checkmarx.yaml file. The keys it shares with
+// the pre-receive configuration have the same semantics, so one file can serve both hooks.
+type PreCommitScanConfig struct {
+	ExcludePath  []string `yaml:"exclude_path"`
+	IgnoreRule   []string `yaml:"ignore_rule_id"`
+	IgnoreSecret []string `yaml:"ignore_result_id"`
+	// MaxFileDiffSizeMB skips the files whose staged additions exceed the size; 0 means the default 10 MB.
+	MaxFileDiffSizeMB int                    `yaml:"max_file_diff_size_mb"`
+	Redaction         report.RedactionConfig `yaml:"redaction"`
+	Report            report.Settings        `yaml:"report"`
+	Severity          severity.Config        `yaml:"severity"`
+	CustomRules       []rules.CustomRule     `yaml:"custom_rules"`
+	SelectRules       []string               `yaml:"select_rules"`
+	SelectTags        []string               `yaml:"select_tags"`
+	IgnoreTags        []string               `yaml:"ignore_tags"`
+	Entropy           scanner.EntropyConfig  `yaml:"entropy"`
+	// Validity is read from the user configuration, see verify.LoadUserConfig, and not from the file
+	// of the repository, which must not choose where the secrets of its developers are sent.
+	Validity verify.Config `yaml:"-"`
+	// DisableInteractive prints the report instead of triaging the findings when run from a terminal.
+	DisableInteractive bool `yaml:"disable_interactive"`
+	// Baseline is the path of the baseline file, whose findings do not fail the commit. The default
+	// is the .checkmarx_baseline.json file of the repository, when it exists.
+	Baseline string `yaml:"baseline"`
+}
+
+// loadScanConfig reads the ".checkmarx.yaml" file located at the root of the repository, and the
+// validity settings of the user. A missing file results in the default configuration.
+func loadScanConfig() (PreCommitScanConfig, error) {
+	validity, err := verify.LoadUserConfig()
+	if err != nil {
+		return PreCommitScanConfig{}, err
+	}
+	cfg := PreCommitScanConfig{Validity: validity}
+	configPath := repositoryFile(configFileName)
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return PreCommitScanConfig{}, fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+
+	if err = yaml.Unmarshal(data, &cfg); err != nil {
+		return PreCommitScanConfig{}, fmt.Errorf("configuration file at %s is misconfigured", configPath)
+	}
+
+	if cfg.MaxFileDiffSizeMB < 0 {
+		return PreCommitScanConfig{}, fmt.Errorf("configuration file at %s is misconfigured: max_file_diff_size_mb must not be negative", configPath)
+	}
+
+	if err = cfg.Report.Validate(); err != nil {
+		return PreCommitScanConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+	}
+
+	if err = cfg.Redaction.Validate(); err != nil {
+		return PreCommitScanConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+	}
+
+	if err = cfg.Severity.Validate(); err != nil {
+		return PreCommitScanConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+	}
+
+	if err = rules.ValidateCustomRules(cfg.CustomRules); err != nil {
+		return PreCommitScanConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+	}
+
+	if err = cfg.Entropy.Validate(); err != nil {
+		return PreCommitScanConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+	}
+
+	if err = cfg.ruleSelection().Validate(cfg.CustomRules); err != nil {
+		return PreCommitScanConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+	}
+	return cfg, nil
+}
+
+// loadBaseline reads the baseline file of the configuration, whose relative path is from the root of
+// the repository. Without one, there is no baseline.
+func (c PreCommitScanConfig) loadBaseline() (*baseline.Baseline, error) {
+	if c.Baseline == "" {
+		return baseline.Load(repositoryFile(baseline.FileName))
+	}
+	baselinePath := c.Baseline
+	if !filepath.IsAbs(baselinePath) {
+		baselinePath = repositoryFile(baselinePath)
+	}
+	if _, err := os.Stat(baselinePath); err != nil {
+		return nil, fmt.Errorf("baseline %s does not exist", c.Baseline)
+	}
+	return baseline.Load(baselinePath)
+}
+
+// repositoryFile returns the path of the file at the root of the repository, where the configuration,
+// ignore and baseline files are, whatever the directory the hook runs from.
+func repositoryFile(name string) string {
+	root, _ := workingTree()
+	return filepath.Join(root, name)
+}
+
+// ruleSelection returns the rule selection settings of the configuration.
+func (c PreCommitScanConfig) ruleSelection() rules.Selection {
+	return rules.Selection{
+		SelectRules: c.SelectRules,
+		SelectTags:  c.SelectTags,
+		IgnoreRules: c.IgnoreRule,
+		IgnoreTags:  c.IgnoreTags,
+		Entropy:     c.Entropy.Enabled,
+	}
+}
+
+// maxFileDiffSize returns the limit of staged additions per file, in bytes.
+func (c PreCommitScanConfig) maxFileDiffSize() int {
+	if c.MaxFileDiffSizeMB == 0 {
+		return parser.DefaultMaxFileDiffSize
+	}
+	return c.MaxFileDiffSizeMB * 1024 * 1024
+}
@@ -0,0 +1,316 @@
+package pre_commit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/config"
+	"github.com/Checkmarx/secret-detection/pkg/parser"
+	"github.com/Checkmarx/secret-detection/pkg/report"
+)
+
+const (
+	// scissorsLine marks the start of the diff that "git commit --verbose" appends to the message file.
+	scissorsLine = "# ------------------------ >8 ------------------------"
+	// wholeRepository is the pathspec of every file of the repository, also when git runs in one of
+	// its subdirectories.
+	wholeRepository = ":/"
+)
+
+// ScanOptions selects the content scanned by ScanWithOptions. The zero value scans the staged changes.
+type ScanOptions struct {
+	// AllFiles scans the whole content of every tracked file.
+	AllFiles bool
+	// Files scans the whole content of the given files.
+	Files []string
+	// Filenames are the files passed by the pre-commit framework when the hook sets pass_filenames.
+	// The staged changes of these files are scanned; the files without staged changes, as passed by
+	// "pre-commit run --all-files", are scanned whole.
+	Filenames []string
+	// CommitMessageFile scans the commit message in the file, as passed to a commit-msg hook.
+	CommitMessageFile string
+}
+
+// commit reports whether the scan is part of a commit, whose staged changes can be triaged.
+func (o ScanOptions) commit() bool {
+	return !o.AllFiles && len(o.Files) == 0 && o.CommitMessageFile == ""
+}
+
+// collectFiles sends the content selected by opts to out as diff hunks, each file as soon as it is
+// read, so that it is scanned while the next ones are collected. Whole files are a single hunk
+// starting at their first line, so that findings are reported with whole-file line numbers. The
+// staged changes that are not scanned are listed on messages.
+func collectFiles(ctx context.Context, scanConfig PreCommitScanConfig, opts ScanOptions, out chan<- parser.FileDiff, messages io.Writer) error {
+	maxSize := scanConfig.maxFileDiffSize()
+	// The files are reported with their path from the root of the repository, as the staged changes
+	// are, so that a secret has the same result ID whatever the directory the scan runs from.
+	root, prefix := workingTree()
+	switch {
+	case opts.CommitMessageFile != "":
+		fileDiffs, err := commitMessageDiffs(opts.CommitMessageFile)
+		if err != nil {
+			return err
+		}
+		for file, hunks := range fileDiffs {
+			out <- parser.FileDiff{Path: file, Hunks: hunks, Content: hunks[0].Content}
+		}
+		return nil
+	case opts.AllFiles:
+		files, err := trackedFiles(ctx, scanConfig)
+		if err != nil {
+			return err
+		}
+		return sendWholeFiles(ctx, root, files, maxSize, out)
+	case len(opts.Files) > 0:
+		return sendWholeFiles(ctx, root, selectFiles(rootRelative(root, prefix, opts.Files), scanConfig.ExcludePath), maxSize, out)
+	case len(opts.Filenames) > 0:
+		files := selectFiles(rootRelative(root, prefix, opts.Filenames), scanConfig.ExcludePath)
+		if len(files) == 0 {
+			return nil
+		}
+		staged, err := stagedFiles(ctx, files)
+		if err != nil {
+			return err
+		}
+		var stagedPaths, unstagedPaths []string
+		for _, file := range files {
+			if _, ok := staged[file]; ok {
+				stagedPaths = append(stagedPaths, file)
+			} else {
+				unstagedPaths = append(unstagedPaths, file)
+			}
+		}
+		if len(stagedPaths) > 0 {
+			if err = runDiffParsing(ctx, scanConfig, out, messages, literalPathspecs(stagedPaths)...); err != nil {
+				return err
+			}
+		}
+		return sendWholeFiles(ctx, root, unstagedPaths, maxSize, out)
+	default:
+		return runDiffParsing(ctx, scanConfig, out, messages)
+	}
+}
+
+// reloadHunks reads the content of the files again, as collectFiles read it, for the report of their
+// findings. layouts are the hunks of the files as they were scanned, without their content; a file
+// whose hunks changed since is reported without its content.
+func reloadHunks(ctx context.Context, scanConfig PreCommitScanConfig, opts ScanOptions, layouts map[string][]parser.Hunk) (map[string][]parser.Hunk, error) {
+	if len(layouts) == 0 {
+		return layouts, nil
+	}
+	files := make([]string, 0, len(layouts))
+	fileDiffs := make(map[string][]parser.Hunk, len(layouts))
+	for file, layout := range layouts {
+		files = append(files, file)
+		fileDiffs[file] = layout
+	}
+	sort.Strings(files)
+
+	reloaded := make(chan parser.FileDiff)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(reloaded)
+		errCh <- collectFilesAgain(ctx, scanConfig, opts, files, reloaded)
+	}()
+	for file := range reloaded {
+		if layout, ok := layouts[file.Path]; ok && slices.Equal(layout, hunkLayout(file.Hunks)) {
+			fileDiffs[file.Path] = file.Hunks
+		}
+	}
+	if err := <-errCh; err != nil {
+		return nil, fmt.Errorf("failed to read the files with findings: %w", err)
+	}
+	return fileDiffs, nil
+}
+
+// collectFilesAgain sends the content of the files, collected by collectFiles with opts, to out.
+func collectFilesAgain(ctx context.Context, scanConfig PreCommitScanConfig, opts ScanOptions, files []string, out chan<- parser.FileDiff) error {
+	switch {
+	case opts.CommitMessageFile != "":
+		return collectFiles(ctx, scanConfig, opts, out, io.Discard)
+	case opts.AllFiles, len(opts.Files) > 0:
+		root, _ := workingTree()
+		return sendWholeFiles(ctx, root, files, scanConfig.maxFileDiffSize(), out)
+	case len(opts.Filenames) > 0:
+		// The files are relative to the root of the repository, and not to the working directory.
+		root, _ := workingTree()
+		filenames := make([]string, len(files))
+		for i, file := range files {
+			filenames[i] = filepath.Join(root, file)
+		}
+		return collectFiles(ctx, scanConfig, ScanOptions{Filenames: filenames}, out, io.Discard)
+	default:
+		return runDiffParsing(ctx, scanConfig, out, io.Discard, literalPathspecs(files)...)
+	}
+}
+
+// commitMessageDiffs returns the commit message in the file as the single hunk of a pseudo-file.
+// The comment lines added by git are blanked, so that the line numbers match the file, and the diff
+// shown below the scissors line of "git commit --verbose" is left out.
+func commitMessageDiffs(filePath string) (map[string][]parser.Hunk, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit message: %w", err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	var builder strings.Builder
+	size, hasContent := 0, false
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		trimmed := strings.TrimRight(line, "\r\n")
+		if trimmed == scissorsLine {
+			break
+		}
+		size++
+		if strings.HasPrefix(trimmed, "#") {
+			builder.WriteString("\n")
+			continue
+		}
+		builder.WriteString(trimmed + "\n")
+		hasContent = hasContent || strings.TrimSpace(trimmed) != ""
+	}
+	if !hasContent {
+		return map[string][]parser.Hunk{}, nil
+	}
+	return map[string][]parser.Hunk{
+		report.CommitMessageFile: {{StartLine: 1, Content: builder.String(), Size: size}},
+	}, nil
+}
+
+// trackedFiles lists the tracked files of the whole repository that are not excluded by the
+// configuration, relative to the root of the repository.
+func trackedFiles(ctx context.Context, scanConfig PreCommitScanConfig) ([]string, error) {
+	args := []string{"ls-files", "-z", "--full-name", "--", wholeRepository}
+	args = append(args, config.ExcludesToGitPathspecs(scanConfig.ExcludePath)...)
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the tracked files: %w", err)
+	}
+	var files []string
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// workingTree returns the root directory of the working tree and the path of the working directory
+// relative to it, ending with a slash unless empty. Outside of a working tree, the working directory
+// is the root.
+func workingTree() (root, prefix string) {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel", "--show-prefix").Output()
+	if err != nil {
+		return ".", ""
+	}
+	lines := strings.Split(string(out), "\n")
+	if len(lines) < 2 {
+		return ".", ""
+	}
+	return lines[0], lines[1]
+}
+
+// rootRelative returns the paths of the files, given relative to the working directory or absolute,
+// relative to the root of the working tree.
+func rootRelative(root, prefix string, files []string) []string {
+	relative := make([]string, len(files))
+	for i, file := range files {
+		relative[i] = prefix + file
+		if filepath.IsAbs(file) {
+			if rel, err := filepath.Rel(root, file); err == nil {
+				relative[i] = rel
+			}
+		}
+	}
+	return relative
+}
+
+// stagedFiles returns the set of files among files that have staged changes.
+func stagedFiles(ctx context.Context, files []string) (map[string]struct{}, error) {
+	args := append([]string{"diff", "--staged", "--name-only", "-z", "--"}, literalPathspecs(files)...)
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the staged files: %w", err)
+	}
+	staged := make(map[string]struct{})
+	for _, file := range strings.Split(string(out), "\x00") {
+		if file != "" {
+			staged[file] = struct{}{}
+		}
+	}
+	return staged, nil
+}
+
+// selectFiles normalizes the paths and drops those excluded by the configuration.
+func selectFiles(files []string, excludes []string) []string {
+	var selected []string
+	seen := make(map[string]struct{}, len(files))
+	for _, file := range files {
+		file = filepath.ToSlash(filepath.Clean(file))
+		if _, ok := seen[file]; ok || config.IsExcluded(file, excludes) {
+			continue
+		}
+		seen[file] = struct{}{}
+		selected = append(selected, file)
+	}
+	return selected
+}
+
+// sendWholeFiles sends the files, relative to root, that wholeFile reads to out, until ctx is done.
+func sendWholeFiles(ctx context.Context, root string, files []string, maxSize int, out chan<- parser.FileDiff) error {
+	for _, file := range files {
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		if fileDiff, ok := wholeFile(root, file, maxSize); ok {
+			out <- fileDiff
+		}
+	}
+	return nil
+}
+
+// wholeFile reads the file, relative to root, from the working tree as a single hunk. Missing, binary
+// and oversized files are skipped, as they are when scanning staged changes.
+func wholeFile(root, file string, maxSize int) (parser.FileDiff, bool) {
+	data, err := os.ReadFile(filepath.Join(root, file))
+	if err != nil || len(data) == 0 || len(data) > maxSize || isBinary(data) {
+		return parser.FileDiff{}, false
+	}
+	content := string(data)
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return parser.FileDiff{
+		Path:    file,
+		Hunks:   []parser.Hunk{{StartLine: 1, Content: content, Size: strings.Count(content, "\n")}},
+		Content: content,
+	}, true
+}
+
+// literalPathspecs selects the files, relative to the root of the repository, without git
+// interpreting wildcards in their names.
+func literalPathspecs(files []string) []string {
+	specs := make([]string, len(files))
+	for i, file := range files {
+		specs[i] = ":(top,literal)" + file
+	}
+	return specs
+}
+
+// isBinary reports whether the content looks binary, as git does: it has a NUL byte in its first 8000 bytes.
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
@@ -0,0 +1,78 @@
+package pre_commit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/parser"
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectFiles(t *testing.T) {
+	files := []string{"./a.txt", "docs/readme.md", "src/../a.txt", "src/main.go", "vendor/lib/x.go"}
+	assert.Equal(t, []string{"a.txt", "src/main.go"}, selectFiles(files, []string{"docs/*", "vendor"}))
+}
+
+func TestWholeFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, data, 0644))
+		return path
+	}
+	text := write("text.txt", []byte("first\nsecond"))
+	empty := write("empty.txt", nil)
+	binary := write("binary.bin", []byte("abc\x00def"))
+	large := write("large.txt", []byte("0123456789\n0123456789\n"))
+
+	fileDiff, ok := wholeFile("", text, 12)
+	assert.True(t, ok)
+	assert.Equal(t, parser.FileDiff{
+		Path:    text,
+		Hunks:   []parser.Hunk{{StartLine: 1, Content: "first\nsecond\n", Size: 2}},
+		Content: "first\nsecond\n",
+	}, fileDiff)
+
+	for _, file := range []string{empty, binary, large, filepath.Join(dir, "missing.txt")} {
+		_, ok = wholeFile("", file, 12)
+		assert.False(t, ok, file)
+	}
+}
+
+func TestRootRelative(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "repo")
+	files := []string{"a.txt", "../b.txt", filepath.Join(root, "src", "c.go")}
+	assert.Equal(t, []string{"sub/a.txt", "sub/../b.txt", filepath.Join("src", "c.go")}, rootRelative(root, "sub/", files))
+	assert.Equal(t, []string{"a.txt", "../b.txt", filepath.Join("src", "c.go")}, rootRelative(root, "", files))
+}
+
+func TestScanOptionsCommit(t *testing.T) {
+	assert.True(t, ScanOptions{}.commit())
+	assert.True(t, ScanOptions{Filenames: []string{"a.txt"}}.commit())
+	assert.False(t, ScanOptions{AllFiles: true}.commit())
+	assert.False(t, ScanOptions{Files: []string{"a.txt"}}.commit())
+	assert.False(t, ScanOptions{CommitMessageFile: "COMMIT_EDITMSG"}.commit())
+}
+
+func TestCommitMessageDiffs(t *testing.T) {
+	dir := t.TempDir()
+	messageFile := filepath.Join(dir, "COMMIT_EDITMSG")
+	message := "Fix auth\r\n\nnew key is ghp_token\n# Please enter the commit message\n" + scissorsLine + "\n+secret = \"diff\"\n"
+	assert.NoError(t, os.WriteFile(messageFile, []byte(message), 0644))
+
+	fileDiffs, err := commitMessageDiffs(messageFile)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]parser.Hunk{
+		report.CommitMessageFile: {{StartLine: 1, Content: "Fix auth\n\nnew key is ghp_token\n\n", Size: 4}},
+	}, fileDiffs)
+
+	assert.NoError(t, os.WriteFile(messageFile, []byte("# only comments\n\n"), 0644))
+	fileDiffs, err = commitMessageDiffs(messageFile)
+	assert.NoError(t, err)
+	assert.Empty(t, fileDiffs)
+
+	_, err = commitMessageDiffs(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -1,50 +1,133 @@
 package pre_commit
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
+	"os/exec"
+	"slices"
 	"strings"
+	"time"
+
+	"github.com/Checkmarx/secret-detection/pkg/baseline"
+	"github.com/Checkmarx/secret-detection/pkg/ignore"
+	secretscanner "github.com/Checkmarx/secret-detection/pkg/scanner"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+	"github.com/fatih/color"
+	"github.com/rs/zerolog"
 )
 
+// IgnoreOptions describes the entries added to the ".checkmarx_ignore" file by IgnoreWithOptions.
+type IgnoreOptions struct {
+	ResultIds []string
+	// Rule ignores every finding of a rule, only in the files matching Path when it is set.
+	Rule string
+	Path string
+	// Fingerprints ignore secret values wherever they are found. They are the fingerprints shown by
+	// the fingerprint redaction mode of the reports; see fingerprint.Of.
+	Fingerprints []string
+	Reason       string
+	// Author defaults to the git user.email of the repository.
+	Author string
+}
+
+// hasMetadata reports whether the entries need the structured format.
+func (o IgnoreOptions) hasMetadata() bool {
+	return o.Rule != "" || len(o.Fingerprints) > 0 || o.Reason != "" || o.Author != ""
+}
+
 // Ignore adds the provided resultIds to the ignore list stored in the ".checkmarx_ignore" file
 func Ignore(resultIds []string) error {
-	ignoreFilePath := filepath.Join(".", ".checkmarx_ignore")
-	existingIDs := make(map[string]struct{})
-	var fileContent []byte
-
-	// If the file exists, read its content once into a map
-	if _, err := os.Stat(ignoreFilePath); err == nil {
-		fileContent, err = os.ReadFile(ignoreFilePath)
-		if err != nil {
-			return fmt.Errorf("failed to read %s: %w", ignoreFilePath, err)
+	return IgnoreWithOptions(IgnoreOptions{ResultIds: resultIds})
+}
+
+// IgnoreWithOptions adds entries to the ".checkmarx_ignore" file. When a rule, fingerprint or reason
+// is given, the entries record the reason, the author and the current date; plain result IDs are
+// written in the legacy format.
+func IgnoreWithOptions(opts IgnoreOptions) error {
+	ignoreFilePath := repositoryFile(ignore.FileName)
+	if opts.Path != "" && opts.Rule == "" {
+		return fmt.Errorf("a path can only be ignored for a rule")
+	}
+
+	existing, err := ignore.Load(ignoreFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", ignoreFilePath, err)
+	}
+
+	var template ignore.Entry
+	if opts.hasMetadata() {
+		template = ignore.Entry{
+			Reason: opts.Reason,
+			Author: opts.Author,
+			Date:   time.Now().Format(ignore.DateLayout),
 		}
-		for _, line := range strings.Split(string(fileContent), "\n") {
-			trimmed := strings.TrimSpace(line)
-			if trimmed != "" {
-				existingIDs[trimmed] = struct{}{}
-			}
+		if template.Author == "" {
+			template.Author = gitUserEmail()
 		}
 	}
 
-	// Collect only new IDs that are not already present
-	var newIDs []string
-	for _, id := range resultIds {
-		id = strings.TrimSpace(id)
-		if id == "" {
+	var candidates []ignore.Entry
+	for _, id := range opts.ResultIds {
+		candidates = append(candidates, withValue(template, ignore.KindResult, strings.TrimSpace(id)))
+	}
+	for _, fingerprint := range opts.Fingerprints {
+		candidates = append(candidates, withValue(template, ignore.KindFingerprint, strings.TrimSpace(fingerprint)))
+	}
+	if opts.Rule != "" {
+		entry := withValue(template, ignore.KindRule, strings.TrimSpace(opts.Rule))
+		entry.Path = strings.TrimSpace(opts.Path)
+		candidates = append(candidates, entry)
+	}
+
+	// Collect only new entries that are not already present
+	var newEntries ignore.List
+	for _, entry := range candidates {
+		if entry.Value == "" || existing.Contains(entry) || newEntries.Contains(entry) {
 			continue
 		}
-		if _, exists := existingIDs[id]; !exists {
-			newIDs = append(newIDs, id)
+		if err := entry.Validate(); err != nil {
+			return err
 		}
+		newEntries = append(newEntries, entry)
+	}
+
+	noun := "IDs"
+	if opts.Rule != "" || len(opts.Fingerprints) > 0 {
+		noun = "entries"
 	}
 
-	// If there are no new IDs, nothing to do.
-	if len(newIDs) == 0 {
-		fmt.Println("No new resultIds to add")
+	// If there are no new entries, nothing to do.
+	if len(newEntries) == 0 {
+		if noun == "IDs" {
+			fmt.Println("No new resultIds to add")
+		} else {
+			fmt.Println("No new entries to add")
+		}
 		return nil
 	}
 
+	if err := appendEntries(ignoreFilePath, newEntries); err != nil {
+		return err
+	}
+	fmt.Printf("Added %d new %s to %s\n", len(newEntries), noun, ignoreFilePath)
+	return nil
+}
+
+func withValue(template ignore.Entry, kind ignore.Kind, value string) ignore.Entry {
+	template.Kind = kind
+	template.Value = value
+	return template
+}
+
+// appendEntries writes the entries at the end of the ignore file, creating it if needed.
+func appendEntries(ignoreFilePath string, entries ignore.List) error {
+	fileContent, err := os.ReadFile(ignoreFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", ignoreFilePath, err)
+	}
+
 	// Open file for appending (creates it if it doesn't exist)
 	file, err := os.OpenFile(ignoreFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
@@ -63,19 +146,32 @@ func Ignore(resultIds []string) error {
 		}
 	}
 
-	// Write all new IDs, each on a new line
-	for _, id := range newIDs {
-		if _, err = file.WriteString(id + "\n"); err != nil {
+	// Write all new entries, each on a new line
+	for _, entry := range entries {
+		if _, err = file.WriteString(entry.String() + "\n"); err != nil {
 			return fmt.Errorf("failed to write to %s: %w", ignoreFilePath, err)
 		}
 	}
-
-	fmt.Printf("Added %d new IDs to %s\n", len(newIDs), ignoreFilePath)
 	return nil
 }
 
-func IgnoreAll() error {
-	report, _, err := runSecretScan()
+// gitUserEmail returns the git user.email of the repository, or "" when none is configured.
+func gitUserEmail() string {
+	out, err := exec.Command("git", "config", "user.email").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func IgnoreAll(ctx context.Context) error {
+	ctx, stop := secretscanner.WithSignals(ctx)
+	defer stop()
+	scanConfig, err := loadScanConfig()
+	if err != nil {
+		return err
+	}
+	report, _, _, err := runSecretScan(ctx, scanConfig, ScanOptions{}, os.Stdout)
 	if err != nil {
 		return err
 	}
@@ -89,24 +185,163 @@ func IgnoreAll() error {
 	return Ignore(resultIds)
 }
 
-// getIgnoredResultIds reads the ".checkmarx_ignore" file located in the current directory and
-// returns a slice of ignored result IDs. Each line in the file is expected to contain a single result ID.
-func getIgnoredResultIds() ([]string, error) {
-	ignoreFilePath := filepath.Join(".", ".checkmarx_ignore")
-	data, err := os.ReadFile(ignoreFilePath)
+// loadIgnoreList reads the ".checkmarx_ignore" file located at the root of the repository.
+// A missing file results in an empty list.
+func loadIgnoreList() (ignore.List, error) {
+	return ignore.Load(repositoryFile(ignore.FileName))
+}
+
+// IgnoreList prints the entries of the ".checkmarx_ignore" file, marking those that no longer match
+// any finding in the working tree as stale.
+func IgnoreList(ctx context.Context) error {
+	ctx, stop := secretscanner.WithSignals(ctx)
+	defer stop()
+	list, err := loadIgnoreList()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Printf("No entries in %s\n", ignore.FileName)
+		return nil
+	}
+	findings, err := scanWorkingTree(ctx)
 	if err != nil {
-		// If the file doesn't exist, return an empty slice without error
-		if os.IsNotExist(err) {
-			return []string{}, nil
+		return err
+	}
+
+	active := 0
+	for _, entry := range list {
+		status := color.New(color.FgRed).Sprint("stale ")
+		if matchesAnyFinding(entry, findings) {
+			status = color.New(color.FgGreen).Sprint("active")
+			active++
+		}
+		fmt.Printf("%s  %s\n", status, describeEntry(entry))
+	}
+	fmt.Printf("\n%d of %d entries match findings in the working tree\n", active, len(list))
+	return nil
+}
+
+// IgnoreRemove removes the entries whose value is one of values: a result ID, a rule ID or a fingerprint.
+func IgnoreRemove(values []string) error {
+	remove := make(map[string]struct{}, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			remove[value] = struct{}{}
 		}
+	}
+	removed, err := ignore.Remove(repositoryFile(ignore.FileName), func(entry ignore.Entry) bool {
+		_, ok := remove[entry.Value]
+		return ok
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", ignore.FileName, err)
+	}
+	if len(removed) == 0 {
+		fmt.Println("No matching entries to remove")
+		return nil
+	}
+	fmt.Printf("Removed %d entries from %s\n", len(removed), ignore.FileName)
+	return nil
+}
+
+// IgnorePrune scans the working tree and removes the result and fingerprint entries that no longer
+// match any finding, so that they cannot hide a future leak with the same ID or value. Rule entries
+// are kept. Nothing is removed when no entry matches a finding.
+func IgnorePrune(ctx context.Context) error {
+	ctx, stop := secretscanner.WithSignals(ctx)
+	defer stop()
+	list, err := loadIgnoreList()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Println("No stale entries to prune")
+		return nil
+	}
+	findings, err := scanWorkingTree(ctx)
+	if err != nil {
+		return err
+	}
+	// When no entry matches, the scan more likely missed the findings than every entry is stale.
+	if !slices.ContainsFunc(list, func(entry ignore.Entry) bool { return matchesAnyFinding(entry, findings) }) {
+		return fmt.Errorf("no entry of %s matches a finding of the working tree; remove the stale entries with \"ignore remove\" instead", ignore.FileName)
+	}
+	removed, err := ignore.Remove(repositoryFile(ignore.FileName), func(entry ignore.Entry) bool {
+		return entry.Prunable() && !matchesAnyFinding(entry, findings)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", ignore.FileName, err)
+	}
+	if len(removed) == 0 {
+		fmt.Println("No stale entries to prune")
+		return nil
+	}
+	for _, entry := range removed {
+		fmt.Printf("Pruned %s\n", describeEntry(entry))
+	}
+	fmt.Printf("Pruned %d stale entries from %s\n", len(removed), ignore.FileName)
+	return nil
+}
+
+// scanWorkingTree scans the tracked files of the working tree without applying the ignore list.
+// The findings have the IDs the pre-commit scan gives to the same secrets.
+func scanWorkingTree(ctx context.Context) (*reporting.Report, error) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	scanConfig, err := loadScanConfig()
+	if err != nil {
+		return nil, err
+	}
+	files, err := trackedFiles(ctx, scanConfig)
+	if err != nil {
 		return nil, err
 	}
+	root, _ := workingTree()
+	return scanItems(ctx, scanConfig, nil, func(ctx context.Context, itemsCh chan<- twoms.ScanItem) error {
+		for _, file := range files {
+			if file == ignore.FileName || file == baseline.FileName {
+				continue
+			}
+			if fileDiff, ok := wholeFile(root, file, scanConfig.maxFileDiffSize()); ok {
+				if err := sendFileForScanning(ctx, fileDiff, itemsCh); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
 
-	var resultIds []string
-	for _, line := range strings.Split(string(data), "\n") {
-		if trimmed := strings.TrimSpace(line); trimmed != "" {
-			resultIds = append(resultIds, trimmed)
+func matchesAnyFinding(entry ignore.Entry, findings *reporting.Report) bool {
+	for _, list := range findings.Results {
+		for _, secret := range list {
+			if entry.Matches(secret) {
+				return true
+			}
 		}
 	}
-	return resultIds, nil
+	return false
+}
+
+// describeEntry formats an entry for display.
+func describeEntry(entry ignore.Entry) string {
+	description := string(entry.Kind) + " " + entry.Value
+	if entry.Path != "" {
+		description += " in " + entry.Path
+	}
+	var details []string
+	if entry.Reason != "" {
+		details = append(details, fmt.Sprintf("reason: %s", entry.Reason))
+	}
+	if entry.Author != "" {
+		details = append(details, fmt.Sprintf("by %s", entry.Author))
+	}
+	if entry.Date != "" {
+		details = append(details, fmt.Sprintf("on %s", entry.Date))
+	}
+	if len(details) > 0 {
+		description += " (" + strings.Join(details, ", ") + ")"
+	}
+	return description
 }
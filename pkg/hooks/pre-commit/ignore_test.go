@@ -0,0 +1,52 @@
+package pre_commit
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/ignore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIgnorePruneFromSubdirectory(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := func(args ...string) {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	assert.NoError(t, os.Mkdir("sub", 0755))
+	assert.NoError(t, os.WriteFile("root.env", []byte("acme_0123456789abcdef0123456789abcdef\n"), 0644))
+	assert.NoError(t, os.WriteFile(configFileName, []byte("custom_rules:\n  - id: acme-key\n    regex: 'acme_[a-f0-9]{32}'\n"), 0644))
+	git("add", "root.env")
+
+	result, err := Run(context.Background(), ScanOptions{}, Streams{})
+	assert.NoError(t, err)
+	var active string
+	for _, finding := range result.Findings {
+		if finding.RuleID == "acme-key" {
+			active = finding.ID
+		}
+	}
+	assert.NotEmpty(t, active)
+	const stale = "0000000000000000000000000000000000000000"
+	ignorePath, err := filepath.Abs(ignore.FileName)
+	assert.NoError(t, err)
+
+	t.Chdir("sub")
+	assert.NoError(t, os.WriteFile(ignorePath, []byte(active+"\n"+stale+"\n"), 0644))
+	assert.NoError(t, IgnorePrune(context.Background()))
+	list, err := ignore.Load(ignorePath)
+	assert.NoError(t, err)
+	assert.Equal(t, ignore.List{{Kind: ignore.KindResult, Value: active}}, list, "the entries are matched with the IDs of the hook")
+
+	// An ignore file without any matching entry is left alone.
+	assert.NoError(t, os.WriteFile(ignorePath, []byte(stale+"\n"), 0644))
+	assert.ErrorContains(t, IgnorePrune(context.Background()), "no entry")
+	data, err := os.ReadFile(ignorePath)
+	assert.NoError(t, err)
+	assert.Equal(t, stale+"\n", string(data))
+}
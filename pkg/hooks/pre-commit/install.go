@@ -11,6 +11,9 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
+// installHookTypes are the arguments that install the git hooks of every stage used by the Cx hooks.
+var installHookTypes = []string{"install", "--hook-type", "pre-commit", "--hook-type", "commit-msg", "--hook-type", "pre-push"}
+
 // Install sets up pre-commit hooks, either locally or globally.
 func Install(global bool) error {
 	if global {
@@ -39,7 +42,7 @@ func installLocal() error {
 		}
 	}
 
-	cmd := exec.Command("pre-commit", "install")
+	cmd := exec.Command("pre-commit", installHookTypes...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to install local pre-commit hooks: %v\n%s", err, output)
@@ -81,21 +84,31 @@ func installGlobal() error {
 		return fmt.Errorf("failed to create global hooks directory: %v", err)
 	}
 
-	// Path to the global pre-commit hook script.
-	preCommitHookPath := filepath.Join(globalHooksPath, "pre-commit")
-
-	// Write the pre-commit hook script.
-	hookScript := `#!/bin/sh
-cx hooks pre-commit secrets-scan
-`
-	if err := os.WriteFile(preCommitHookPath, []byte(hookScript), 0755); err != nil {
-		return fmt.Errorf("failed to write pre-commit hook script: %v", err)
+	// Write the hook scripts. The commit-msg and pre-push hooks forward the arguments git passes to them.
+	for name, hookScript := range globalHookScripts {
+		hookPath := filepath.Join(globalHooksPath, name)
+		if err := os.WriteFile(hookPath, []byte(hookScript), 0755); err != nil {
+			return fmt.Errorf("failed to write %s hook script: %v", name, err)
+		}
 	}
 
-	fmt.Println("Global pre-commit hook installed successfully.")
+	fmt.Println("Global pre-commit, commit-msg and pre-push hooks installed successfully.")
 	return nil
 }
 
+// globalHookScripts are the scripts written to the global hooks directory, by hook name.
+var globalHookScripts = map[string]string{
+	"pre-commit": `#!/bin/sh
+cx hooks pre-commit secrets-scan
+`,
+	"commit-msg": `#!/bin/sh
+cx hooks pre-commit secrets-scan --commit-msg "$1"
+`,
+	"pre-push": `#!/bin/sh
+cx hooks pre-push secrets-scan "$@"
+`,
+}
+
 // isGitRepo checks if the current directory is a Git repository.
 func isGitRepo() bool {
 	cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
@@ -103,7 +116,7 @@ func isGitRepo() bool {
 	return err == nil
 }
 
-// updateConfigFile updates a .pre-commit-config.yaml file with the required hook if not present.
+// updateConfigFile updates a .pre-commit-config.yaml file with the required hooks if not present.
 func updateConfigFile(filePath string) error {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
@@ -119,12 +132,7 @@ func updateConfigFile(filePath string) error {
 	for i := range preCommitConfig.Repos {
 		if preCommitConfig.Repos[i].Repo == "local" {
 			foundLocalRepo = true
-			for _, hook := range preCommitConfig.Repos[i].Hooks {
-				if hook.ID == "cx-secret-detection" {
-					return nil // already present
-				}
-			}
-			preCommitConfig.Repos[i].Hooks = append(preCommitConfig.Repos[i].Hooks, config.PreloadedConfig.Repos[0].Hooks[0])
+			preCommitConfig.Repos[i].Hooks = addMissingHooks(preCommitConfig.Repos[i].Hooks)
 			break
 		}
 	}
@@ -144,3 +152,17 @@ func updateConfigFile(filePath string) error {
 
 	return nil
 }
+
+// addMissingHooks appends the preloaded Cx hooks that are not in hooks.
+func addMissingHooks(hooks []config.Hook) []config.Hook {
+	present := make(map[string]bool, len(hooks))
+	for _, hook := range hooks {
+		present[hook.ID] = true
+	}
+	for _, hook := range config.PreloadedConfig.Repos[0].Hooks {
+		if !present[hook.ID] {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks
+}
@@ -1,110 +1,287 @@
 package pre_commit
 
 import (
+	"context"
 	"fmt"
+	"github.com/Checkmarx/secret-detection/pkg/config"
 	"github.com/Checkmarx/secret-detection/pkg/parser"
 	"github.com/Checkmarx/secret-detection/pkg/report"
+	secretscanner "github.com/Checkmarx/secret-detection/pkg/scanner"
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/Checkmarx/secret-detection/pkg/suppress"
+	"github.com/Checkmarx/secret-detection/pkg/verify"
 	"github.com/checkmarx/2ms/v3/lib/reporting"
 	twoms "github.com/checkmarx/2ms/v3/pkg"
 	"github.com/fatih/color"
 	"github.com/rs/zerolog"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
 )
 
-// Scan is the entry point for the secret scanning hook.
-func Scan() error {
+// Scan is the entry point for the secret scanning hook. It scans the staged changes.
+func Scan(ctx context.Context) error {
+	return ScanWithOptions(ctx, ScanOptions{})
+}
+
+// ScanWithOptions scans the content selected by opts and reports the findings like the hook does. The
+// process exits with status 1 when the findings block the commit. The scan stops when ctx is done or on
+// SIGINT and SIGTERM.
+func ScanWithOptions(ctx context.Context, opts ScanOptions) error {
+	ctx, stop := secretscanner.WithSignals(ctx)
+	defer stop()
 	color.NoColor = false
 
-	scanReport, fileDiffs, err := runSecretScan()
+	streams := Streams{Out: os.Stdout}
+	if tty := openTerminal(); tty != nil {
+		defer tty.Close()
+		streams.TriageIn, streams.TriageOut = tty.in, tty.out
+	}
+	result, err := Run(ctx, opts, streams)
 	if err != nil {
-		return fmt.Errorf("failed to run scan: %w", err)
+		return err
 	}
-
-	if scanReport.TotalSecretsFound > 0 {
-		report.PrintGitDiffReport(scanReport, fileDiffs)
+	if result.Blocked() {
 		os.Exit(1)
 	}
 	return nil
 }
 
-// runSecretScan executes the secret scan workflow.
-func runSecretScan() (*reporting.Report, map[string][]parser.Hunk, error) {
-	zerolog.SetGlobalLevel(zerolog.Disabled)
+// Streams are the input and output of a scan run by Run.
+type Streams struct {
+	// Out receives the report and the other messages of the scan; they are discarded when it is nil.
+	Out io.Writer
+	// TriageIn and TriageOut, when both are set, triage the findings that block a commit interactively,
+	// unless the configuration disables it. The hook uses the terminal, as git leaves it no standard input.
+	TriageIn  io.Reader
+	TriageOut io.Writer
+}
 
-	// Execute the diff parsing workflow.
-	fileDiffs, err := runDiffParsing()
+// Run scans the content selected by opts and writes the report to streams.Out, without printing to
+// the console or exiting, so that the scan can be embedded. The caller decides what a blocking result
+// means; the scan stops when ctx is done.
+func Run(ctx context.Context, opts ScanOptions, streams Streams) (*report.Result, error) {
+	scanConfig, err := loadScanConfig()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+	var rendered strings.Builder
+	out := io.Writer(&rendered)
+	if streams.Out != nil {
+		out = io.MultiWriter(streams.Out, &rendered)
+	}
+
+	scanReport, fileDiffs, removed, err := runSecretScan(ctx, scanConfig, opts, out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run scan: %w", err)
+	}
+	result := &report.Result{Suppressed: removed.suppressed, Baselined: removed.baselined}
+	if removed.suppressed > 0 {
+		color.New(color.FgHiBlack).Fprintf(out, "%d finding(s) suppressed by inline allow-comments\n", removed.suppressed) // nolint:errcheck
 	}
+	if removed.baselined > 0 {
+		color.New(color.FgHiBlack).Fprintf(out, "%d finding(s) accepted by the baseline\n", removed.baselined) // nolint:errcheck
+	}
+
+	result.Decision = scanConfig.Severity.Apply(scanReport)
+	if scanReport.TotalSecretsFound > 0 {
+		if scanConfig.Validity.Enabled {
+			verify.Apply(ctx, verify.NewHTTPVerifier(scanConfig.Validity), scanReport)
+			result.Decision = scanConfig.Validity.Decide(result.Decision, scanReport, currentBranch())
+		}
+		result.Findings = report.RedactedFindings(scanReport, scanConfig.Redaction)
+		reportOptions := report.Options{
+			Redaction: scanConfig.Redaction,
+			Settings:  scanConfig.Report,
+			Severity:  scanConfig.Severity,
+			Decision:  result.Decision,
+		}
+		interactive := streams.TriageIn != nil && streams.TriageOut != nil && !scanConfig.DisableInteractive
+		if result.Decision == severity.Block && interactive && opts.commit() {
+			resolved, err := triage(scanReport, fileDiffs, reportOptions, streams.TriageIn, streams.TriageOut, defaultTriageActions)
+			if err != nil {
+				return nil, err
+			}
+			if resolved {
+				// The findings were ignored or unstaged, so none of them is committed.
+				result.Decision = severity.Pass
+				color.New(color.FgGreen).Fprintln(out, "All findings were resolved, continuing the commit.") // nolint:errcheck
+			}
+			result.Rendered = rendered.String()
+			return result, nil
+		}
+		report.PrintGitDiffReport(out, scanReport, fileDiffs, reportOptions)
+		if result.Decision != severity.Block {
+			color.New(color.FgYellow).Fprintln(out, "Commit allowed: none of the detected secrets meet the blocking policy.") // nolint:errcheck
+		}
+	}
+	result.Rendered = rendered.String()
+	return result, nil
+}
+
+// removedFindings counts the findings removed from the report of a scan before it is shown.
+type removedFindings struct {
+	// suppressed findings have an inline allow-comment.
+	suppressed int
+	// baselined findings are in the baseline.
+	baselined int
+}
+
+// runSecretScan executes the secret scan workflow. The files are scanned while they are collected;
+// only where their hunks start and the inline allow-comments of their lines are kept, and the content
+// of the files with findings is read again for the report.
+func runSecretScan(ctx context.Context, scanConfig PreCommitScanConfig, opts ScanOptions, out io.Writer) (*reporting.Report, map[string][]parser.Hunk, removedFindings, error) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
 
-	ignoredIDs, err := getIgnoredResultIds()
+	ignoreList, err := loadIgnoreList()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, removedFindings{}, err
 	}
+	accepted, err := scanConfig.loadBaseline()
+	if err != nil {
+		return nil, nil, removedFindings{}, err
+	}
+	ignoredIDs := append(ignoreList.ResultIDs(), scanConfig.IgnoreSecret...)
 
-	// Create the secrets scanner.
-	scanner := twoms.NewScanner()
-	itemsCh := make(chan twoms.ScanItem)
-	reportCh := make(chan *reporting.Report)
-	errScanCh := make(chan error, 1)
+	layouts := make(map[string][]parser.Hunk)
+	markers := suppress.Index{}
+	rep, err := scanItems(ctx, scanConfig, ignoredIDs, func(ctx context.Context, itemsCh chan<- twoms.ScanItem) error {
+		// Collect the content to scan, as diff hunks, in a separate goroutine.
+		files := make(chan parser.FileDiff)
+		errCollectCh := make(chan error, 1)
+		go func() {
+			defer close(files)
+			errCollectCh <- collectFiles(ctx, scanConfig, opts, files, out)
+		}()
 
-	// Start the scanning in a separate goroutine.
-	go func() {
-		report, err := scanner.ScanDynamic(itemsCh, twoms.ScanConfig{IgnoreResultIds: ignoredIDs})
-		if err != nil {
-			errScanCh <- err
-			return
+		var errSend error
+		for file := range files {
+			// After a failed send, the files are drained until the collection, stopped by ctx, ends.
+			if errSend == nil {
+				layouts[file.Path] = hunkLayout(file.Hunks)
+				trackMarkers(markers, file)
+				errSend = sendFileForScanning(ctx, file, itemsCh)
+			}
 		}
-		reportCh <- report
-	}()
+		if err := <-errCollectCh; err != nil {
+			return err
+		}
+		return errSend
+	})
+	if err != nil {
+		return nil, nil, removedFindings{}, err
+	}
+	ignoreList.Apply(rep)
+	removed := removedFindings{baselined: accepted.Apply(rep)}
+	removed.suppressed = suppressInlineFindings(rep, markers, filesWithFindings(layouts, rep))
+	fileDiffs, err := reloadHunks(ctx, scanConfig, opts, filesWithFindings(layouts, rep))
+	if err != nil {
+		return nil, nil, removedFindings{}, err
+	}
+	return rep, fileDiffs, removed, nil
+}
+
+// filesWithFindings returns the hunks of the files that have findings, which the report shows.
+func filesWithFindings(fileDiffs map[string][]parser.Hunk, rep *reporting.Report) map[string][]parser.Hunk {
+	kept := make(map[string][]parser.Hunk)
+	for _, results := range rep.Results {
+		for _, secret := range results {
+			if hunks, ok := fileDiffs[secret.Source]; ok {
+				kept[secret.Source] = hunks
+			}
+		}
+	}
+	return kept
+}
 
-	// For each file, send its secret diff content for scanning.
-	for file, hunks := range fileDiffs {
-		sendDiffContentForScanning(file, hunks, itemsCh)
+// hunkLayout returns the hunks without their content: the line each one starts at and its size.
+func hunkLayout(hunks []parser.Hunk) []parser.Hunk {
+	layout := make([]parser.Hunk, len(hunks))
+	for i, hunk := range hunks {
+		layout[i] = parser.Hunk{StartLine: hunk.StartLine, Size: hunk.Size}
 	}
-	close(itemsCh)
+	return layout
+}
 
-	// Wait for the scanner to finish.
-	select {
-	case rep := <-reportCh:
-		return rep, fileDiffs, nil
-	case err := <-errScanCh:
-		return nil, nil, err
+// newScanner creates the secrets scanner; tests replace it to inject failures.
+var newScanner = func(config secretscanner.Config) (secretscanner.ItemScanner, error) {
+	return secretscanner.New(config)
+}
+
+// scanItems scans the items sent by produce with the rules of the configuration.
+func scanItems(ctx context.Context, scanConfig PreCommitScanConfig, ignoredIDs []string, produce secretscanner.Producer) (*reporting.Report, error) {
+	scanner, err := newScanner(secretscanner.Config{
+		IgnoreResultIds: ignoredIDs,
+		Selection:       scanConfig.ruleSelection(),
+		CustomRules:     scanConfig.CustomRules,
+		Entropy:         scanConfig.Entropy,
+	})
+	if err != nil {
+		return nil, err
 	}
+	return secretscanner.Run(ctx, scanner, produce)
 }
 
-// runDiffParsing executes the git diff command and returns the parsed file diffs.
-func runDiffParsing() (map[string][]parser.Hunk, error) {
-	cmd := exec.Command("git", "diff", "--unified=0", "--staged")
+// runDiffParsing executes the git diff command on the staged changes of the paths, or of the whole
+// repository when there are none, and sends each parsed file diff to out. The changes that are not
+// scanned are listed on messages.
+func runDiffParsing(ctx context.Context, scanConfig PreCommitScanConfig, out chan<- parser.FileDiff, messages io.Writer, paths ...string) error {
+	// Pin the options that user configuration could change in a way the parser does not expect.
+	args := []string{"diff", "--unified=0", "--staged", "--no-color", "--no-ext-diff", "--submodule=short",
+		"--src-prefix=a/", "--dst-prefix=b/", "--"}
+	if len(paths) == 0 {
+		paths = []string{wholeRepository}
+	}
+	args = append(args, paths...)
+	args = append(args, config.ExcludesToGitPathspecs(scanConfig.ExcludePath)...)
+	cmd := exec.CommandContext(ctx, "git", args...)
 	pipe, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get stdout pipe: %w", err)
+		return fmt.Errorf("failed to get stdout pipe: %w", err)
 	}
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("failed to start git diff: %w", err)
+		return fmt.Errorf("failed to start git diff: %w", err)
 	}
 	parser := parser.NewDiffParser()
+	parser.MaxFileDiffSize = scanConfig.maxFileDiffSize()
+	parser.Output = out
 	if err := parser.ParseDiffStream(pipe); err != nil {
-		return nil, err
+		return err
 	}
 	if err := cmd.Wait(); err != nil {
-		return nil, fmt.Errorf("git diff command failed: %w", err)
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		return fmt.Errorf("git diff command failed: %w", err)
 	}
-	return parser.FileDiffs, nil
+	printUnscannedChanges(messages, "Binary files", parser.Binary)
+	printUnscannedChanges(messages, "Submodules", parser.Submodules)
+	return nil
 }
 
-// sendDiffContentForScanning sends the concatenated hunk content of a file to the scan channel.
-func sendDiffContentForScanning(file string, hunks []parser.Hunk, items chan<- twoms.ScanItem) {
-	var builder strings.Builder
-	for _, hunk := range hunks {
-		builder.WriteString(hunk.Content)
+// printUnscannedChanges lists the changed paths whose content is not scanned.
+func printUnscannedChanges(w io.Writer, kind string, paths []string) {
+	if len(paths) > 0 {
+		color.New(color.FgHiBlack).Fprintf(w, "%s not scanned: %s\n", kind, strings.Join(paths, ", ")) // nolint:errcheck
 	}
-	content := builder.String()
-	items <- twoms.ScanItem{
+}
+
+// sendFileForScanning sends the added content of a file to the scan channel.
+func sendFileForScanning(ctx context.Context, file parser.FileDiff, items chan<- twoms.ScanItem) error {
+	content := file.Content
+	return secretscanner.Send(ctx, items, twoms.ScanItem{
 		Content: &content,
-		ID:      fmt.Sprintf("hooks-%s", file),
-		Source:  file,
+		ID:      fmt.Sprintf("hooks-%s", file.Path),
+		Source:  file.Path,
+	})
+}
+
+// currentBranch returns the full ref name of the checked-out branch, or nil on a detached HEAD.
+func currentBranch() []string {
+	out, err := exec.Command("git", "symbolic-ref", "-q", "HEAD").Output()
+	if err != nil {
+		return nil
 	}
+	return []string{strings.TrimSpace(string(out))}
 }
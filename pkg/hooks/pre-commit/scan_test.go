@@ -0,0 +1,315 @@
+package pre_commit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Checkmarx/secret-detection/pkg/ignore"
+	"github.com/Checkmarx/secret-detection/pkg/parser"
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	secretscanner "github.com/Checkmarx/secret-detection/pkg/scanner"
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/Checkmarx/secret-detection/pkg/verify"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilesWithFindings(t *testing.T) {
+	fileDiffs := map[string][]parser.Hunk{
+		"clean.go":  {{StartLine: 1, Content: "package clean\n", Size: 1}},
+		"secret.go": {{StartLine: 3, Content: "token := \"ghp_token\"\n", Size: 1}},
+	}
+	rep := &reporting.Report{
+		TotalSecretsFound: 2,
+		Results: map[string][]*secrets.Secret{
+			"1": {{ID: "1", Source: "secret.go", StartLine: 3}},
+			"2": {{ID: "2", Source: "secret.go", StartLine: 3}},
+		},
+	}
+
+	assert.Equal(t, map[string][]parser.Hunk{"secret.go": fileDiffs["secret.go"]}, filesWithFindings(fileDiffs, rep))
+	assert.Empty(t, filesWithFindings(fileDiffs, &reporting.Report{}))
+}
+
+var errScanner = errors.New("scanner failed")
+
+// failingScanner fails after reading the given number of items. It returns an empty report when the
+// items channel is closed first.
+type failingScanner struct{ reads int }
+
+func (s failingScanner) ScanDynamic(itemsCh <-chan twoms.ScanItem) (*reporting.Report, error) {
+	for i := 0; i < s.reads; i++ {
+		if _, ok := <-itemsCh; !ok {
+			return reporting.Init(), nil
+		}
+	}
+	return nil, errScanner
+}
+
+func TestRunSecretScanStops(t *testing.T) {
+	t.Chdir(t.TempDir())
+	out, err := exec.Command("git", "init", "-q").CombinedOutput()
+	assert.NoError(t, err, string(out))
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, os.WriteFile(fmt.Sprintf("file%d.txt", i), []byte(fmt.Sprintf("line %d\n", i)), 0644))
+	}
+	out, err = exec.Command("git", "add", ".").CombinedOutput()
+	assert.NoError(t, err, string(out))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		opts    ScanOptions
+		scanner secretscanner.ItemScanner
+		err     error
+	}{
+		{"scanner fails before reading", context.Background(), ScanOptions{}, failingScanner{}, errScanner},
+		{"scanner fails while reading", context.Background(), ScanOptions{}, failingScanner{reads: 5}, errScanner},
+		{"scanner fails while reading whole files", context.Background(), ScanOptions{AllFiles: true}, failingScanner{reads: 5}, errScanner},
+		{"canceled", canceled, ScanOptions{}, failingScanner{reads: 1 << 30}, context.Canceled},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			restore := newScanner
+			newScanner = func(secretscanner.Config) (secretscanner.ItemScanner, error) { return tc.scanner, nil }
+			t.Cleanup(func() { newScanner = restore })
+
+			done := make(chan error, 1)
+			go func() {
+				_, _, _, err := runSecretScan(tc.ctx, PreCommitScanConfig{}, tc.opts, io.Discard)
+				done <- err
+			}()
+			select {
+			case err := <-done:
+				assert.ErrorIs(t, err, tc.err)
+			case <-time.After(10 * time.Second):
+				t.Fatal("the scan did not terminate")
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	t.Chdir(t.TempDir())
+	out, err := exec.Command("git", "init", "-q").CombinedOutput()
+	assert.NoError(t, err, string(out))
+	config := "custom_rules:\n  - id: acme-key\n    regex: 'acme_[a-f0-9]{32}'\n"
+	assert.NoError(t, os.WriteFile(configFileName, []byte(config), 0644))
+
+	var rendered bytes.Buffer
+	result, err := Run(context.Background(), ScanOptions{}, Streams{Out: &rendered})
+	assert.NoError(t, err)
+	assert.Equal(t, severity.Pass, result.Decision, "nothing is staged")
+	assert.Empty(t, result.Findings)
+	assert.Empty(t, rendered.String())
+
+	assert.NoError(t, os.WriteFile("config.env", []byte("ACME_KEY=acme_0123456789abcdef0123456789abcdef\n"), 0644))
+	out, err = exec.Command("git", "add", "config.env").CombinedOutput()
+	assert.NoError(t, err, string(out))
+
+	// Without a terminal to triage them, the blocking findings are reported.
+	result, err = Run(context.Background(), ScanOptions{}, Streams{Out: &rendered})
+	assert.NoError(t, err)
+	assert.True(t, result.Blocked())
+	var rules []string
+	for _, finding := range result.Findings {
+		rules = append(rules, finding.RuleID)
+		assert.Equal(t, "config.env", finding.Source)
+	}
+	assert.Contains(t, rules, "acme-key")
+	assert.Equal(t, rendered.String(), result.Rendered)
+	assert.Contains(t, result.Rendered, "Options for proceeding with the commit")
+
+	// The output is optional.
+	result, err = Run(context.Background(), ScanOptions{}, Streams{})
+	assert.NoError(t, err)
+	assert.Contains(t, result.Rendered, "config.env")
+}
+
+func TestRunReportsFilesWithFindings(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := func(args ...string) {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	git("config", "user.email", "dev@example.com")
+	git("config", "user.name", "dev")
+	assert.NoError(t, os.WriteFile("app.env", []byte("HEADER=1\n# cx:ignore-secret\nPLAIN=1\n"), 0644))
+	git("add", ".")
+	git("commit", "-qm", "Add the configuration")
+	config := "custom_rules:\n  - id: acme-key\n    regex: 'acme_[a-f0-9]{32}'\n"
+	assert.NoError(t, os.WriteFile(configFileName, []byte(config), 0644))
+
+	// The first line of the hunk is annotated by the line before it, which is not part of the diff.
+	content := "HEADER=1\n# cx:ignore-secret\nSUPPRESSED=acme_0123456789abcdef0123456789abcdef\nCONTEXT=1\nLEAKED=acme_fedcba9876543210fedcba9876543210\n"
+	assert.NoError(t, os.WriteFile("app.env", []byte(content), 0644))
+	git("add", "app.env")
+
+	result, err := Run(context.Background(), ScanOptions{}, Streams{})
+	assert.NoError(t, err)
+	assert.True(t, result.Blocked())
+	assert.Positive(t, result.Suppressed)
+	var lines []int
+	for _, finding := range result.Findings {
+		if finding.RuleID == "acme-key" {
+			lines = append(lines, finding.StartLine)
+		}
+	}
+	assert.Equal(t, []int{2}, lines, "only the finding on the last line of the hunk is left")
+	// The content of the file is read again for the context of the finding.
+	assert.Contains(t, result.Rendered, "Line 5")
+	assert.Contains(t, result.Rendered, "CONTEXT=1")
+}
+
+func TestRunAllowCommentsOfOtherRules(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := func(args ...string) {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	git("config", "user.email", "dev@example.com")
+	git("config", "user.name", "dev")
+	assert.NoError(t, os.WriteFile("app.env", []byte("HEADER=1\n# cx-secret-allow rule=acme-key reason=fixture\nPLAIN=1\n"), 0644))
+	git("add", ".")
+	git("commit", "-qm", "Add the configuration")
+	config := "custom_rules:\n  - id: acme-key\n    regex: 'acme_[a-f0-9]{32}'\n"
+	assert.NoError(t, os.WriteFile(configFileName, []byte(config), 0644))
+
+	// The allow-comment of the line does not cover the acme-key finding, the one of the line before does.
+	content := "HEADER=1\n# cx-secret-allow rule=acme-key reason=fixture\nKEY=acme_0123456789abcdef0123456789abcdef # cx-secret-allow rule=jwt\n"
+	assert.NoError(t, os.WriteFile("app.env", []byte(content), 0644))
+	git("add", "app.env")
+
+	result, err := Run(context.Background(), ScanOptions{}, Streams{})
+	assert.NoError(t, err)
+	for _, finding := range result.Findings {
+		assert.NotEqual(t, "acme-key", finding.RuleID)
+	}
+	assert.Positive(t, result.Suppressed)
+}
+
+func TestHunkStartLine(t *testing.T) {
+	hunks := []parser.Hunk{{StartLine: 3, Size: 2}, {StartLine: 10, Size: 1}}
+	tests := []struct {
+		localLine int
+		startLine int
+		ok        bool
+	}{
+		{0, 3, true},
+		{1, 0, false},
+		{2, 10, true},
+		{3, 0, false},
+	}
+	for _, tc := range tests {
+		startLine, ok := hunkStartLine(hunks, tc.localLine)
+		assert.Equal(t, tc.startLine, startLine)
+		assert.Equal(t, tc.ok, ok)
+	}
+}
+
+func TestRunValidityFromUserConfig(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	validity := "validity:\n  enabled: true\n  checks:\n    - rule_id: acme-key\n      base_url: " + server.URL + "\n      allow_http: true\n      path: /{{secret}}\n"
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	out, err := exec.Command("git", "init", "-q").CombinedOutput()
+	assert.NoError(t, err, string(out))
+	config := "custom_rules:\n  - id: acme-key\n    regex: 'acme_[a-f0-9]{32}'\n" + validity
+	assert.NoError(t, os.WriteFile(configFileName, []byte(config), 0644))
+	assert.NoError(t, os.WriteFile("config.env", []byte("ACME_KEY=acme_0123456789abcdef0123456789abcdef\n"), 0644))
+	out, err = exec.Command("git", "add", "config.env").CombinedOutput()
+	assert.NoError(t, err, string(out))
+
+	// The repository does not choose where the secrets are sent.
+	result, err := Run(context.Background(), ScanOptions{}, Streams{})
+	assert.NoError(t, err)
+	assert.True(t, result.Blocked())
+	assert.Zero(t, requests.Load())
+
+	userConfig, err := verify.UserConfigPath()
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(userConfig), 0755))
+	assert.NoError(t, os.WriteFile(userConfig, []byte(validity), 0644))
+	result, err = Run(context.Background(), ScanOptions{}, Streams{})
+	assert.NoError(t, err)
+	assert.True(t, result.Blocked())
+	assert.NotZero(t, requests.Load())
+	for _, finding := range result.Findings {
+		if finding.RuleID == "acme-key" {
+			assert.Equal(t, secrets.ValidResult, finding.ValidationStatus)
+		}
+	}
+}
+
+func TestRunFromSubdirectory(t *testing.T) {
+	t.Chdir(t.TempDir())
+	git := func(args ...string) {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		assert.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	key := []byte("ACME_KEY=acme_0123456789abcdef0123456789abcdef\n")
+	assert.NoError(t, os.Mkdir("sub", 0755))
+	assert.NoError(t, os.WriteFile("root.env", key, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join("sub", "sub.env"), key, 0644))
+	assert.NoError(t, os.WriteFile("excluded.env", key, 0644))
+	config := "exclude_path: [excluded.env]\ncustom_rules:\n  - id: acme-key\n    regex: 'acme_[a-f0-9]{32}'\n"
+	git("add", ".")
+	// The configuration, ignore and baseline files are read from the root of the repository.
+	assert.NoError(t, os.WriteFile(configFileName, []byte(config), 0644))
+	t.Chdir("sub")
+
+	findings := func(result *report.Result) map[string]string {
+		found := make(map[string]string)
+		for _, finding := range result.Findings {
+			if finding.RuleID == "acme-key" {
+				found[finding.Source] = finding.ID
+			}
+		}
+		return found
+	}
+	result, err := Run(context.Background(), ScanOptions{}, Streams{})
+	assert.NoError(t, err)
+	staged := findings(result)
+	assert.Len(t, staged, 2)
+	assert.Contains(t, staged, "root.env")
+	assert.Contains(t, staged, "sub/sub.env")
+
+	// Whole files are reported from the root of the repository too, with the IDs of the staged changes.
+	result, err = Run(context.Background(), ScanOptions{AllFiles: true}, Streams{})
+	assert.NoError(t, err)
+	assert.Equal(t, staged, findings(result))
+
+	result, err = Run(context.Background(), ScanOptions{Files: []string{"sub.env", "../root.env"}}, Streams{})
+	assert.NoError(t, err)
+	assert.Equal(t, staged, findings(result))
+	assert.NoError(t, os.WriteFile(filepath.Join("..", ignore.FileName), []byte("rule:acme-key path=\"sub/*\"\n"), 0644))
+	result, err = Run(context.Background(), ScanOptions{}, Streams{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"root.env": staged["root.env"]}, findings(result))
+}
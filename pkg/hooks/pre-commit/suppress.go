@@ -0,0 +1,85 @@
+package pre_commit
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/parser"
+	"github.com/Checkmarx/secret-detection/pkg/suppress"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+)
+
+// trackMarkers records the inline allow-comments of the scanned lines of the file in markers, so that
+// its content is not needed once it is scanned.
+func trackMarkers(markers suppress.Index, file parser.FileDiff) {
+	track := markers.NewTrack(file.Path)
+	for _, hunk := range file.Hunks {
+		// The line before a hunk is not scanned; it is read from the staged file when a finding
+		// needs it.
+		track.Context("")
+		rest := hunk.Content
+		for i := 0; i < hunk.Size; i++ {
+			var line string
+			line, rest, _ = strings.Cut(rest, "\n")
+			track.Scanned(line)
+		}
+	}
+}
+
+// suppressInlineFindings removes the findings annotated with an inline allow-comment, on the line of
+// the finding or the line before it, and returns how many were removed. markers holds the comments of
+// the scanned lines; the line before the first line of a hunk is read from the staged file, whose
+// layout gives where the hunks start.
+func suppressInlineFindings(scanReport *reporting.Report, markers suppress.Index, layouts map[string][]parser.Hunk) int {
+	stagedFiles := make(map[string][]string)
+	for _, list := range scanReport.Results {
+		for _, secret := range list {
+			if markers.Allows(secret.Source, secret.StartLine, secret.RuleID) {
+				continue
+			}
+			startLine, ok := hunkStartLine(layouts[secret.Source], secret.StartLine)
+			if !ok {
+				continue
+			}
+			if marker, ok := suppress.Parse(stagedLine(secret.Source, startLine-1, stagedFiles)); ok {
+				markers.Add(secret.Source, secret.StartLine, marker)
+			}
+		}
+	}
+	return markers.Apply(scanReport)
+}
+
+// hunkStartLine returns the file line of the hunk whose first scanned line is localLine, the index of
+// a line of the file's concatenated hunks.
+func hunkStartLine(hunks []parser.Hunk, localLine int) (int, bool) {
+	cumulative := 0
+	for _, hunk := range hunks {
+		if localLine == cumulative {
+			return hunk.StartLine, true
+		}
+		cumulative += hunk.Size
+		if localLine < cumulative {
+			break
+		}
+	}
+	return 0, false
+}
+
+// stagedLine returns the 1-based line of the staged version of file, caching the file content.
+func stagedLine(file string, lineNumber int, stagedFiles map[string][]string) string {
+	if lineNumber < 1 {
+		return ""
+	}
+	lines, ok := stagedFiles[file]
+	if !ok {
+		out, err := exec.Command("git", "show", ":"+file).Output()
+		if err == nil {
+			lines = strings.Split(string(out), "\n")
+		}
+		stagedFiles[file] = lines
+	}
+	if lineNumber > len(lines) {
+		return ""
+	}
+	return lines[lineNumber-1]
+}
@@ -0,0 +1,250 @@
+package pre_commit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/parser"
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+)
+
+// triageActions performs the resolutions chosen during triage; tests replace them.
+type triageActions struct {
+	ignore  func(resultID, reason string) error
+	unstage func(file string) error
+	edit    func(file string, line int) error
+}
+
+var defaultTriageActions = triageActions{
+	ignore: func(resultID, reason string) error {
+		return IgnoreWithOptions(IgnoreOptions{ResultIds: []string{resultID}, Reason: reason})
+	},
+	unstage: unstageFile,
+	edit:    openInEditor,
+}
+
+// triage walks the developer through the findings of the report and returns whether every finding
+// was resolved, either by ignoring it or by unstaging its file. The chosen resolutions are applied
+// once every finding has been reviewed; nothing is applied when the developer aborts.
+func triage(
+	scanReport *reporting.Report,
+	fileDiffs map[string][]parser.Hunk,
+	opts report.Options,
+	in io.Reader,
+	out io.Writer,
+	actions triageActions,
+) (bool, error) {
+	secretsByFile := report.SortedSecretsByFile(scanReport.Results)
+	files := make([]string, 0, len(secretsByFile))
+	for file := range secretsByFile {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	reader := bufio.NewReader(in)
+	ignored := make(map[string]string) // result ID -> reason
+	var unstaged []string
+	resolved := true
+	position := 0
+
+filesLoop:
+	for _, file := range files {
+		fileSecrets := secretsByFile[file]
+		for i, secret := range fileSecrets {
+			position++
+			line, context := report.FindingContext(secret, fileSecrets, fileDiffs[file], opts)
+			printFinding(out, secret, file, line, context, position, scanReport.TotalSecretsFound, opts)
+
+			for {
+				choice, err := prompt(reader, out, "[i]gnore, [u]nstage file, [e]dit, [s]kip, [a]bort: ")
+				if err != nil {
+					return false, err
+				}
+				switch strings.ToLower(choice) {
+				case "i", "ignore":
+					reason, err := promptReason(reader, out)
+					if err != nil {
+						return false, err
+					}
+					ignored[secret.ID] = reason
+				case "u", "unstage":
+					unstaged = append(unstaged, file)
+					// Unstaging the file resolves its remaining findings as well.
+					position += len(fileSecrets) - i - 1
+					continue filesLoop
+				case "e", "edit":
+					if err := actions.edit(file, line); err != nil {
+						fmt.Fprintf(out, "Failed to open the editor: %v\n", err) // nolint:errcheck
+						continue
+					}
+					// The fix needs to be staged and scanned again.
+					resolved = false
+				case "s", "skip":
+					resolved = false
+				case "a", "abort":
+					fmt.Fprintln(out, "Aborted: no finding was ignored or unstaged.") // nolint:errcheck
+					return false, nil
+				default:
+					continue
+				}
+				break
+			}
+		}
+	}
+
+	for _, id := range sortedIDs(ignored) {
+		if err := actions.ignore(id, ignored[id]); err != nil {
+			return false, err
+		}
+	}
+	for _, file := range unstaged {
+		if err := actions.unstage(file); err != nil {
+			return false, err
+		}
+		fmt.Fprintf(out, "Unstaged %s\n", file) // nolint:errcheck
+	}
+	if !resolved {
+		fmt.Fprintln(out, "Some findings are unresolved: stage your fixes and commit again.") // nolint:errcheck
+	}
+	return resolved, nil
+}
+
+func printFinding(out io.Writer, secret *secrets.Secret, file string, line int, context string, position, total int, opts report.Options) {
+	fmt.Fprintf(out, "\nFinding %d of %d\n", position, total)                                    // nolint:errcheck
+	fmt.Fprintf(out, "\tFile: %s\n", color.HiYellowString(file))                                 // nolint:errcheck
+	fmt.Fprintf(out, "\tSecret detected: %s\n", color.HiYellowString(secret.RuleID))             // nolint:errcheck
+	fmt.Fprintf(out, "\tResult ID: %s\n", color.HiYellowString(secret.ID))                       // nolint:errcheck
+	fmt.Fprintf(out, "\tSeverity: %s\n", color.HiYellowString(string(opts.Severity.Of(secret)))) // nolint:errcheck
+	fmt.Fprintf(out, "\tLocation: %s\n", color.HiYellowString("Line %d", line))                  // nolint:errcheck
+	fmt.Fprint(out, context)                                                                     // nolint:errcheck
+}
+
+func prompt(reader *bufio.Reader, out io.Writer, question string) (string, error) {
+	fmt.Fprint(out, question) // nolint:errcheck
+	answer, err := reader.ReadString('\n')
+	if err != nil && (err != io.EOF || answer == "") {
+		return "", fmt.Errorf("failed to read the answer: %w", err)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+func promptReason(reader *bufio.Reader, out io.Writer) (string, error) {
+	for {
+		reason, err := prompt(reader, out, "Reason: ")
+		if err != nil || reason != "" {
+			return reason, err
+		}
+		fmt.Fprintln(out, "A reason is required to ignore a finding.") // nolint:errcheck
+	}
+}
+
+func sortedIDs(ids map[string]string) []string {
+	keys := make([]string, 0, len(ids))
+	for id := range ids {
+		keys = append(keys, id)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// terminal is the controlling terminal of the process, which remains available to hooks whose
+// standard streams are redirected.
+type terminal struct {
+	in, out *os.File
+}
+
+// openTerminal opens the terminal, or returns nil when there is none.
+func openTerminal() *terminal {
+	if runtime.GOOS == "windows" {
+		in, err := os.OpenFile("CONIN$", os.O_RDWR, 0)
+		if err != nil {
+			return nil
+		}
+		out, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+		if err != nil {
+			in.Close() // nolint:errcheck
+			return nil
+		}
+		return &terminal{in: in, out: out}
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil
+	}
+	if !isatty.IsTerminal(tty.Fd()) && !isatty.IsCygwinTerminal(tty.Fd()) {
+		tty.Close() // nolint:errcheck
+		return nil
+	}
+	return &terminal{in: tty, out: tty}
+}
+
+func (t *terminal) Close() {
+	t.in.Close() // nolint:errcheck
+	if t.out != t.in {
+		t.out.Close() // nolint:errcheck
+	}
+}
+
+// unstageFile removes the staged changes of the file from the index.
+func unstageFile(file string) error {
+	cmd := exec.Command("git", "reset", "-q", "--", file)
+	if err := exec.Command("git", "rev-parse", "-q", "--verify", "HEAD").Run(); err != nil {
+		// There is no commit to reset to yet.
+		cmd = exec.Command("git", "rm", "-q", "--cached", "--", file)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to unstage %s: %s", file, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// openInEditor opens the file at the line in $VISUAL or $EDITOR, attached to the terminal.
+func openInEditor(file string, line int) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	fields := strings.Fields(editor)
+	cmd := exec.Command(fields[0], append(fields[1:], editorArgs(fields[0], file, line)...)...)
+
+	tty := openTerminal()
+	if tty == nil {
+		return fmt.Errorf("no terminal available")
+	}
+	defer tty.Close()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = tty.in, tty.out, tty.out
+	return cmd.Run()
+}
+
+// editorArgs returns the arguments that open the file at the line, for the editors known to support it.
+func editorArgs(editor, file string, line int) []string {
+	name := strings.TrimSuffix(filepath.Base(editor), ".exe")
+	switch name {
+	case "vi", "vim", "nvim", "nano", "emacs", "emacsclient", "micro", "kak", "hx", "joe", "mg":
+		return []string{"+" + strconv.Itoa(line), file}
+	case "code", "code-insiders", "codium", "cursor":
+		return []string{"--goto", file + ":" + strconv.Itoa(line)}
+	case "subl", "zed":
+		return []string{file + ":" + strconv.Itoa(line)}
+	default:
+		return []string{file}
+	}
+}
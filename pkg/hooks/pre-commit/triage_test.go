@@ -0,0 +1,116 @@
+package pre_commit
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/parser"
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriage(t *testing.T) {
+	newReport := func() (*reporting.Report, map[string][]parser.Hunk) {
+		scanReport := &reporting.Report{
+			TotalSecretsFound: 3,
+			Results: map[string][]*secrets.Secret{
+				"id-a1": {{ID: "id-a1", RuleID: "github-pat", Source: "a.txt", StartLine: 0, Value: "ghp_first"}},
+				"id-a2": {{ID: "id-a2", RuleID: "github-pat", Source: "a.txt", StartLine: 1, Value: "ghp_second"}},
+				"id-b1": {{ID: "id-b1", RuleID: "jwt", Source: "b.txt", StartLine: 0, Value: "eyJtoken"}},
+			},
+		}
+		fileDiffs := map[string][]parser.Hunk{
+			"a.txt": {{StartLine: 3, Size: 2, Content: "token=ghp_first\ntoken=ghp_second\n"}},
+			"b.txt": {{StartLine: 1, Size: 1, Content: "jwt=eyJtoken\n"}},
+		}
+		return scanReport, fileDiffs
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		resolved bool
+		ignored  []string
+		unstaged []string
+		edited   []string
+	}{
+		{
+			name:     "ignore every finding",
+			input:    "i\nfixture\ni\n\nfixture\ni\nsample\n",
+			resolved: true,
+			ignored:  []string{"id-a1:fixture", "id-a2:fixture", "id-b1:sample"},
+		},
+		{
+			name:     "unstaging resolves the remaining findings of the file",
+			input:    "u\nignore\nsample\n",
+			resolved: true,
+			ignored:  []string{"id-b1:sample"},
+			unstaged: []string{"a.txt"},
+		},
+		{
+			name:     "edited and skipped findings are unresolved",
+			input:    "x\ne\ns\nu\n",
+			resolved: false,
+			unstaged: []string{"b.txt"},
+			edited:   []string{"a.txt:3"},
+		},
+		{
+			name:     "abort applies nothing",
+			input:    "i\nfixture\na\n",
+			resolved: false,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var ignored, unstaged, edited []string
+			actions := triageActions{
+				ignore: func(resultID, reason string) error {
+					ignored = append(ignored, resultID+":"+reason)
+					return nil
+				},
+				unstage: func(file string) error {
+					unstaged = append(unstaged, file)
+					return nil
+				},
+				edit: func(file string, line int) error {
+					edited = append(edited, file+":"+strconv.Itoa(line))
+					return nil
+				},
+			}
+
+			scanReport, fileDiffs := newReport()
+			var out bytes.Buffer
+			resolved, err := triage(scanReport, fileDiffs, report.Options{}, strings.NewReader(tc.input), &out, actions)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.resolved, resolved)
+			assert.Equal(t, tc.ignored, ignored)
+			assert.Equal(t, tc.unstaged, unstaged)
+			assert.Equal(t, tc.edited, edited)
+			assert.Contains(t, out.String(), "Finding 1 of 3")
+			assert.NotContains(t, out.String(), "ghp_second", "the context must be redacted")
+		})
+	}
+}
+
+func TestTriageEndOfInput(t *testing.T) {
+	scanReport := &reporting.Report{
+		TotalSecretsFound: 1,
+		Results: map[string][]*secrets.Secret{
+			"id": {{ID: "id", RuleID: "jwt", Source: "a.txt", Value: "eyJtoken"}},
+		},
+	}
+	fileDiffs := map[string][]parser.Hunk{"a.txt": {{StartLine: 1, Size: 1, Content: "eyJtoken\n"}}}
+	resolved, err := triage(scanReport, fileDiffs, report.Options{}, strings.NewReader(""), &bytes.Buffer{}, triageActions{})
+	assert.Error(t, err)
+	assert.False(t, resolved)
+}
+
+func TestEditorArgs(t *testing.T) {
+	assert.Equal(t, []string{"+12", "a.txt"}, editorArgs("/usr/bin/vim", "a.txt", 12))
+	assert.Equal(t, []string{"--goto", "a.txt:12"}, editorArgs("code", "a.txt", 12))
+	assert.Equal(t, []string{"a.txt"}, editorArgs("gedit", "a.txt", 12))
+}
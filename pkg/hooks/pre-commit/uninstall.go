@@ -35,11 +35,11 @@ func uninstallLocal() error {
 		return fmt.Errorf("failed to unmarshal YAML: %v", err)
 	}
 
-	// Remove the cx-secret-detection hook from the repos
+	// Remove the Cx hooks from the repos
 	for i, repo := range preCommitConfig.Repos {
 		var updatedHooks []config.Hook
 		for _, hook := range repo.Hooks {
-			if hook.ID != "cx-secret-detection" {
+			if !config.IsCxHook(hook.ID) {
 				updatedHooks = append(updatedHooks, hook)
 			}
 		}
@@ -90,19 +90,19 @@ func uninstallGlobal() error {
 		globalHooksPath = filepath.Join(homeDir, ".git", "hooks")
 	}
 
-	// Path to the global pre-commit hook script
-	preCommitHookPath := filepath.Join(globalHooksPath, "pre-commit")
-
-	// Remove the pre-commit hook script if it exists
-	if _, err := os.Stat(preCommitHookPath); err == nil {
-		if err := os.Remove(preCommitHookPath); err != nil {
-			return fmt.Errorf("failed to remove global pre-commit hook: %v", err)
+	// Remove the hook scripts if they exist
+	for _, name := range []string{"pre-commit", "commit-msg", "pre-push"} {
+		hookPath := filepath.Join(globalHooksPath, name)
+		if _, err := os.Stat(hookPath); err == nil {
+			if err := os.Remove(hookPath); err != nil {
+				return fmt.Errorf("failed to remove global %s hook: %v", name, err)
+			}
+			fmt.Printf("Global %s hook removed successfully.\n", name)
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("error checking %s hook: %v", name, err)
+		} else {
+			fmt.Printf("No global %s hook found.\n", name)
 		}
-		fmt.Println("Global pre-commit hook removed successfully.")
-	} else if !os.IsNotExist(err) {
-		return fmt.Errorf("error checking pre-commit hook: %v", err)
-	} else {
-		fmt.Println("No global pre-commit hook found.")
 	}
 
 	// Unset the global core.hooksPath configuration
@@ -31,7 +31,7 @@ func updateLocal() error {
 	}
 
 	// Reinstall the pre-commit hooks to apply changes.
-	cmd := exec.Command("pre-commit", "install")
+	cmd := exec.Command("pre-commit", installHookTypes...)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("failed to reinstall local pre-commit hooks: %v\n%s", err, output)
@@ -59,7 +59,8 @@ func updateGlobal() error {
 	return nil
 }
 
-// updateHookInConfig updates the cx-secret-detection hook in the specified pre-commit config file.
+// updateHookInConfig refreshes the Cx hooks in the specified pre-commit config file and adds the ones
+// introduced since it was installed.
 func updateHookInConfig(configFilePath string) error {
 	data, err := os.ReadFile(configFilePath)
 	if err != nil {
@@ -71,16 +72,24 @@ func updateHookInConfig(configFilePath string) error {
 		return fmt.Errorf("failed to unmarshal YAML: %v", err)
 	}
 
+	preloaded := make(map[string]config.Hook)
+	for _, hook := range config.PreloadedConfig.Repos[0].Hooks {
+		preloaded[hook.ID] = hook
+	}
+
 	updated := false
 	for i := range preCommitConfig.Repos {
 		if preCommitConfig.Repos[i].Repo == "local" {
-			for j := range preCommitConfig.Repos[i].Hooks {
-				if preCommitConfig.Repos[i].Hooks[j].ID == "cx-secret-detection" {
-					preCommitConfig.Repos[i].Hooks[j] = config.PreloadedConfig.Repos[0].Hooks[0]
+			for j, hook := range preCommitConfig.Repos[i].Hooks {
+				if preloadedHook, ok := preloaded[hook.ID]; ok {
+					preCommitConfig.Repos[i].Hooks[j] = preloadedHook
 					updated = true
-					break
 				}
 			}
+			if updated {
+				preCommitConfig.Repos[i].Hooks = addMissingHooks(preCommitConfig.Repos[i].Hooks)
+				break
+			}
 		}
 	}
 
@@ -0,0 +1,182 @@
+package pre_push
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/baseline"
+	pre_receive "github.com/Checkmarx/secret-detection/pkg/hooks/pre-receive"
+	"github.com/Checkmarx/secret-detection/pkg/ignore"
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	secretscanner "github.com/Checkmarx/secret-detection/pkg/scanner"
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/Checkmarx/secret-detection/pkg/verify"
+)
+
+const (
+	zeroRev = "0000000000000000000000000000000000000000"
+	// configFileName is the repository-level configuration file, shared with the pre-commit hook.
+	configFileName = ".checkmarx.yaml"
+
+	// The pre-commit framework consumes the standard input of the hook and describes the push with
+	// these environment variables instead.
+	envFromRef      = "PRE_COMMIT_FROM_REF"
+	envToRef        = "PRE_COMMIT_TO_REF"
+	envRemoteName   = "PRE_COMMIT_REMOTE_NAME"
+	envRemoteBranch = "PRE_COMMIT_REMOTE_BRANCH"
+	envLocalBranch  = "PRE_COMMIT_LOCAL_BRANCH"
+)
+
+// Scan is the entry point of the pre-push hook. It scans the commits that the push sends to the remote
+// and reports the findings like the pre-receive hook, so that a push the server would reject is stopped
+// before it leaves the machine. remote is the name of the remote, the first argument git passes to the
+// hook; the pushed refs are read from the standard input. The process exits with status 1 when the
+// findings block the push. The scan stops when ctx is done or on SIGINT and SIGTERM.
+func Scan(ctx context.Context, remote string) error {
+	ctx, stop := secretscanner.WithSignals(ctx)
+	defer stop()
+	result, err := Run(ctx, remote, os.Stdin, os.Stdout)
+	if err != nil {
+		return err
+	}
+	if result.Blocked() {
+		os.Exit(1)
+	}
+	return nil
+}
+
+// Run scans the commits of the push described by the "localRef localRev remoteRef remoteRev" lines
+// read from in, or by the environment of the pre-commit framework, and writes the report to out, which
+// may be nil. It returns the result instead of exiting, so that the scan can be embedded. The scan
+// stops when ctx is done.
+func Run(ctx context.Context, remote string, in io.Reader, out io.Writer) (*report.Result, error) {
+	if remote == "" {
+		remote = os.Getenv(envRemoteName)
+	}
+	updates, ranges, err := pushedCommits(remote, in, revisionExists)
+	if err != nil {
+		return nil, fmt.Errorf("reading pushed refs: %w", err)
+	}
+	if len(ranges) == 0 {
+		result := &report.Result{Decision: severity.Pass, Rendered: "No new commits to scan\n"}
+		if out != nil {
+			if _, err = io.WriteString(out, result.Rendered); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+
+	ignoreList, err := ignore.Load(filepath.Join(".", ignore.FileName))
+	if err != nil {
+		return nil, err
+	}
+	accepted, err := baseline.Load(filepath.Join(".", baseline.FileName))
+	if err != nil {
+		return nil, err
+	}
+	validity, err := verify.LoadUserConfig()
+	if err != nil {
+		return nil, err
+	}
+	configPath := ""
+	if _, err = os.Stat(configFileName); err == nil {
+		configPath = configFileName
+	}
+	return pre_receive.RunCommits(ctx, configPath, ranges, pre_receive.CommitScanOptions{
+		Pusher:   gitUser(),
+		Refs:     updates,
+		Template: report.PrePushTemplate(),
+		Ignore:   ignoreList,
+		Baseline: accepted,
+		Validity: &validity,
+	}, out)
+}
+
+// pushedCommits returns the pushed ref updates and the commits they send that the remote does not have.
+func pushedCommits(remote string, stdin io.Reader, exists func(rev string) bool) ([]report.RefUpdate, []pre_receive.CommitRange, error) {
+	if toRef := os.Getenv(envToRef); toRef != "" {
+		fromRef := os.Getenv(envFromRef)
+		update := report.RefUpdate{OldObject: fromRef, NewObject: toRef, RefName: os.Getenv(envRemoteBranch)}
+		return []report.RefUpdate{update}, []pre_receive.CommitRange{{
+			RefName: update.RefName,
+			LogArgs: []string{fmt.Sprintf("%s..%s", fromRef, toRef)},
+		}}, nil
+	}
+	if os.Getenv(envRemoteName) != "" {
+		// The pre-commit framework sets no refs when the whole history of the branch is pushed.
+		localBranch := os.Getenv(envLocalBranch)
+		if localBranch == "" {
+			localBranch = "HEAD"
+		}
+		update := report.RefUpdate{OldObject: zeroRev, NewObject: localBranch, RefName: os.Getenv(envRemoteBranch)}
+		return []report.RefUpdate{update}, []pre_receive.CommitRange{{
+			RefName: update.RefName,
+			LogArgs: unpushedLogArgs(remote, localBranch),
+		}}, nil
+	}
+
+	var updates []report.RefUpdate
+	var ranges []pre_receive.CommitRange
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		// Expect exactly four fields: localRef, localRev, remoteRef and remoteRev.
+		parts := strings.Fields(line)
+		if len(parts) != 4 {
+			return nil, nil, fmt.Errorf("invalid input line: %s", line)
+		}
+		localRev, remoteRef, remoteRev := parts[1], parts[2], parts[3]
+		updates = append(updates, report.RefUpdate{OldObject: remoteRev, NewObject: localRev, RefName: remoteRef})
+
+		switch {
+		case localRev == zeroRev:
+			// Ref deletion — nothing is sent.
+		case remoteRev != zeroRev && exists(remoteRev):
+			ranges = append(ranges, pre_receive.CommitRange{RefName: remoteRef, LogArgs: []string{fmt.Sprintf("%s..%s", remoteRev, localRev)}})
+		default:
+			// New ref, or a remote ref unknown locally: send what no ref of the remote has.
+			ranges = append(ranges, pre_receive.CommitRange{RefName: remoteRef, LogArgs: unpushedLogArgs(remote, localRev)})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return updates, ranges, nil
+}
+
+// unpushedLogArgs selects the commits of rev that are not on any remote-tracking ref of the remote.
+func unpushedLogArgs(remote, rev string) []string {
+	if remote == "" {
+		return []string{rev}
+	}
+	return []string{rev, "--not", "--remotes=" + remote}
+}
+
+// revisionExists reports whether the commit is known to the local repository.
+func revisionExists(rev string) bool {
+	return exec.Command("git", "cat-file", "-e", rev+"^{commit}").Run() == nil
+}
+
+// gitUser returns the git identity of the developer, shown as the pusher in the report.
+func gitUser() string {
+	name, _ := exec.Command("git", "config", "user.name").Output()
+	email, _ := exec.Command("git", "config", "user.email").Output()
+	user := strings.TrimSpace(string(name))
+	if e := strings.TrimSpace(string(email)); e != "" {
+		user = strings.TrimSpace(fmt.Sprintf("%s (%s)", user, e))
+	}
+	if user == "" {
+		user = "unknown"
+	}
+	return user
+}
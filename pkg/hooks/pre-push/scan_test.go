@@ -0,0 +1,123 @@
+package pre_push
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	pre_receive "github.com/Checkmarx/secret-detection/pkg/hooks/pre-receive"
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	"github.com/Checkmarx/secret-detection/pkg/verify"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPushedCommits(t *testing.T) {
+	exists := func(rev string) bool { return rev != "fffffff" }
+	// The tests may run from a hook of the pre-commit framework.
+	for _, name := range []string{envFromRef, envToRef, envRemoteName, envRemoteBranch, envLocalBranch} {
+		t.Setenv(name, "")
+	}
+
+	t.Run("git hook input", func(t *testing.T) {
+		stdin := strings.Join([]string{
+			"refs/heads/main 2222222 refs/heads/main 1111111",
+			"refs/heads/feature 3333333 refs/heads/feature " + zeroRev,
+			"(delete) " + zeroRev + " refs/heads/old 4444444",
+			"refs/heads/forced 5555555 refs/heads/forced fffffff",
+			"",
+		}, "\n")
+		updates, ranges, err := pushedCommits("origin", strings.NewReader(stdin), exists)
+		assert.NoError(t, err)
+		assert.Equal(t, []report.RefUpdate{
+			{OldObject: "1111111", NewObject: "2222222", RefName: "refs/heads/main"},
+			{OldObject: zeroRev, NewObject: "3333333", RefName: "refs/heads/feature"},
+			{OldObject: "4444444", NewObject: zeroRev, RefName: "refs/heads/old"},
+			{OldObject: "fffffff", NewObject: "5555555", RefName: "refs/heads/forced"},
+		}, updates)
+		assert.Equal(t, []pre_receive.CommitRange{
+			{RefName: "refs/heads/main", LogArgs: []string{"1111111..2222222"}},
+			{RefName: "refs/heads/feature", LogArgs: []string{"3333333", "--not", "--remotes=origin"}},
+			{RefName: "refs/heads/forced", LogArgs: []string{"5555555", "--not", "--remotes=origin"}},
+		}, ranges)
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, _, err := pushedCommits("origin", strings.NewReader("refs/heads/main 2222222\n"), exists)
+		assert.ErrorContains(t, err, "invalid input line")
+	})
+
+	t.Run("pre-commit framework refs", func(t *testing.T) {
+		t.Setenv(envFromRef, "1111111")
+		t.Setenv(envToRef, "2222222")
+		t.Setenv(envRemoteBranch, "refs/heads/main")
+		updates, ranges, err := pushedCommits("origin", strings.NewReader(""), exists)
+		assert.NoError(t, err)
+		assert.Equal(t, []report.RefUpdate{{OldObject: "1111111", NewObject: "2222222", RefName: "refs/heads/main"}}, updates)
+		assert.Equal(t, []pre_receive.CommitRange{{RefName: "refs/heads/main", LogArgs: []string{"1111111..2222222"}}}, ranges)
+	})
+
+	t.Run("pre-commit framework whole branch", func(t *testing.T) {
+		t.Setenv(envRemoteName, "origin")
+		t.Setenv(envLocalBranch, "refs/heads/feature")
+		t.Setenv(envRemoteBranch, "refs/heads/feature")
+		_, ranges, err := pushedCommits("origin", strings.NewReader(""), exists)
+		assert.NoError(t, err)
+		assert.Equal(t, []pre_receive.CommitRange{
+			{RefName: "refs/heads/feature", LogArgs: []string{"refs/heads/feature", "--not", "--remotes=origin"}},
+		}, ranges)
+	})
+}
+
+func TestRunValidityFromUserConfig(t *testing.T) {
+	for _, name := range []string{envFromRef, envToRef, envRemoteName, envRemoteBranch, envLocalBranch} {
+		t.Setenv(name, "")
+	}
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	validity := "validity:\n  enabled: true\n  checks:\n    - rule_id: acme-key\n      base_url: " + server.URL + "\n      allow_http: true\n      path: /{{secret}}\n"
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	git := func(args ...string) string {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	git("config", "user.email", "dev@example.com")
+	git("config", "user.name", "dev")
+	config := "custom_rules:\n  - id: acme-key\n    regex: 'acme_[a-f0-9]{32}'\n" + validity
+	assert.NoError(t, os.WriteFile(configFileName, []byte(config), 0644))
+	assert.NoError(t, os.WriteFile("config.env", []byte("ACME_KEY=acme_0123456789abcdef0123456789abcdef\n"), 0644))
+	git("add", "config.env")
+	git("commit", "-qm", "Add the key")
+	push := "refs/heads/main " + git("rev-parse", "HEAD") + " refs/heads/main " + zeroRev + "\n"
+
+	// The repository does not choose where the secrets are sent.
+	result, err := Run(context.Background(), "origin", strings.NewReader(push), nil)
+	assert.NoError(t, err)
+	assert.True(t, result.Blocked())
+	assert.Zero(t, requests.Load())
+
+	userConfig, err := verify.UserConfigPath()
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(userConfig), 0755))
+	assert.NoError(t, os.WriteFile(userConfig, []byte(validity), 0644))
+	result, err = Run(context.Background(), "origin", strings.NewReader(push), nil)
+	assert.NoError(t, err)
+	assert.True(t, result.Blocked())
+	assert.NotZero(t, requests.Load())
+}
@@ -0,0 +1,410 @@
+package pre_receive
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Checkmarx/secret-detection/pkg/baseline"
+	"github.com/Checkmarx/secret-detection/pkg/ignore"
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	secretscanner "github.com/Checkmarx/secret-detection/pkg/scanner"
+)
+
+const (
+	// auditBatchSize is the number of commits scanned between two saves of the audit state.
+	auditBatchSize     = 200
+	auditStateFileName = "cx-secrets-audit.json"
+	auditStateVersion  = 1
+	shortCommitLength  = 8
+)
+
+// Report formats of the audit.
+const (
+	AuditFormatJSON     = "json"
+	AuditFormatSARIF    = "sarif"
+	AuditFormatIgnore   = "ignore"
+	AuditFormatBaseline = "baseline"
+)
+
+// AuditOptions selects the history scanned by Audit and how its findings are reported.
+type AuditOptions struct {
+	// Branches limits the audit to the named branches, or those matching glob patterns. Every ref is
+	// audited when empty.
+	Branches []string
+	// Since and Until limit the audit to the commits of the date range, in any format git log accepts.
+	Since string
+	Until string
+	// Format is the report format: json (the default), sarif, ignore for .checkmarx_ignore entries, or
+	// baseline for a .checkmarx_baseline.json file.
+	Format string
+	// Output is the file the report is written to, the standard output when empty.
+	Output string
+	// Resume continues an interrupted audit of the same branches and dates from its state file.
+	Resume bool
+	// StateFile records the progress of the audit. It defaults to a file in the git directory.
+	StateFile string
+	// Progress receives the progress messages when set.
+	Progress io.Writer
+}
+
+// auditFilters are the options that select the audited commits; a resumed audit must use the same.
+type auditFilters struct {
+	Branches []string `json:"branches,omitempty"`
+	Since    string   `json:"since,omitempty"`
+	Until    string   `json:"until,omitempty"`
+}
+
+// auditState is saved after each batch of commits so that an interrupted audit can be resumed.
+type auditState struct {
+	Version int                 `json:"version"`
+	Filters auditFilters        `json:"filters"`
+	Scanned []string            `json:"scanned"`
+	Report  report.ReportOutput `json:"report"`
+	// Salt keys the fingerprints of Findings, the baseline findings of the secrets of Report, which
+	// holds their redacted values only.
+	Salt     string             `json:"salt"`
+	Findings []baseline.Finding `json:"findings"`
+}
+
+// auditAccepted holds what the repository already accepts: the audit leaves these findings out.
+type auditAccepted struct {
+	ignore   ignore.List
+	baseline *baseline.Baseline
+}
+
+// Audit scans every commit reachable from the refs of the repository, or from the selected branches,
+// and reports each secret with the commit and author that introduced it. Only the added lines are
+// scanned, so a secret is reported once, in the commit that added it. The findings accepted by the
+// ignore file and the baseline of the repository, or by the baseline of the configuration, are left
+// out as in the hooks, so that the ignore and baseline reports add to them. The audit stops when ctx
+// is done or on SIGINT and SIGTERM, and can then be resumed from the last saved batch.
+func Audit(ctx context.Context, configPath string, opts AuditOptions) error {
+	ctx, stop := secretscanner.WithSignals(ctx)
+	defer stop()
+	if err := validateAuditFormat(opts.Format); err != nil {
+		return err
+	}
+	scanConfig, err := loadScanConfig(configPath)
+	if err != nil {
+		return err
+	}
+	stateFile := opts.StateFile
+	if stateFile == "" {
+		gitDir, err := gitDirectory()
+		if err != nil {
+			return err
+		}
+		stateFile = filepath.Join(gitDir, auditStateFileName)
+	}
+
+	accepted, err := loadAuditAccepted(scanConfig)
+	if err != nil {
+		return err
+	}
+
+	filters := auditFilters{Branches: opts.Branches, Since: opts.Since, Until: opts.Until}
+	commits, err := auditCommits(ctx, filters)
+	if err != nil {
+		return err
+	}
+	state := &auditState{Version: auditStateVersion, Filters: filters, Salt: accepted.baseline.Salt()}
+	if opts.Resume {
+		if state, err = loadAuditState(stateFile, filters, state.Salt); err != nil {
+			return err
+		}
+	}
+
+	scanned := make(map[string]bool, len(state.Scanned))
+	for _, commit := range state.Scanned {
+		scanned[commit] = true
+	}
+	var pending []string
+	for _, commit := range commits {
+		if !scanned[commit] {
+			pending = append(pending, commit)
+		}
+	}
+	progressf(opts.Progress, "Auditing %d commits (%d already scanned)\n", len(commits), len(commits)-len(pending))
+
+	for start := 0; start < len(pending); start += auditBatchSize {
+		batch := pending[start:min(start+auditBatchSize, len(pending))]
+		batchReport, findings, err := auditBatch(ctx, scanConfig, accepted, state.Salt, batch)
+		if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("audit stopped after %d of %d commits, resume it to continue: %w",
+					len(commits)-len(pending)+start, len(commits), err)
+			}
+			return err
+		}
+		mergeReportOutput(&state.Report, batchReport)
+		state.Findings = append(state.Findings, findings...)
+		state.Scanned = append(state.Scanned, batch...)
+		if err = saveAuditState(stateFile, state); err != nil {
+			return err
+		}
+		progressf(opts.Progress, "Audited %d of %d commits, %d secrets found\n",
+			len(commits)-len(pending)+start+len(batch), len(commits), state.Report.TotalSecretsFound)
+	}
+
+	if state.Report.Commits == nil {
+		state.Report.Commits = []report.CommitSummary{}
+	}
+	state.Report.RulesUsed = scanConfig.ruleSelection().SelectedIDs(scanConfig.CustomRules)
+	sortCommitsByDate(&state.Report)
+	data, err := formatAuditReport(state, accepted.baseline, opts.Format)
+	if err != nil {
+		return err
+	}
+	if opts.Output == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(opts.Output, data, 0o644)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the audit report: %w", err)
+	}
+	if err = os.Remove(stateFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove the audit state %q: %w", stateFile, err)
+	}
+	progressf(opts.Progress, "Audit complete: %d secrets found in %d commits\n", state.Report.TotalSecretsFound, len(state.Report.Commits))
+	return nil
+}
+
+func validateAuditFormat(format string) error {
+	switch format {
+	case "", AuditFormatJSON, AuditFormatSARIF, AuditFormatIgnore, AuditFormatBaseline:
+		return nil
+	default:
+		return fmt.Errorf("unknown audit format %q", format)
+	}
+}
+
+// auditCommits lists the commits selected by the filters, oldest first.
+func auditCommits(ctx context.Context, filters auditFilters) ([]string, error) {
+	output, err := exec.CommandContext(ctx, "git", auditRevListArgs(filters)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the commits to audit: %w", err)
+	}
+	var commits []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		if commit := strings.TrimSpace(scanner.Text()); commit != "" {
+			commits = append(commits, commit)
+		}
+	}
+	return commits, scanner.Err()
+}
+
+func auditRevListArgs(filters auditFilters) []string {
+	args := []string{"rev-list", "--reverse"}
+	if filters.Since != "" {
+		args = append(args, "--since="+filters.Since)
+	}
+	if filters.Until != "" {
+		args = append(args, "--until="+filters.Until)
+	}
+	if len(filters.Branches) == 0 {
+		return append(args, "--all")
+	}
+	for _, branch := range filters.Branches {
+		// git reads a pattern without wildcards as a prefix of branch names, so a plain name is passed
+		// as a revision instead.
+		if strings.ContainsAny(branch, "*?[") {
+			args = append(args, "--branches="+branch)
+		} else {
+			args = append(args, branch)
+		}
+	}
+	return args
+}
+
+// loadAuditAccepted reads the ignore file and the baseline at the root of the working tree of the
+// repository, if any. The baseline of the configuration replaces the one of the repository, as in the
+// pre-receive hook.
+func loadAuditAccepted(scanConfig PreReceiveConfig) (auditAccepted, error) {
+	accepted := auditAccepted{baseline: scanConfig.baseline}
+	output, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		// A bare repository has no working tree.
+		return accepted, nil
+	}
+	root := strings.TrimSpace(string(output))
+	if accepted.ignore, err = ignore.Load(filepath.Join(root, ignore.FileName)); err != nil {
+		return auditAccepted{}, err
+	}
+	if accepted.baseline == nil {
+		if accepted.baseline, err = baseline.Load(filepath.Join(root, baseline.FileName)); err != nil {
+			return auditAccepted{}, err
+		}
+	}
+	return accepted, nil
+}
+
+// auditBatch scans the added lines of the commits and returns their findings that are not accepted,
+// and their baseline findings, fingerprinted with the salt.
+func auditBatch(ctx context.Context, scanConfig PreReceiveConfig, accepted auditAccepted, salt string, commits []string) (*report.ReportOutput, []baseline.Finding, error) {
+	ranges := []CommitRange{{
+		RefName: fmt.Sprintf("%s (audit)", shortCommit(commits[0])),
+		LogArgs: append([]string{"--no-walk=unsorted"}, commits...),
+	}}
+	scanConfig.IgnoreSecret = append(scanConfig.IgnoreSecret, accepted.ignore.ResultIDs()...)
+	scanReport, index, suppressed, err := runSecretScan(ctx, scanConfig, ranges, false)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to run scan: %w", err)
+	}
+	accepted.ignore.Apply(scanReport)
+	accepted.baseline.Apply(scanReport)
+	scanConfig.Severity.Apply(scanReport)
+	if scanReport.TotalSecretsFound == 0 {
+		return &report.ReportOutput{SuppressedInline: suppressed}, nil, nil
+	}
+	if err = updateResultsStartAndEndLine(scanReport, index); err != nil {
+		return nil, nil, err
+	}
+	removeDuplicateResults(scanReport)
+	var findings []baseline.Finding
+	for _, secret := range report.SortedFindings(scanReport) {
+		findings = append(findings, baseline.FindingOf(secret, salt))
+	}
+	return report.NewReportOutput(scanReport, index.commits, report.Options{
+		Redaction:        scanConfig.Redaction,
+		Severity:         scanConfig.Severity,
+		SuppressedInline: suppressed,
+	}), findings, nil
+}
+
+// mergeReportOutput adds the findings of a batch of commits to the report of the audit.
+func mergeReportOutput(dst, src *report.ReportOutput) {
+	dst.TotalSecretsFound += src.TotalSecretsFound
+	dst.SuppressedInline += src.SuppressedInline
+	dst.Commits = append(dst.Commits, src.Commits...)
+}
+
+// sortCommitsByDate orders the commits of the report from the newest, like the pre-receive report.
+func sortCommitsByDate(data *report.ReportOutput) {
+	sort.SliceStable(data.Commits, func(i, j int) bool {
+		return data.Commits[i].Date.After(data.Commits[j].Date)
+	})
+}
+
+// formatAuditReport returns the report of the audit in the format. The baseline report replaces the
+// accepted baseline, so it keeps its findings.
+func formatAuditReport(state *auditState, accepted *baseline.Baseline, format string) ([]byte, error) {
+	switch format {
+	case AuditFormatSARIF:
+		return report.SARIFReport(&state.Report)
+	case AuditFormatIgnore:
+		return auditIgnoreEntries(&state.Report, time.Now()), nil
+	case AuditFormatBaseline:
+		var findings []baseline.Finding
+		if accepted != nil {
+			findings = append(findings, accepted.Findings...)
+		}
+		return baseline.New(append(findings, state.Findings...)).Marshal()
+	default:
+		return json.MarshalIndent(&state.Report, "", "  ")
+	}
+}
+
+// auditIgnoreEntries returns a .checkmarx_ignore entry for each secret of the report, so that the
+// existing findings of a repository can be accepted when it is onboarded.
+func auditIgnoreEntries(data *report.ReportOutput, now time.Time) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "# Findings of the secrets audit of %s\n", now.Format(ignore.DateLayout))
+	seen := make(map[string]bool)
+	for _, commit := range data.Commits {
+		for _, file := range commit.Files {
+			for _, secret := range file.Secrets {
+				if seen[secret.ID] {
+					continue
+				}
+				seen[secret.ID] = true
+				entry := ignore.Entry{
+					Kind:   ignore.KindResult,
+					Value:  secret.ID,
+					Reason: fmt.Sprintf("audit: %s in %s (commit %s)", secret.RuleID, file.FileName, shortCommit(commit.CommitID)),
+					Date:   now.Format(ignore.DateLayout),
+				}
+				buf.WriteString(entry.String() + "\n")
+			}
+		}
+	}
+	return buf.Bytes()
+}
+
+// loadAuditState reads the state of an interrupted audit. A missing file starts a new audit, whose
+// fingerprints are keyed by salt, as are those of a state recorded without a salt.
+func loadAuditState(filePath string, filters auditFilters, salt string) (*auditState, error) {
+	state := &auditState{Version: auditStateVersion, Filters: filters, Salt: salt}
+	data, err := os.ReadFile(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the audit state %q: %w", filePath, err)
+	}
+	if err = json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("audit state %q is corrupted: %w", filePath, err)
+	}
+	if state.Version != auditStateVersion {
+		return nil, fmt.Errorf("audit state %q has unsupported version %d", filePath, state.Version)
+	}
+	if !reflect.DeepEqual(state.Filters, filters) {
+		return nil, fmt.Errorf("audit state %q was recorded with other branch or date filters; run the audit without resuming to restart it", filePath)
+	}
+	if state.Salt == "" {
+		state.Salt = salt
+	}
+	return state, nil
+}
+
+// saveAuditState replaces the state file atomically, so that an interruption leaves the previous state.
+func saveAuditState(filePath string, state *auditState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal the audit state: %w", err)
+	}
+	tmpPath := filePath + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write the audit state %q: %w", tmpPath, err)
+	}
+	if err = os.Rename(tmpPath, filePath); err != nil {
+		return fmt.Errorf("failed to write the audit state %q: %w", filePath, err)
+	}
+	return nil
+}
+
+// gitDirectory returns the path of the git directory of the repository, which is the repository
+// itself when it is bare.
+func gitDirectory() (string, error) {
+	output, err := exec.Command("git", "rev-parse", "--git-dir").Output()
+	if err != nil {
+		return "", fmt.Errorf("current directory is not a Git repository")
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > shortCommitLength {
+		return commit[:shortCommitLength]
+	}
+	return commit
+}
+
+func progressf(w io.Writer, format string, args ...interface{}) {
+	if w != nil {
+		fmt.Fprintf(w, format, args...) // nolint:errcheck
+	}
+}
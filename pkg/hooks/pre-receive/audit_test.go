@@ -0,0 +1,137 @@
+package pre_receive
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Checkmarx/secret-detection/pkg/baseline"
+	"github.com/Checkmarx/secret-detection/pkg/fingerprint"
+	"github.com/Checkmarx/secret-detection/pkg/ignore"
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditRevListArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		filters  auditFilters
+		expected []string
+	}{
+		{"all refs", auditFilters{}, []string{"rev-list", "--reverse", "--all"}},
+		{"branches", auditFilters{Branches: []string{"main", "release/*"}}, []string{"rev-list", "--reverse", "main", "--branches=release/*"}},
+		{"dates", auditFilters{Since: "2024-01-01", Until: "1 week ago"}, []string{"rev-list", "--reverse", "--since=2024-01-01", "--until=1 week ago", "--all"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, auditRevListArgs(tc.filters))
+		})
+	}
+}
+
+func TestValidateAuditFormat(t *testing.T) {
+	for _, format := range []string{"", AuditFormatJSON, AuditFormatSARIF, AuditFormatIgnore, AuditFormatBaseline} {
+		assert.NoError(t, validateAuditFormat(format))
+	}
+	assert.ErrorContains(t, validateAuditFormat("xml"), "unknown audit format")
+}
+
+func TestAuditState(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), auditStateFileName)
+	filters := auditFilters{Branches: []string{"main"}}
+
+	state, err := loadAuditState(filePath, filters, "salt")
+	assert.NoError(t, err)
+	assert.Empty(t, state.Scanned, "a missing state starts a new audit")
+	assert.Equal(t, "salt", state.Salt)
+
+	state.Scanned = []string{"aaa", "bbb"}
+	state.Report.TotalSecretsFound = 1
+	state.Report.Commits = []report.CommitSummary{{CommitID: "aaa", Author: "Dev (dev@example.com)"}}
+	assert.NoError(t, saveAuditState(filePath, state))
+
+	loaded, err := loadAuditState(filePath, filters, "pepper")
+	assert.NoError(t, err)
+	assert.Equal(t, state, loaded, "the salt of the state is kept")
+
+	_, err = loadAuditState(filePath, auditFilters{Since: "2024-01-01"}, "salt")
+	assert.ErrorContains(t, err, "other branch or date filters")
+}
+
+func TestMergeReportOutput(t *testing.T) {
+	older := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(24 * time.Hour)
+	data := &report.ReportOutput{}
+	mergeReportOutput(data, &report.ReportOutput{TotalSecretsFound: 1, Commits: []report.CommitSummary{{CommitID: "old", Date: older}}})
+	mergeReportOutput(data, &report.ReportOutput{SuppressedInline: 2})
+	mergeReportOutput(data, &report.ReportOutput{TotalSecretsFound: 2, Commits: []report.CommitSummary{{CommitID: "new", Date: newer}}})
+	sortCommitsByDate(data)
+
+	assert.Equal(t, 3, data.TotalSecretsFound)
+	assert.Equal(t, 2, data.SuppressedInline)
+	assert.Equal(t, "new", data.Commits[0].CommitID)
+	assert.Equal(t, "old", data.Commits[1].CommitID)
+}
+
+func TestAuditIgnoreEntries(t *testing.T) {
+	secret := report.SecretEntry{ID: "id-1", RuleID: "github-pat"}
+	data := &report.ReportOutput{Commits: []report.CommitSummary{
+		{CommitID: "0123456789abcdef", Files: []report.FileSummary{{FileName: "a.txt", Secrets: []report.SecretEntry{secret}}}},
+		{CommitID: "fedcba9876543210", Files: []report.FileSummary{{FileName: "a.txt", Secrets: []report.SecretEntry{secret}}}},
+	}}
+
+	output := auditIgnoreEntries(data, time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, "# Findings of the secrets audit of 2025-01-31\n"+
+		"result:id-1 reason=\"audit: github-pat in a.txt (commit 01234567)\" date=2025-01-31\n", string(output))
+
+	entry, err := ignore.ParseEntry("result:id-1 reason=\"audit: github-pat in a.txt (commit 01234567)\" date=2025-01-31")
+	assert.NoError(t, err)
+	assert.Equal(t, "id-1", entry.Value)
+}
+
+func TestAuditLeavesOutAcceptedFindings(t *testing.T) {
+	git := gitRepo(t, 1)
+	keys := map[string]string{"a.env": acmeKey, "b.env": "acme_" + strings.Repeat("1", 32), "c.env": "acme_" + strings.Repeat("2", 32)}
+	for name, key := range keys {
+		assert.NoError(t, os.WriteFile(name, []byte(key+"\n"), 0o644))
+	}
+	git("add", ".")
+	git("commit", "-qm", "Add the keys")
+	configPath := acmeConfig(t)
+
+	// The repository ignores the key of a.env and accepts the one of b.env.
+	assert.NoError(t, os.WriteFile(ignore.FileName, []byte("rule:acme-key path=a.env\n"), 0o644))
+	accepted := baseline.FindingOf(&secrets.Secret{RuleID: "acme-key", Source: "b.env", Value: keys["b.env"]}, "salt")
+	assert.NoError(t, baseline.New([]baseline.Finding{accepted}).Write(baseline.FileName))
+
+	output := filepath.Join(t.TempDir(), "audit")
+	assert.NoError(t, Audit(context.Background(), configPath, AuditOptions{Format: AuditFormatIgnore, Output: output}))
+	data, err := os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "result:"))
+	assert.Contains(t, string(data), "in c.env")
+
+	assert.NoError(t, Audit(context.Background(), configPath, AuditOptions{Format: AuditFormatBaseline, Output: output}))
+	audited, err := baseline.Load(output)
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, audited.Len()) {
+		assert.Equal(t, accepted, audited.Findings[0], "the findings of the baseline are kept")
+		assert.Equal(t, "c.env", audited.Findings[1].Path)
+		assert.True(t, fingerprint.Matches(audited.Findings[1].Fingerprint, keys["c.env"]))
+		assert.Equal(t, "salt", audited.Salt(), "the salt of the baseline is kept")
+	}
+
+	// A resumed audit without a state yet keys the fingerprints with the salt of the baseline too.
+	resumed := filepath.Join(t.TempDir(), "resumed")
+	stateFile := filepath.Join(t.TempDir(), auditStateFileName)
+	assert.NoError(t, Audit(context.Background(), configPath, AuditOptions{Format: AuditFormatBaseline, Output: resumed, Resume: true, StateFile: stateFile}))
+	resumedData, err := os.ReadFile(resumed)
+	assert.NoError(t, err)
+	data, err = os.ReadFile(output)
+	assert.NoError(t, err)
+	assert.Equal(t, string(data), string(resumedData))
+}
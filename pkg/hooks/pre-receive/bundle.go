@@ -0,0 +1,231 @@
+package pre_receive
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	secretscanner "github.com/Checkmarx/secret-detection/pkg/scanner"
+)
+
+const (
+	bundleSignatureV2 = "# v2 git bundle"
+	bundleSignatureV3 = "# v3 git bundle"
+)
+
+// Bundle is the header of a git bundle file.
+type Bundle struct {
+	// Refs are the refs the bundle contains.
+	Refs []BundleRef
+	// Prerequisites are the commits the bundle requires: the repository that imports it must have
+	// them. A bundle without prerequisites records a complete history.
+	Prerequisites []BundlePrerequisite
+}
+
+// BundleRef is a ref of a bundle and the object it points to.
+type BundleRef struct {
+	Name   string
+	Object string
+}
+
+// BundlePrerequisite is a commit required by a bundle, with the subject git records for it.
+type BundlePrerequisite struct {
+	Object  string
+	Comment string
+}
+
+// ReadBundle reads the header of the git bundle file at path.
+func ReadBundle(path string) (*Bundle, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bundle %s: %w", path, err)
+	}
+	defer file.Close() // nolint:errcheck
+	bundle, err := parseBundleHeader(bufio.NewReader(file))
+	if err != nil {
+		return nil, fmt.Errorf("bundle %s is invalid: %w", path, err)
+	}
+	return bundle, nil
+}
+
+// parseBundleHeader parses the header of a bundle, which ends with an empty line before the packed
+// objects. Version 3 capabilities, as the hash algorithm, are checked by git when the bundle is
+// verified.
+func parseBundleHeader(r *bufio.Reader) (*Bundle, error) {
+	signature, err := r.ReadString('\n')
+	if err != nil {
+		return nil, errors.New("not a git bundle")
+	}
+	signature = strings.TrimSuffix(signature, "\n")
+	if signature != bundleSignatureV2 && signature != bundleSignatureV3 {
+		return nil, errors.New("not a git bundle")
+	}
+
+	bundle := &Bundle{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, errors.New("truncated header")
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if len(bundle.Refs) == 0 {
+				return nil, errors.New("the bundle contains no refs")
+			}
+			return bundle, nil
+		case strings.HasPrefix(line, "@") && signature == bundleSignatureV3:
+			// A capability.
+		case strings.HasPrefix(line, "-"):
+			object, comment, _ := strings.Cut(line[1:], " ")
+			bundle.Prerequisites = append(bundle.Prerequisites, BundlePrerequisite{Object: object, Comment: comment})
+		default:
+			object, name, ok := strings.Cut(line, " ")
+			if !ok || name == "" {
+				return nil, fmt.Errorf("invalid header line: %s", line)
+			}
+			bundle.Refs = append(bundle.Refs, BundleRef{Name: name, Object: object})
+		}
+	}
+}
+
+// ScanBundle verifies the git bundle file at path, prints its refs and prerequisites, and scans the
+// commits the bundle adds to the repository of the working directory with the configuration at
+// configPath, which may be empty. The report is the one of a push of the bundle refs, and the process
+// exits with status 1 when the findings block it. The scan stops when ctx is done or on SIGINT and
+// SIGTERM.
+func ScanBundle(ctx context.Context, configPath, path string) error {
+	ctx, stop := secretscanner.WithSignals(ctx)
+	defer stop()
+	return exitOnBlock(RunBundle(ctx, configPath, path, os.Stdout))
+}
+
+// RunBundle is ScanBundle without exiting: it writes the refs and prerequisites of the bundle and the
+// report to out, which may be nil, and returns the result. The bundle is not imported: its objects are
+// unpacked in a temporary repository that borrows the objects of the repository of the working
+// directory, if any, to find the prerequisites. The scan stops when ctx is done.
+func RunBundle(ctx context.Context, configPath, path string, out io.Writer) (*report.Result, error) {
+	if out == nil {
+		out = io.Discard
+	}
+	scanConfig, err := loadScanConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateLogsFolderPath(scanConfig.LogsFolderPath); err != nil {
+		return nil, err
+	}
+	bundle, err := ReadBundle(path)
+	if err != nil {
+		return nil, err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return nil, err
+	}
+
+	scanConfig.gitDir, err = os.MkdirTemp("", "cx-bundle-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the bundle repository: %w", err)
+	}
+	defer os.RemoveAll(scanConfig.gitDir) // nolint:errcheck
+	if err = unbundle(ctx, scanConfig.gitDir, path); err != nil {
+		return nil, err
+	}
+
+	if err = writeBundle(out, bundle); err != nil {
+		return nil, err
+	}
+	return scanCommits(ctx, scanConfig, bundleRanges(bundle), CommitScanOptions{Refs: bundleRefUpdates(bundle)}, out)
+}
+
+// unbundle verifies the bundle and unpacks its objects in a new repository at gitDir. The repository
+// borrows the objects of the repository of the working directory, which the bundle may require.
+func unbundle(ctx context.Context, gitDir, path string) error {
+	if _, err := runGit(ctx, gitDir, "init", "--bare", "--quiet"); err != nil {
+		return fmt.Errorf("failed to create the bundle repository: %w", err)
+	}
+	if objects, err := runGit(ctx, "", "rev-parse", "--path-format=absolute", "--git-path", "objects"); err == nil {
+		alternates := filepath.Join(gitDir, "objects", "info", "alternates")
+		if err = os.WriteFile(alternates, []byte(strings.TrimSpace(objects)+"\n"), 0o644); err != nil {
+			return fmt.Errorf("failed to create the bundle repository: %w", err)
+		}
+	}
+	if _, err := runGit(ctx, gitDir, "bundle", "verify", path); err != nil {
+		return fmt.Errorf("bundle verification failed: %w", err)
+	}
+	if _, err := runGit(ctx, gitDir, "bundle", "unbundle", path); err != nil {
+		return fmt.Errorf("failed to unpack the bundle: %w", err)
+	}
+	return nil
+}
+
+// runGit runs git on the repository at gitDir and returns its output. The error holds what git
+// reported.
+func runGit(ctx context.Context, gitDir string, args ...string) (string, error) {
+	cmd := gitCommand(ctx, gitDir, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", context.Cause(ctx)
+		}
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("%w: %s", err, message)
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// bundleRanges selects the commits the bundle adds: those of its refs that are not reachable from its
+// prerequisites. The commits shared with a previous ref are excluded, so that each one is scanned once.
+func bundleRanges(bundle *Bundle) []CommitRange {
+	var excluded []string
+	for _, prerequisite := range bundle.Prerequisites {
+		excluded = append(excluded, prerequisite.Object)
+	}
+	var ranges []CommitRange
+	for _, ref := range bundle.Refs {
+		logArgs := append([]string{"--root", ref.Object, "--not"}, excluded...)
+		ranges = append(ranges, CommitRange{RefName: ref.Name, LogArgs: logArgs})
+		excluded = append(excluded, ref.Object)
+	}
+	return ranges
+}
+
+// bundleRefUpdates returns the refs of the bundle as the ref updates of a push that creates them.
+func bundleRefUpdates(bundle *Bundle) []report.RefUpdate {
+	updates := make([]report.RefUpdate, 0, len(bundle.Refs))
+	for _, ref := range bundle.Refs {
+		updates = append(updates, report.RefUpdate{OldObject: zeroRev, NewObject: ref.Object, RefName: ref.Name})
+	}
+	return updates
+}
+
+// writeBundle lists the refs and prerequisites of the bundle.
+func writeBundle(w io.Writer, bundle *Bundle) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "The bundle contains %d ref(s):\n", len(bundle.Refs))
+	for _, ref := range bundle.Refs {
+		fmt.Fprintf(&sb, "  %s %s\n", ref.Object, ref.Name)
+	}
+	if len(bundle.Prerequisites) == 0 {
+		sb.WriteString("The bundle records a complete history.\n")
+	} else {
+		fmt.Fprintf(&sb, "The bundle requires %d commit(s):\n", len(bundle.Prerequisites))
+		for _, prerequisite := range bundle.Prerequisites {
+			fmt.Fprintf(&sb, "  %s %s\n", prerequisite.Object, prerequisite.Comment)
+		}
+	}
+	sb.WriteString("\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,110 @@
+package pre_receive
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBundleHeader(t *testing.T) {
+	const (
+		base = "1111111111111111111111111111111111111111"
+		tip  = "2222222222222222222222222222222222222222"
+	)
+	tests := []struct {
+		name   string
+		header string
+		bundle *Bundle
+		err    string
+	}{
+		{
+			name:   "v2 with a prerequisite",
+			header: "# v2 git bundle\n-" + base + " Initial commit\n" + tip + " refs/heads/main\n\nPACK",
+			bundle: &Bundle{
+				Refs:          []BundleRef{{Name: "refs/heads/main", Object: tip}},
+				Prerequisites: []BundlePrerequisite{{Object: base, Comment: "Initial commit"}},
+			},
+		},
+		{
+			name:   "v3 with a capability",
+			header: "# v3 git bundle\n@object-format=sha1\n" + tip + " refs/heads/main\n" + tip + " HEAD\n\nPACK",
+			bundle: &Bundle{Refs: []BundleRef{{Name: "refs/heads/main", Object: tip}, {Name: "HEAD", Object: tip}}},
+		},
+		{name: "not a bundle", header: "PACK", err: "not a git bundle"},
+		{name: "capability in v2", header: "# v2 git bundle\n@object-format=sha1\n\n", err: "invalid header line"},
+		{name: "no refs", header: "# v2 git bundle\n-" + base + " Initial commit\n\n", err: "no refs"},
+		{name: "truncated", header: "# v2 git bundle\n" + tip + " refs/heads/main\n", err: "truncated header"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			bundle, err := parseBundleHeader(bufio.NewReader(strings.NewReader(tc.header)))
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.bundle, bundle)
+		})
+	}
+}
+
+func TestRunBundle(t *testing.T) {
+	git := gitRepo(t, 1)
+	git("branch", "-M", "main")
+	base := git("rev-parse", "HEAD")
+	assert.NoError(t, os.WriteFile("config.env", []byte("ACME_KEY="+acmeKey+"\n"), 0o644))
+	git("add", ".")
+	git("commit", "-qm", "Add the key")
+	leaked := git("rev-parse", "HEAD")
+	configPath := acmeConfig(t)
+
+	bundles := t.TempDir()
+	incremental := filepath.Join(bundles, "incremental.bundle")
+	full := filepath.Join(bundles, "full.bundle")
+	git("bundle", "create", "-q", incremental, base+"..main")
+	git("bundle", "create", "-q", full, "main")
+	objects := git("count-objects", "-v")
+
+	t.Run("incremental", func(t *testing.T) {
+		var out bytes.Buffer
+		result, err := RunBundle(context.Background(), configPath, incremental, &out)
+		assert.NoError(t, err)
+		assert.True(t, result.Blocked())
+		assert.Contains(t, findingRules(t, result.Findings, "Added:"+leaked+":config.env"), "acme-key")
+		assert.Contains(t, out.String(), "The bundle contains 1 ref(s):\n  "+leaked+" refs/heads/main\n")
+		assert.Contains(t, out.String(), "The bundle requires 1 commit(s):\n  "+base+" commit 0\n")
+		assert.Contains(t, out.String(), result.Rendered)
+		assert.NotEmpty(t, result.JSON)
+		assert.Equal(t, objects, git("count-objects", "-v"), "the bundle must not be imported")
+	})
+
+	t.Run("full outside a repository", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		var out bytes.Buffer
+		result, err := RunBundle(context.Background(), configPath, full, &out)
+		assert.NoError(t, err)
+		assert.True(t, result.Blocked())
+		assert.Contains(t, findingRules(t, result.Findings, "Added:"+leaked+":config.env"), "acme-key")
+		assert.Contains(t, out.String(), "The bundle records a complete history.\n")
+	})
+
+	t.Run("incremental outside a repository", func(t *testing.T) {
+		t.Chdir(t.TempDir())
+		result, err := RunBundle(context.Background(), configPath, incremental, nil)
+		assert.ErrorContains(t, err, "bundle verification failed")
+		assert.ErrorContains(t, err, base)
+		assert.Nil(t, result)
+	})
+
+	t.Run("not a bundle", func(t *testing.T) {
+		result, err := RunBundle(context.Background(), configPath, "config.env", nil)
+		assert.ErrorContains(t, err, "not a git bundle")
+		assert.Nil(t, result)
+	})
+}
@@ -2,17 +2,48 @@ package pre_receive
 
 import (
 	"fmt"
+	"github.com/Checkmarx/secret-detection/pkg/baseline"
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	"github.com/Checkmarx/secret-detection/pkg/rules"
+	"github.com/Checkmarx/secret-detection/pkg/scanner"
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/Checkmarx/secret-detection/pkg/verify"
 	"gopkg.in/yaml.v2"
 	"os"
-	"strings"
+	"path/filepath"
+	"text/template"
 )
 
 type PreReceiveConfig struct {
-	ExcludePath    []string `yaml:"exclude_path"`
-	IgnoreRule     []string `yaml:"ignore_rule_id"`
-	IgnoreSecret   []string `yaml:"ignore_result_id"`
-	LogsFolderPath string   `yaml:"logs_folder_path"`
-	AllowSkip      bool     `yaml:"allow_skip"`
+	ExcludePath              []string               `yaml:"exclude_path"`
+	IgnoreRule               []string               `yaml:"ignore_rule_id"`
+	SelectRules              []string               `yaml:"select_rules"`
+	SelectTags               []string               `yaml:"select_tags"`
+	IgnoreTags               []string               `yaml:"ignore_tags"`
+	IgnoreSecret             []string               `yaml:"ignore_result_id"`
+	LogsFolderPath           string                 `yaml:"logs_folder_path"`
+	AllowSkip                bool                   `yaml:"allow_skip"`
+	DisableInlineSuppression bool                   `yaml:"disable_inline_suppression"`
+	Redaction                report.RedactionConfig `yaml:"redaction"`
+	ReportTemplate           string                 `yaml:"report_template"`
+	Severity                 severity.Config        `yaml:"severity"`
+	CustomRules              []rules.CustomRule     `yaml:"custom_rules"`
+	Entropy                  scanner.EntropyConfig  `yaml:"entropy"`
+	Validity                 verify.Config          `yaml:"validity"`
+	Report                   report.Settings        `yaml:"report"`
+	// Baseline is the path of a baseline file, relative to the configuration file; the findings it
+	// contains do not fail the push.
+	Baseline string `yaml:"baseline"`
+	// Concurrency is the number of git log commands run at the same time; 0 runs a single one.
+	Concurrency int `yaml:"concurrency"`
+
+	// reportTemplate is the parsed ReportTemplate, nil when the default layout is used.
+	reportTemplate *template.Template
+	// baseline is the loaded Baseline, nil when none is configured.
+	baseline *baseline.Baseline
+	// gitDir is the repository the scanned commits are read from, the repository of the working
+	// directory when empty.
+	gitDir string
 }
 
 func loadScanConfig(configPath string) (PreReceiveConfig, error) {
@@ -26,30 +57,106 @@ func loadScanConfig(configPath string) (PreReceiveConfig, error) {
 		if err = yaml.Unmarshal(data, &cfg); err != nil {
 			return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured", configPath)
 		}
+
+		if cfg.Concurrency < 0 {
+			return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured: concurrency must not be negative", configPath)
+		}
+
+		if err = cfg.Redaction.Validate(); err != nil {
+			return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+		}
+
+		if err = cfg.Report.Validate(); err != nil {
+			return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+		}
+
+		if err = cfg.Severity.Validate(); err != nil {
+			return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+		}
+
+		if err = rules.ValidateCustomRules(cfg.CustomRules); err != nil {
+			return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+		}
+
+		if err = cfg.Validity.Validate(); err != nil {
+			return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+		}
+
+		if err = cfg.Entropy.Validate(); err != nil {
+			return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+		}
+
+		if err = cfg.ruleSelection().Validate(cfg.CustomRules); err != nil {
+			return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+		}
+
+		if cfg.ReportTemplate != "" {
+			cfg.reportTemplate, err = report.LoadTemplate(configRelativePath(configPath, cfg.ReportTemplate))
+			if err != nil {
+				return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+			}
+		}
+
+		if cfg.Baseline != "" {
+			baselinePath := configRelativePath(configPath, cfg.Baseline)
+			if _, err = os.Stat(baselinePath); err != nil {
+				return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured: baseline %s does not exist", configPath, cfg.Baseline)
+			}
+			cfg.baseline, err = baseline.Load(baselinePath)
+			if err != nil {
+				return PreReceiveConfig{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+			}
+		}
 	}
 	return PreReceiveConfig{
-		ExcludePath:    cfg.ExcludePath,
-		IgnoreRule:     cfg.IgnoreRule,
-		IgnoreSecret:   cfg.IgnoreSecret,
-		LogsFolderPath: cfg.LogsFolderPath,
-		AllowSkip:      cfg.AllowSkip,
+		ExcludePath:              cfg.ExcludePath,
+		IgnoreRule:               cfg.IgnoreRule,
+		SelectRules:              cfg.SelectRules,
+		SelectTags:               cfg.SelectTags,
+		IgnoreTags:               cfg.IgnoreTags,
+		IgnoreSecret:             cfg.IgnoreSecret,
+		LogsFolderPath:           cfg.LogsFolderPath,
+		AllowSkip:                cfg.AllowSkip,
+		DisableInlineSuppression: cfg.DisableInlineSuppression,
+		Redaction:                cfg.Redaction,
+		ReportTemplate:           cfg.ReportTemplate,
+		Severity:                 cfg.Severity,
+		CustomRules:              cfg.CustomRules,
+		Entropy:                  cfg.Entropy,
+		Validity:                 cfg.Validity,
+		Report:                   cfg.Report,
+		Baseline:                 cfg.Baseline,
+		Concurrency:              cfg.Concurrency,
+		reportTemplate:           cfg.reportTemplate,
+		baseline:                 cfg.baseline,
 	}, nil
 }
 
-func configExcludesToGitExcludes(patterns []string) []string {
-	var specs []string
-	for _, pattern := range patterns {
-		// Trim spaces and surrounding quotes
-		p := strings.Trim(strings.TrimSpace(pattern), `"`)
-		if p == "" {
-			continue
-		}
-		// Normalize Windows backslashes to forward slashes
-		p = strings.ReplaceAll(p, `\`, "/")
-		// Strip any leading slashes
-		p = strings.TrimLeft(p, "/")
-		// Wrap in Git negative pathspec
-		specs = append(specs, fmt.Sprintf(`:(exclude)%s`, p))
+// workers returns the number of git log commands run at the same time. Unless configured, the pushed
+// commits are parsed by a single git log, which does not list them beforehand.
+func (c PreReceiveConfig) workers() int {
+	if c.Concurrency == 0 {
+		return 1
+	}
+	return c.Concurrency
+}
+
+// ruleSelection returns the rule selection settings of the configuration.
+func (c PreReceiveConfig) ruleSelection() rules.Selection {
+	return rules.Selection{
+		SelectRules: c.SelectRules,
+		SelectTags:  c.SelectTags,
+		IgnoreRules: c.IgnoreRule,
+		IgnoreTags:  c.IgnoreTags,
+		Entropy:     c.Entropy.Enabled,
+	}
+}
+
+// configRelativePath resolves a path set in the configuration file at configPath: relative paths are
+// relative to the directory of the file, not to the working directory of the hook.
+func configRelativePath(configPath, path string) string {
+	if filepath.IsAbs(path) {
+		return path
 	}
-	return specs
+	return filepath.Join(filepath.Dir(configPath), path)
 }
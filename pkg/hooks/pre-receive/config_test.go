@@ -1,38 +1,90 @@
 package pre_receive
 
 import (
-	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/fingerprint"
+	"github.com/stretchr/testify/assert"
 )
 
-func TestConfigExcludesToGitExcludes(t *testing.T) {
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadScanConfig(t *testing.T) {
 	tests := []struct {
-		name     string
-		input    []string
-		expected []string
+		name    string
+		content string
+		wantErr bool
 	}{
-		{"empty", []string{}, nil},
-		{"single filename", []string{"a.txt"}, []string{":(exclude)a.txt"}},
-		{"single path", []string{"dir/a.txt"}, []string{":(exclude)dir/a.txt"}},
-		{"windows backslash", []string{`"\folder\file.txt"`}, []string{":(exclude)folder/file.txt"}},
-		{"leading slash", []string{"/root.txt"}, []string{":(exclude)root.txt"}},
-		{"multiple patterns", []string{"a.txt", "dir/b.log"}, []string{":(exclude)a.txt", ":(exclude)dir/b.log"}},
-		{"trims spaces", []string{" a.txt", "dir/c.md "}, []string{":(exclude)a.txt", ":(exclude)dir/c.md"}},
-		{"filename with space", []string{"my file.txt"}, []string{":(exclude)my file.txt"}},
-		{"path with spaces", []string{"dir name/file name.txt"}, []string{":(exclude)dir name/file name.txt"}},
-
-		// Glob‐pattern cases
-		{"simple glob", []string{"*.log"}, []string{":(exclude)*.log"}},
-		{"glob path", []string{"dir/*.js"}, []string{":(exclude)dir/*.js"}},
-		{"recursive glob", []string{"src/**/*.go"}, []string{":(exclude)src/**/*.go"}},
-		{"glob with quotes", []string{`"*.tmp"`}, []string{":(exclude)*.tmp"}},
-		{"leading slash glob", []string{"/cache/*.cache"}, []string{":(exclude)cache/*.cache"}},
-		{"trimmed glob", []string{" *.md "}, []string{":(exclude)*.md"}},
+		{"empty", ``, false},
+		{"report settings", "report:\n  max_displayed_results: 20\n  context_lines: 0\n", false},
+		{"negative report limit", "report:\n  max_displayed_results: -1\n", true},
+		{"custom rule", "custom_rules:\n  - id: acme-key\n    regex: 'acme_[a-z0-9]{32}'\n", false},
+		{"invalid custom rule regex", "custom_rules:\n  - id: acme-key\n    regex: 'acme_('\n", true},
+		{"unknown selected rule", "select_rules: [nope]\n", true},
+		{"invalid yaml", "exclude_path: [", true},
+		{"missing baseline", "baseline: /nonexistent/.checkmarx_baseline.json\n", true},
+		{"concurrency", "concurrency: 4\n", false},
+		{"negative concurrency", "concurrency: -1\n", true},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			actual := configExcludesToGitExcludes(tc.input)
-			assert.Equal(t, tc.expected, actual)
+			_, err := loadScanConfig(writeConfig(t, tc.content))
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
 		})
 	}
+
+	cfg, err := loadScanConfig(writeConfig(t, "report:\n  context_lines: 0\n"))
+	assert.NoError(t, err)
+	if assert.NotNil(t, cfg.Report.ContextLines) {
+		assert.Equal(t, 0, *cfg.Report.ContextLines)
+	}
+}
+
+func TestLoadScanConfigBaseline(t *testing.T) {
+	baselinePath := filepath.Join(t.TempDir(), "baseline.json")
+	content := `{"version": 1, "findings": [{"rule_id": "jwt", "path": "a.json", "fingerprint": "` + fingerprint.Of("salt", "eyJtoken") + `"}]}`
+	assert.NoError(t, os.WriteFile(baselinePath, []byte(content), 0644))
+
+	cfg, err := loadScanConfig(writeConfig(t, "baseline: "+baselinePath+"\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cfg.baseline.Len())
+
+	// A relative path is resolved from the directory of the configuration file.
+	configPath := filepath.Join(filepath.Dir(baselinePath), "config.yaml")
+	assert.NoError(t, os.WriteFile(configPath, []byte("baseline: baseline.json\n"), 0644))
+	cfg, err = loadScanConfig(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cfg.baseline.Len())
+
+	assert.NoError(t, os.WriteFile(baselinePath, []byte(`{"version": 1, "findings": [{"rule_id": "jwt"}]}`), 0644))
+	_, err = loadScanConfig(writeConfig(t, "baseline: "+baselinePath+"\n"))
+	assert.ErrorContains(t, err, "malformed")
+}
+
+func TestLoadScanConfigReportTemplate(t *testing.T) {
+	configPath := writeConfig(t, "report_template: templates/report.tmpl\n")
+	templates := filepath.Join(filepath.Dir(configPath), "templates")
+	assert.NoError(t, os.Mkdir(templates, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(templates, "report.tmpl"), []byte("{{.Pusher}}\n"), 0644))
+	// The hook runs in a directory unrelated to the configuration.
+	t.Chdir(t.TempDir())
+
+	cfg, err := loadScanConfig(configPath)
+	assert.NoError(t, err)
+	assert.NotNil(t, cfg.reportTemplate)
+
+	absolute := writeConfig(t, "report_template: "+filepath.Join(templates, "report.tmpl")+"\n")
+	cfg, err = loadScanConfig(absolute)
+	assert.NoError(t, err)
+	assert.NotNil(t, cfg.reportTemplate)
 }
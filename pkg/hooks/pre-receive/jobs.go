@@ -0,0 +1,187 @@
+package pre_receive
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	secretsconfig "github.com/Checkmarx/secret-detection/pkg/config"
+	"github.com/Checkmarx/secret-detection/pkg/suppress"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+)
+
+const (
+	// minCommitsPerJob and maxCommitsPerJob bound the chunks a long commit range is split into.
+	minCommitsPerJob = 10
+	maxCommitsPerJob = 200
+	rootArg          = "--root"
+)
+
+// diffJob is a "git log -p" run of the scan: the commits of a range, or a chunk of them.
+type diffJob struct {
+	refName string
+	logArgs []string
+}
+
+// diffJobResult holds what a job recorded, merged in the order of the jobs so that the result of a
+// scan does not depend on which worker finishes first.
+type diffJobResult struct {
+	index   *diffIndex
+	markers suppress.Index
+	err     error
+}
+
+// diffJobs returns the jobs of the ranges. With several workers, the ranges of many commits are split
+// into chunks, so that the commits of a single ref are also parsed in parallel.
+func diffJobs(ctx context.Context, gitDir string, ranges []CommitRange, workers int, pathspecs []string) ([]diffJob, error) {
+	var jobs []diffJob
+	for _, commitRange := range ranges {
+		if workers < 2 {
+			jobs = append(jobs, diffJob{refName: commitRange.RefName, logArgs: commitRange.LogArgs})
+			continue
+		}
+		commits, err := rangeCommits(ctx, gitDir, commitRange, pathspecs)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, chunkRange(commitRange, commits, workers)...)
+	}
+	return jobs, nil
+}
+
+// rangeCommits lists the commits of the range that git log shows for the pathspecs, newest first.
+func rangeCommits(ctx context.Context, gitDir string, commitRange CommitRange, pathspecs []string) ([]string, error) {
+	args := []string{"rev-list"}
+	for _, arg := range commitRange.LogArgs {
+		if arg != rootArg {
+			args = append(args, arg)
+		}
+	}
+	args = append(args, "--")
+	args = append(args, pathspecs...)
+	out, err := gitCommand(ctx, gitDir, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the commits of ref %s: %w", commitRange.RefName, err)
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// chunkRange splits the commits of the range into about one chunk per worker, within the bounds of
+// the chunk size. A range that fits in one chunk is left as is.
+func chunkRange(commitRange CommitRange, commits []string, workers int) []diffJob {
+	size := (len(commits) + workers - 1) / workers
+	size = max(minCommitsPerJob, min(size, maxCommitsPerJob))
+	if len(commits) <= size {
+		return []diffJob{{refName: commitRange.RefName, logArgs: commitRange.LogArgs}}
+	}
+	var prefix []string
+	for _, arg := range commitRange.LogArgs {
+		if arg == rootArg {
+			prefix = append(prefix, rootArg)
+		}
+	}
+	var jobs []diffJob
+	for start := 0; start < len(commits); start += size {
+		end := min(start+size, len(commits))
+		args := append(append([]string{}, prefix...), "--no-walk=unsorted")
+		jobs = append(jobs, diffJob{refName: commitRange.RefName, logArgs: append(args, commits[start:end]...)})
+	}
+	return jobs
+}
+
+// runDiffJobs runs the jobs with the given number of workers, which all send the content to scan to
+// itemsChan. The first error stops the other jobs and is returned. The indexes and inline suppression
+// markers of the jobs are merged in the order of the jobs; markers is nil when inline suppression is
+// disabled.
+func runDiffJobs(ctx context.Context, gitDir string, jobs []diffJob, workers int, itemsChan chan<- twoms.ScanItem, pathspecs []string, markers suppress.Index, scanRemoved bool) (*diffIndex, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	results := make([]diffJobResult, len(jobs))
+	jobCh := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), len(jobs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobCh {
+				results[i] = runDiffJob(ctx, gitDir, jobs[i], itemsChan, pathspecs, markers != nil, scanRemoved)
+				if results[i].err != nil {
+					cancel(results[i].err)
+				}
+			}
+		}()
+	}
+dispatch:
+	for i := range jobs {
+		select {
+		case jobCh <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobCh)
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, context.Cause(ctx)
+	}
+
+	index := newDiffIndex()
+	for _, result := range results {
+		index.merge(result.index)
+		markers.Merge(result.markers)
+	}
+	return index, nil
+}
+
+// runDiffJob parses the output of the git log command of the job.
+func runDiffJob(ctx context.Context, gitDir string, job diffJob, itemsChan chan<- twoms.ScanItem, pathspecs []string, trackMarkers, scanRemoved bool) diffJobResult {
+	result := diffJobResult{index: newDiffIndex()}
+	if trackMarkers {
+		result.markers = suppress.Index{}
+	}
+
+	args := append([]string{"log", "-p"}, job.logArgs...)
+	args = append(args, "--")
+	args = append(args, pathspecs...)
+	diffCmd := gitCommand(ctx, gitDir, args...)
+
+	// Get the stdout pipe to parse the log output.
+	pipe, err := diffCmd.StdoutPipe()
+	if err != nil {
+		result.err = fmt.Errorf("failed to get stdout pipe for ref %s: %w", job.refName, err)
+		return result
+	}
+	if err = diffCmd.Start(); err != nil {
+		result.err = fmt.Errorf("failed to start log command for ref %s: %w", job.refName, err)
+		return result
+	}
+	if err = parseDiffs(ctx, pipe, itemsChan, result.index, result.markers, scanRemoved); err != nil {
+		// Parsing only fails once ctx is done, which stops git.
+		_ = diffCmd.Wait()
+		result.err = fmt.Errorf("failed to parse diff for ref %s: %w", job.refName, err)
+		return result
+	}
+	if err = diffCmd.Wait(); err != nil {
+		result.err = fmt.Errorf("log command failed for ref %s: %w", job.refName, err)
+	}
+	return result
+}
+
+// gitCommand returns the git command with the arguments, run on the repository at gitDir, or on the
+// repository of the working directory when gitDir is empty.
+func gitCommand(ctx context.Context, gitDir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	if gitDir != "" {
+		cmd.Env = append(os.Environ(), "GIT_DIR="+gitDir)
+	}
+	return cmd
+}
+
+// scanPathspecs returns the pathspecs of the scanned files: the whole tree without the excluded paths.
+func scanPathspecs(excludes []string) []string {
+	return append([]string{"."}, secretsconfig.ExcludesToGitPathspecs(excludes)...)
+}
@@ -0,0 +1,164 @@
+package pre_receive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	secretscanner "github.com/Checkmarx/secret-detection/pkg/scanner"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkRange(t *testing.T) {
+	commits := func(n int) []string {
+		list := make([]string, n)
+		for i := range list {
+			list[i] = fmt.Sprintf("c%d", i)
+		}
+		return list
+	}
+	newRef := CommitRange{RefName: "refs/heads/feature", LogArgs: []string{"--root", "abc"}}
+	update := CommitRange{RefName: "refs/heads/main", LogArgs: []string{"111..222"}}
+
+	tests := []struct {
+		name        string
+		commitRange CommitRange
+		commits     int
+		workers     int
+		expected    []diffJob
+	}{
+		{
+			name:        "short range",
+			commitRange: update,
+			commits:     minCommitsPerJob,
+			workers:     4,
+			expected:    []diffJob{{refName: "refs/heads/main", logArgs: []string{"111..222"}}},
+		},
+		{
+			name:        "chunk per worker",
+			commitRange: update,
+			commits:     25,
+			workers:     2,
+			expected: []diffJob{
+				{refName: "refs/heads/main", logArgs: append([]string{"--no-walk=unsorted"}, commits(25)[:13]...)},
+				{refName: "refs/heads/main", logArgs: append([]string{"--no-walk=unsorted"}, commits(25)[13:]...)},
+			},
+		},
+		{
+			name:        "minimum chunk size",
+			commitRange: newRef,
+			commits:     15,
+			workers:     8,
+			expected: []diffJob{
+				{refName: "refs/heads/feature", logArgs: append([]string{"--root", "--no-walk=unsorted"}, commits(15)[:10]...)},
+				{refName: "refs/heads/feature", logArgs: append([]string{"--root", "--no-walk=unsorted"}, commits(15)[10:]...)},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, chunkRange(tc.commitRange, commits(tc.commits), tc.workers))
+		})
+	}
+
+	jobs := chunkRange(update, commits(1000), 2)
+	assert.Len(t, jobs, 5, "chunks have at most %d commits", maxCommitsPerJob)
+}
+
+func TestDiffJobsSingleWorker(t *testing.T) {
+	ranges := []CommitRange{
+		{RefName: "refs/heads/main", LogArgs: []string{"111..222"}},
+		{RefName: "refs/heads/feature", LogArgs: []string{"--root", "abc"}},
+	}
+	assert.Equal(t, 1, PreReceiveConfig{}.workers(), "a single worker is the default")
+	assert.Equal(t, 4, PreReceiveConfig{Concurrency: 4}.workers())
+	jobs, err := diffJobs(context.Background(), "", ranges, PreReceiveConfig{}.workers(), scanPathspecs(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, []diffJob{
+		{refName: "refs/heads/main", logArgs: []string{"111..222"}},
+		{refName: "refs/heads/feature", logArgs: []string{"--root", "abc"}},
+	}, jobs, "a single worker runs one git log per ref, without listing the commits first")
+}
+
+// failingScanner fails after reading the given number of items. It returns an empty report when the
+// items channel is closed first.
+type failingScanner struct{ reads int }
+
+func (s failingScanner) ScanDynamic(itemsCh <-chan twoms.ScanItem) (*reporting.Report, error) {
+	for i := 0; i < s.reads; i++ {
+		if _, ok := <-itemsCh; !ok {
+			return reporting.Init(), nil
+		}
+	}
+	return nil, errScanner
+}
+
+var errScanner = errors.New("scanner failed")
+
+// gitRepo creates a repository with the given number of commits and changes to it. It returns a
+// function that runs git in the repository and returns its output.
+func gitRepo(t *testing.T, commits int) func(args ...string) string {
+	t.Chdir(t.TempDir())
+	git := func(args ...string) string {
+		out, err := exec.Command("git", args...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	git("init", "-q")
+	git("config", "user.email", "dev@example.com")
+	git("config", "user.name", "dev")
+	for i := 0; i < commits; i++ {
+		assert.NoError(t, os.WriteFile(fmt.Sprintf("file%d.txt", i), []byte(fmt.Sprintf("line %d\n", i)), 0o644))
+		git("add", ".")
+		git("commit", "-qm", fmt.Sprintf("commit %d", i))
+	}
+	return git
+}
+
+func TestRunSecretScanStops(t *testing.T) {
+	gitRepo(t, 30)
+	ranges := []CommitRange{{RefName: "refs/heads/main", LogArgs: []string{"--root", "HEAD"}}}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		scanner secretscanner.ItemScanner
+		err     error
+	}{
+		{"scanner fails before reading", context.Background(), failingScanner{}, errScanner},
+		{"scanner fails while reading", context.Background(), failingScanner{reads: 5}, errScanner},
+		{"canceled", canceled, failingScanner{reads: 1 << 30}, context.Canceled},
+	}
+	for _, tc := range tests {
+		for _, workers := range []int{1, 4} {
+			t.Run(fmt.Sprintf("%s with %d workers", tc.name, workers), func(t *testing.T) {
+				restore := newScanner
+				newScanner = func(secretscanner.Config) (secretscanner.ItemScanner, error) { return tc.scanner, nil }
+				t.Cleanup(func() { newScanner = restore })
+
+				done := make(chan error, 1)
+				go func() {
+					_, _, _, err := runSecretScan(tc.ctx, PreReceiveConfig{Concurrency: workers}, ranges, true)
+					done <- err
+				}()
+				select {
+				case err := <-done:
+					assert.ErrorIs(t, err, tc.err)
+				case <-time.After(10 * time.Second):
+					t.Fatal("the scan did not terminate")
+				}
+			})
+		}
+	}
+}
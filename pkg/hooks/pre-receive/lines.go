@@ -0,0 +1,124 @@
+package pre_receive
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+)
+
+// lineRun maps consecutive lines of the scanned content of a source to consecutive lines of its file.
+type lineRun struct {
+	// scanned is the index of the first line of the run in the scanned content.
+	scanned int32
+	// line is the file line of the first line of the run.
+	line  int32
+	count int32
+}
+
+// lineMap maps the lines of the scanned content of a source to the lines of its file. It replaces
+// the diff fragments, which are much larger, once the content is sent to the scanner.
+type lineMap []lineRun
+
+// add records that the next line of the scanned content is the given line of the file.
+func (m *lineMap) add(line int) {
+	if n := len(*m); n > 0 {
+		last := &(*m)[n-1]
+		if int(last.line+last.count) == line {
+			last.count++
+			return
+		}
+		*m = append(*m, lineRun{scanned: last.scanned + last.count, line: int32(line), count: 1})
+		return
+	}
+	*m = append(*m, lineRun{line: int32(line), count: 1})
+}
+
+// fileLines returns the file lines of the scanned lines start to end, which are 0-based like the
+// lines of the findings. Both are mapped, as the scanned lines of a finding need not be consecutive
+// in the file.
+func (m lineMap) fileLines(start, end int) (int, int, error) {
+	startLine, ok := m.fileLine(start)
+	if !ok {
+		return 0, 0, fmt.Errorf("failed to find start line %d in hunks", start)
+	}
+	endLine, ok := m.fileLine(end)
+	if !ok {
+		return 0, 0, fmt.Errorf("failed to find end line %d in hunks", end)
+	}
+	return startLine, endLine, nil
+}
+
+// fileLine returns the file line of a scanned line.
+func (m lineMap) fileLine(scanned int) (int, bool) {
+	i := sort.Search(len(m), func(i int) bool { return int(m[i].scanned+m[i].count) > scanned })
+	if i == len(m) || int(m[i].scanned) > scanned {
+		return 0, false
+	}
+	return int(m[i].line) + scanned - int(m[i].scanned), true
+}
+
+// fragmentLineMaps returns the line maps of the added and removed lines of the fragments.
+func fragmentLineMaps(fragments []*gitdiff.TextFragment) (added, removed lineMap) {
+	for _, tf := range fragments {
+		if tf == nil {
+			continue
+		}
+		newLine, oldLine := int(tf.NewPosition), int(tf.OldPosition)
+		for _, line := range tf.Lines {
+			switch line.Op {
+			case gitdiff.OpAdd:
+				added.add(newLine)
+				newLine++
+			case gitdiff.OpDelete:
+				removed.add(oldLine)
+				oldLine++
+			default:
+				newLine++
+				oldLine++
+			}
+		}
+	}
+	return added, removed
+}
+
+// diffIndex keeps what the report needs of the scanned diffs: the line map of each source and the
+// author of each commit. Its size depends on the number of scanned files, not on their content.
+type diffIndex struct {
+	lines   map[string]lineMap
+	commits map[string]report.CommitInfo
+}
+
+func newDiffIndex() *diffIndex {
+	return &diffIndex{
+		lines:   make(map[string]lineMap),
+		commits: make(map[string]report.CommitInfo),
+	}
+}
+
+// add records the line map of a source of the commit.
+func (idx *diffIndex) add(source string, header *gitdiff.PatchHeader, lines lineMap) {
+	idx.lines[source] = lines
+	if _, ok := idx.commits[header.SHA]; ok {
+		return
+	}
+	author := gitdiff.PatchIdentity{}
+	if header.Author != nil {
+		author = *header.Author
+	}
+	idx.commits[header.SHA] = report.CommitInfo{
+		Author: fmt.Sprintf("%s (%s)", author.Name, author.Email),
+		Date:   header.AuthorDate,
+	}
+}
+
+// merge adds the line maps and commits of another index.
+func (idx *diffIndex) merge(other *diffIndex) {
+	for source, lines := range other.lines {
+		idx.lines[source] = lines
+	}
+	for sha, info := range other.commits {
+		idx.commits[sha] = info
+	}
+}
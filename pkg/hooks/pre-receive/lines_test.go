@@ -0,0 +1,182 @@
+package pre_receive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/metrics"
+	"strings"
+	"testing"
+	"time"
+
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+	"github.com/checkmarx/2ms/v3/plugins"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+const lineMapPatch = `diff --git a/app.go b/app.go
+index 1111111..2222222 100644
+--- a/app.go
++++ b/app.go
+@@ -3,5 +3,6 @@ package app
+ import "os"
+-var a = 1
++var a = 2
++var b = 3
+ var c = 4
+-var d = 5
++var d = 6
+ var e = 7
+@@ -40,2 +41,3 @@ func main() {
+ 	os.Exit(0)
++	// done
+ }
+`
+
+func TestLineMap(t *testing.T) {
+	var m lineMap
+	for _, line := range []int{4, 5, 7, 20, 21} {
+		m.add(line)
+	}
+	assert.Equal(t, lineMap{{scanned: 0, line: 4, count: 2}, {scanned: 2, line: 7, count: 1}, {scanned: 3, line: 20, count: 2}}, m)
+
+	tests := []struct {
+		start, end         int
+		fileStart, fileEnd int
+	}{
+		{0, 0, 4, 4},
+		{0, 1, 4, 5},
+		{1, 2, 5, 7},
+		{2, 2, 7, 7},
+		{1, 4, 5, 21},
+		{4, 4, 21, 21},
+	}
+	for _, tc := range tests {
+		start, end, err := m.fileLines(tc.start, tc.end)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.fileStart, start, "start of scanned line %d", tc.start)
+		assert.Equal(t, tc.fileEnd, end, "end of scanned line %d", tc.start)
+	}
+
+	_, _, err := m.fileLines(5, 5)
+	assert.ErrorContains(t, err, "failed to find start line 5")
+	_, _, err = m.fileLines(4, 5)
+	assert.ErrorContains(t, err, "failed to find end line 5")
+	_, _, err = lineMap(nil).fileLines(0, 0)
+	assert.Error(t, err)
+}
+
+func TestFragmentLineMapsMatchGitInfo(t *testing.T) {
+	file := parsePatch(t, lineMapPatch)[0]
+	added, removed := fragmentLineMaps(file.TextFragments)
+
+	check := func(lines lineMap, contentType plugins.DiffType, count int) {
+		for scanned := 0; scanned < count; scanned++ {
+			// 2ms maps the end as if the scanned lines followed each other in the file, so only the
+			// start is compared.
+			expectedStart, _, err := plugins.GetGitStartAndEndLine(&plugins.GitInfo{
+				Hunks:       file.TextFragments,
+				ContentType: contentType,
+			}, scanned, scanned+1)
+			assert.NoError(t, err)
+			start, end, err := lines.fileLines(scanned, scanned)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedStart, start, "start of scanned line %d", scanned)
+			assert.Equal(t, start, end, "end of scanned line %d", scanned)
+		}
+	}
+	check(added, plugins.AddedContent, 4)
+	check(removed, plugins.RemovedContent, 2)
+}
+
+// syntheticLog writes a "git log -p" output of commits that each add files of the given lines.
+func syntheticLog(w io.Writer, commits, filesPerCommit, lines int) {
+	for c := 0; c < commits; c++ {
+		fmt.Fprintf(w, "commit %040x\nAuthor: Dev <dev@example.com>\nDate:   Mon Jan 2 15:04:05 2006 -0700\n\n    Commit %d\n\n", c+1, c) // nolint:errcheck
+		for f := 0; f < filesPerCommit; f++ {
+			name := fmt.Sprintf("dir/file-%d-%d.txt", c, f)
+			fmt.Fprintf(w, "diff --git a/%[1]s b/%[1]s\nnew file mode 100644\nindex 0000000..1111111\n--- /dev/null\n+++ b/%[1]s\n@@ -0,0 +1,%[2]d @@\n", name, lines) // nolint:errcheck
+			for l := 0; l < lines; l++ {
+				fmt.Fprintf(w, "+value_%d = \"%s\"\n", l, strings.Repeat("x", 60)) // nolint:errcheck
+			}
+		}
+	}
+}
+
+// heapObjects returns the bytes of the live and not yet collected heap objects.
+func heapObjects() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
+
+// heapPeak samples the heap objects until stop is closed and returns the highest value.
+func heapPeak(stop <-chan struct{}) <-chan uint64 {
+	peak := make(chan uint64, 1)
+	go func() {
+		var max uint64
+		ticker := time.NewTicker(time.Millisecond)
+		defer ticker.Stop()
+		for {
+			if v := heapObjects(); v > max {
+				max = v
+			}
+			select {
+			case <-stop:
+				peak <- max
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return peak
+}
+
+// BenchmarkParseDiffs measures the memory of parsing pushes of increasing size. The peak heap grows
+// with the index only, which holds a few line runs per file, and not with the scanned content.
+func BenchmarkParseDiffs(b *testing.B) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	for _, commits := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("files=%d", commits*10), func(b *testing.B) {
+			var peak, retained uint64
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				pr, pw := io.Pipe()
+				go func() {
+					syntheticLog(pw, commits, 10, 50)
+					pw.Close() // nolint:errcheck
+				}()
+				itemsChan := make(chan twoms.ScanItem)
+				go func() {
+					for range itemsChan {
+					}
+				}()
+
+				runtime.GC()
+				before := heapObjects()
+				stop := make(chan struct{})
+				peakCh := heapPeak(stop)
+
+				index := newDiffIndex()
+				if err := parseDiffs(context.Background(), pr, itemsChan, index, nil, true); err != nil {
+					b.Fatal(err)
+				}
+				close(itemsChan)
+				close(stop)
+				if max := <-peakCh; max > before {
+					peak += max - before
+				}
+
+				runtime.GC()
+				if after := heapObjects(); after > before {
+					retained += after - before
+				}
+				runtime.KeepAlive(index)
+			}
+			b.ReportMetric(float64(peak)/float64(b.N), "peak-heap-B")
+			b.ReportMetric(float64(retained)/float64(b.N)/float64(commits*10), "retained-B/file")
+		})
+	}
+}
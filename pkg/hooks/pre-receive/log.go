@@ -3,6 +3,7 @@ package pre_receive
 import (
 	"encoding/json"
 	"fmt"
+	"github.com/Checkmarx/secret-detection/pkg/report"
 	"os"
 	"path/filepath"
 	"strings"
@@ -33,7 +34,39 @@ func logSkip(folderPath string, refs []string) error {
 		return nil
 	}
 
-	// Determine pusher username
+	// Parse each ref line into structured fields
+	updates := parseRefUpdates(refs)
+	parsed := make([]skipRefEntry, 0, len(updates))
+	for _, update := range updates {
+		parsed = append(parsed, skipRefEntry{
+			OldObject: update.OldObject,
+			NewObject: update.NewObject,
+			RefName:   update.RefName,
+		})
+	}
+
+	// Build JSON payload
+	entry := skipLogEntry{
+		User: pusherName(),
+		Refs: parsed,
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal skip log JSON: %w", err)
+	}
+
+	ts := time.Now().UTC().Format("2006-01-02_15-04-05.000000000")
+	fileName := fmt.Sprintf("skip_%s.json", ts)
+	path := filepath.Join(folderPath, fileName)
+
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write skip log JSON to %q: %w", path, err)
+	}
+	return nil
+}
+
+// pusherName returns the username of the pusher as exposed by the Git server.
+func pusherName() string {
 	user := os.Getenv(envGitHubUserLogin)
 	if user == "" {
 		user = os.Getenv(envGitLabUsername)
@@ -44,39 +77,33 @@ func logSkip(folderPath string, refs []string) error {
 	if user == "" {
 		user = "unknown (could not retrieve pusher username)"
 	}
+	return user
+}
 
-	// Parse each ref line into structured fields
-	parsed := make([]skipRefEntry, 0, len(refs))
+// parseRefUpdates parses each "oldRev newRev refName" line, skipping malformed ones.
+func parseRefUpdates(refs []string) []report.RefUpdate {
+	updates := make([]report.RefUpdate, 0, len(refs))
 	for _, r := range refs {
 		parts := strings.Fields(r)
 		if len(parts) < 3 {
 			continue
 		}
-		parsed = append(parsed, skipRefEntry{
+		updates = append(updates, report.RefUpdate{
 			OldObject: parts[0],
 			NewObject: parts[1],
 			RefName:   parts[2],
 		})
 	}
+	return updates
+}
 
-	// Build JSON payload
-	entry := skipLogEntry{
-		User: user,
-		Refs: parsed,
-	}
-	data, err := json.MarshalIndent(entry, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal skip log JSON: %w", err)
-	}
-
-	ts := time.Now().UTC().Format("2006-01-02_15-04-05.000000000")
-	fileName := fmt.Sprintf("skip_%s.json", ts)
-	path := filepath.Join(folderPath, fileName)
-
-	if err := os.WriteFile(path, data, 0o644); err != nil {
-		return fmt.Errorf("failed to write skip log JSON to %q: %w", path, err)
+// refNames returns the names of the updated refs.
+func refNames(updates []report.RefUpdate) []string {
+	names := make([]string, 0, len(updates))
+	for _, update := range updates {
+		names = append(names, update.RefName)
 	}
-	return nil
+	return names
 }
 
 // validateLogsFolderPath checks if the given non-empty folderPath exists and is a directory, returning an error otherwise.
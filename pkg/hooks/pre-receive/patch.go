@@ -0,0 +1,206 @@
+package pre_receive
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	secretscanner "github.com/Checkmarx/secret-detection/pkg/scanner"
+	"github.com/Checkmarx/secret-detection/pkg/suppress"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+)
+
+const (
+	// stdinPath reads the patches of the standard input.
+	stdinPath = "-"
+	// mboxSeparatorPrefix starts the separator of the messages of an mbox.
+	mboxSeparatorPrefix = "From "
+	// mailHeaderPrefix starts the headers of a single mail without the mbox separator.
+	mailHeaderPrefix = "From:"
+	gitDiffPrefix    = "diff --git "
+)
+
+var (
+	// commitSHA matches the object names of git, which the mbox separator of git format-patch holds.
+	commitSHA = regexp.MustCompile(`^([0-9a-f]{40}|[0-9a-f]{64})$`)
+	// mboxSeparator matches the "From <sender> <date>" line that starts each message of an mbox, as
+	// "From 1234abcd Mon Sep 17 00:00:00 2001" written by git format-patch.
+	mboxSeparator = regexp.MustCompile(`^From \S+ +[A-Z][a-z]{2} [A-Z][a-z]{2} +\d{1,2} \d{2}:\d{2}:\d{2}( [+-]\d{4})? \d{4}\s*$`)
+)
+
+// ScanPatch scans the patches of the files at paths, or of the standard input when there are none or
+// a path is "-", with the configuration at configPath, which may be empty, and prints the report. The
+// process exits with status 1 when the findings block the patches. The scan stops when ctx is done or
+// on SIGINT and SIGTERM.
+func ScanPatch(ctx context.Context, configPath string, paths []string) error {
+	ctx, stop := secretscanner.WithSignals(ctx)
+	defer stop()
+	if len(paths) == 0 {
+		paths = []string{stdinPath}
+	}
+	var readers []io.Reader
+	for _, path := range paths {
+		if path == stdinPath {
+			readers = append(readers, os.Stdin)
+			continue
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open patch %s: %w", path, err)
+		}
+		defer file.Close() // nolint:errcheck
+		// The last line of a file may have no newline, and the next file starts a new message.
+		readers = append(readers, file, strings.NewReader("\n"))
+	}
+	return exitOnBlock(RunPatch(ctx, configPath, io.MultiReader(readers...), os.Stdout))
+}
+
+// RunPatch scans the patches read from in, without a repository: a unified diff, the output of
+// git log -p, or one or more patch mails as written by git format-patch or saved in an mbox. The
+// findings are reported per patch with the author and subject of its mail, and the report is written
+// to out, which may be nil. It returns the result instead of exiting. The scan stops when ctx is done.
+func RunPatch(ctx context.Context, configPath string, in io.Reader, out io.Writer) (*report.Result, error) {
+	if out == nil {
+		out = io.Discard
+	}
+	scanConfig, err := loadScanConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateLogsFolderPath(scanConfig.LogsFolderPath); err != nil {
+		return nil, err
+	}
+	return reportDiffs(ctx, scanConfig, func(ctx context.Context, itemsCh chan<- twoms.ScanItem, markers suppress.Index) (*diffIndex, error) {
+		index := newDiffIndex()
+		return index, parsePatches(ctx, in, itemsCh, index, markers)
+	}, CommitScanOptions{Template: report.PatchTemplate()}, out)
+}
+
+// parsePatches splits the input into messages and sends the content of each to scan. The input that
+// is not an mbox is a single message.
+func parsePatches(ctx context.Context, r io.Reader, itemsChan chan<- twoms.ScanItem, index *diffIndex, markers suppress.Index) error {
+	reader := bufio.NewReader(r)
+	var message strings.Builder
+	number := 0
+	flush := func() error {
+		if strings.TrimSpace(message.String()) == "" {
+			message.Reset()
+			return nil
+		}
+		number++
+		err := scanPatchMessage(ctx, message.String(), number, itemsChan, index, markers)
+		message.Reset()
+		return err
+	}
+
+	previousBlank := true
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read patch: %w", err)
+		}
+		// Diff lines start with an operation, and git format-patch does not quote the lines of a
+		// message body that start with "From ", so the whole separator is matched.
+		if previousBlank && mboxSeparator.MatchString(line) {
+			if flushErr := flush(); flushErr != nil {
+				return flushErr
+			}
+		}
+		message.WriteString(line)
+		previousBlank = strings.TrimSpace(line) == ""
+		if err != nil {
+			return flush()
+		}
+	}
+}
+
+// scanPatchMessage sends the content of the message to scan. The files of a patch mail are attributed
+// to its headers; the commits of other messages are those of the git log headers they include, or the
+// patch number when there are none.
+func scanPatchMessage(ctx context.Context, message string, number int, itemsChan chan<- twoms.ScanItem, index *diffIndex, markers suppress.Index) error {
+	patchID := fmt.Sprintf("patch-%d", number)
+	header := mailHeader(message, patchID)
+	// A mail without changes, as the cover letter of a series, may still expose a secret.
+	if err := processPatchMessage(ctx, header, itemsChan, index); err != nil {
+		return err
+	}
+
+	diffs, err := gitdiff.Parse(strings.NewReader(message))
+	if err != nil {
+		return fmt.Errorf("failed to parse patch %d: %w", number, err)
+	}
+	for file := range diffs {
+		switch {
+		case header != nil:
+			file.PatchHeader = header
+		case file.PatchHeader == nil || file.PatchHeader.SHA == "":
+			file.PatchHeader = &gitdiff.PatchHeader{SHA: patchID}
+		}
+		err = processPatchMessage(ctx, file.PatchHeader, itemsChan, index)
+		if err == nil {
+			err = processFileDiff(ctx, file, itemsChan, index, markers, true)
+		}
+		if err == nil {
+			setPatchInfo(index, file.PatchHeader)
+		}
+		if err != nil {
+			for range diffs {
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// processPatchMessage sends the message of the patch to scan and records its subject.
+func processPatchMessage(ctx context.Context, header *gitdiff.PatchHeader, itemsChan chan<- twoms.ScanItem, index *diffIndex) error {
+	if err := processCommitMessage(ctx, header, itemsChan, index); err != nil {
+		return err
+	}
+	setPatchInfo(index, header)
+	return nil
+}
+
+// setPatchInfo records the subject of the patch, and leaves out the author when the patch has none.
+func setPatchInfo(index *diffIndex, header *gitdiff.PatchHeader) {
+	if header == nil {
+		return
+	}
+	info, ok := index.commits[header.SHA]
+	if !ok {
+		return
+	}
+	info.Subject = strings.TrimSpace(header.SubjectPrefix + header.Title)
+	if header.Author == nil {
+		info.Author = ""
+	}
+	index.commits[header.SHA] = info
+}
+
+// mailHeader parses the headers of a patch mail, or returns nil when the message is not a mail. The
+// patch is named after the commit of git format-patch, or patchID when the mail has none.
+func mailHeader(message, patchID string) *gitdiff.PatchHeader {
+	if !strings.HasPrefix(message, mboxSeparatorPrefix) && !strings.HasPrefix(message, mailHeaderPrefix) {
+		return nil
+	}
+	preamble := message
+	if i := strings.Index(message, "\n"+gitDiffPrefix); i >= 0 {
+		preamble = message[:i+1]
+	}
+	header, err := gitdiff.ParsePatchHeader(preamble)
+	if err != nil {
+		return nil
+	}
+	if !commitSHA.MatchString(header.SHA) || strings.Trim(header.SHA, "0") == "" {
+		// git format-patch --zero-commit, or the separator of a mail client.
+		header.SHA = patchID
+	}
+	return header
+}
@@ -0,0 +1,128 @@
+package pre_receive
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPatch(t *testing.T) {
+	git := gitRepo(t, 1)
+	assert.NoError(t, os.WriteFile("config.env", []byte("ACME_KEY="+acmeKey+"\n"), 0o644))
+	git("add", ".")
+	git("commit", "-qm", "Add the key", "--author", "Jane Dev <jane@example.com>")
+	leaked := git("rev-parse", "HEAD")
+	assert.NoError(t, os.WriteFile("notes.txt", []byte("nothing to see\n"), 0o644))
+	git("add", ".")
+	git("commit", "-qm", "Add notes")
+	configPath := acmeConfig(t)
+
+	tests := []struct {
+		name    string
+		patch   string
+		source  string
+		subject string
+		author  string
+	}{
+		{
+			name:    "format-patch mbox",
+			patch:   git("format-patch", "--stdout", "HEAD~2..HEAD"),
+			source:  "Added:" + leaked + ":config.env",
+			subject: "[PATCH 1/2] Add the key",
+			author:  "Jane Dev (jane@example.com)",
+		},
+		{
+			name:    "zero commit",
+			patch:   git("format-patch", "--stdout", "--zero-commit", "-1", "HEAD~1"),
+			source:  "Added:patch-1:config.env",
+			subject: "[PATCH] Add the key",
+			author:  "Jane Dev (jane@example.com)",
+		},
+		{
+			name:    "git log",
+			patch:   git("log", "-p", "HEAD~2..HEAD"),
+			source:  "Added:" + leaked + ":config.env",
+			subject: "Add the key",
+			author:  "Jane Dev (jane@example.com)",
+		},
+		{
+			name:   "unified diff",
+			patch:  git("diff", "HEAD~2", "HEAD"),
+			source: "Added:patch-1:config.env",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			result, err := RunPatch(context.Background(), configPath, strings.NewReader(tc.patch+"\n"), &out)
+			assert.NoError(t, err)
+			assert.True(t, result.Blocked())
+			assert.Contains(t, findingRules(t, result.Findings, tc.source), "acme-key")
+			assert.Equal(t, out.String(), result.Rendered)
+			assert.Contains(t, result.Rendered, "across 1 patch\n")
+			if tc.subject != "" {
+				assert.Contains(t, result.Rendered, "Subject: "+tc.subject+"\n")
+				assert.Contains(t, string(result.JSON), `"subject": "`+tc.subject+`"`)
+			} else {
+				assert.NotContains(t, result.Rendered, "Subject:")
+			}
+			if tc.author != "" {
+				assert.Contains(t, result.Rendered, "Author: "+tc.author+"\n")
+			} else {
+				assert.NotContains(t, result.Rendered, "Author:")
+			}
+		})
+	}
+
+	result, err := RunPatch(context.Background(), configPath, strings.NewReader(git("format-patch", "--stdout", "HEAD~1..HEAD")), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, severity.Pass, result.Decision)
+	assert.Equal(t, "No secrets detected by Cx Secret Scanner", result.Rendered)
+}
+
+func TestRunPatchMessage(t *testing.T) {
+	mbox := `From 0000000000000000000000000000000000000000 Mon Sep 17 00:00:00 2001
+From: Jane Dev <jane@example.com>
+Date: Tue, 3 Mar 2026 10:00:00 +0100
+Subject: [PATCH 0/2] Rotate the keys
+
+The old key was ` + acmeKey + `
+
+From 0000000000000000000000000000000000000000 Mon Sep 17 00:00:00 2001
+From: John Dev <john@example.com>
+Date: Tue, 3 Mar 2026 11:00:00 +0100
+Subject: [PATCH 1/2] Nothing
+
+From the start of the body, nothing is leaked.
+---
+ a.txt | 1 +
+ 1 file changed, 1 insertion(+)
+
+diff --git a/a.txt b/a.txt
+index 1111111..2222222 100644
+--- a/a.txt
++++ b/a.txt
+@@ -1 +1,3 @@
+ a
++From here on
++key = ` + acmeKey + `
+-- 
+2.43.0
+`
+	result, err := RunPatch(context.Background(), acmeConfig(t), strings.NewReader(mbox), nil)
+	assert.NoError(t, err)
+	sources := map[string]bool{}
+	for _, finding := range result.Findings {
+		sources[finding.Source] = true
+	}
+	assert.Equal(t, map[string]bool{"Added:patch-1:commit message": true, "Added:patch-2:a.txt": true}, sources)
+	// The patches are listed from the newest, like the commits of the pre-receive report.
+	assert.Contains(t, result.Rendered, "Patch #2 (patch-1)")
+	assert.Contains(t, result.Rendered, "Subject: [PATCH 1/2] Nothing\nAuthor: John Dev (john@example.com)\n")
+	assert.Contains(t, result.Rendered, "Subject: [PATCH 0/2] Rotate the keys\nAuthor: Jane Dev (jane@example.com)\nDate: ")
+}
@@ -2,19 +2,28 @@ package pre_receive
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"github.com/Checkmarx/secret-detection/pkg/baseline"
+	"github.com/Checkmarx/secret-detection/pkg/ignore"
 	"github.com/Checkmarx/secret-detection/pkg/report"
+	secretscanner "github.com/Checkmarx/secret-detection/pkg/scanner"
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/Checkmarx/secret-detection/pkg/suppress"
+	"github.com/Checkmarx/secret-detection/pkg/verify"
 	"github.com/checkmarx/2ms/v3/lib/reporting"
 	"github.com/checkmarx/2ms/v3/lib/secrets"
 	twoms "github.com/checkmarx/2ms/v3/pkg"
-	"github.com/checkmarx/2ms/v3/plugins"
 	"github.com/gitleaks/go-gitdiff/gitdiff"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"io"
 	"os"
-	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 )
 
 const (
@@ -23,99 +32,205 @@ const (
 	gitPushOptionPrefix = "GIT_PUSH_OPTION_"
 	gitPushOptionCount  = "GIT_PUSH_OPTION_COUNT"
 	skipScanKeyword     = "skip-secret-scanner"
+	// commitMessageFormat prints each commit as NUL-terminated fields: the SHA, the author name, email
+	// and date, and the raw message.
+	commitMessageFormat = "--format=%H%x00%an%x00%ae%x00%aI%x00%B%x00"
+	commitMessageFields = 5
 )
 
-func Scan(configPath string) error {
-	refs, err := readRefsFromStdin()
+// Scan is the entry point of the pre-receive hook. It scans the commits of the ref updates read from
+// the standard input and prints the report; the process exits with status 1 when the findings block
+// the push. The scan stops when ctx is done or on SIGINT and SIGTERM.
+func Scan(ctx context.Context, configPath string) error {
+	ctx, stop := secretscanner.WithSignals(ctx)
+	defer stop()
+	return exitOnBlock(Run(ctx, configPath, os.Stdin, os.Stdout))
+}
+
+// Run scans the commits of the "oldRev newRev refName" ref updates read from in and writes the report
+// to out, which may be nil, like the pre-receive hook. It returns the result instead of exiting, so that
+// the scan can be embedded. The scan stops when ctx is done.
+func Run(ctx context.Context, configPath string, in io.Reader, out io.Writer) (*report.Result, error) {
+	if out == nil {
+		out = io.Discard
+	}
+	refs, err := readRefs(in)
 	if err != nil {
-		return fmt.Errorf("reading pushed refs: %w", err)
+		return nil, fmt.Errorf("reading pushed refs: %w", err)
 	}
 
 	scanConfig, err := loadScanConfig(configPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = validateLogsFolderPath(scanConfig.LogsFolderPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if scanConfig.AllowSkip && skipScan() {
-		fmt.Print("Cx Secret Scanner bypassed")
-		err = logSkip(scanConfig.LogsFolderPath, refs)
-		return err
+		result := &report.Result{Decision: severity.Pass, Skipped: true, Rendered: "Cx Secret Scanner bypassed"}
+		if _, err = io.WriteString(out, result.Rendered); err != nil {
+			return nil, err
+		}
+		if err = logSkip(scanConfig.LogsFolderPath, refs); err != nil {
+			return nil, err
+		}
+		return result, nil
 	}
 
-	scanReport, fileDiffs, err := runSecretScan(scanConfig, refs)
+	ranges, err := commitRanges(refs)
 	if err != nil {
-		return fmt.Errorf("failed to run scan: %w", err)
+		return nil, err
 	}
+	return scanCommits(ctx, scanConfig, ranges, CommitScanOptions{
+		Pusher: pusherName(),
+		Refs:   parseRefUpdates(refs),
+	}, out)
+}
 
-	if scanReport.TotalSecretsFound > 0 {
-		commitInfo := commitInfoByCommitID(fileDiffs)
-		err = updateResultsStartAndEndLine(scanReport, fileDiffs)
-		if err != nil {
-			return err
-		}
-		removeDuplicateResults(scanReport)
-		preReceiveReportText, preReceiveReportJson, err := report.PreReceiveReport(scanReport, commitInfo)
-		if err != nil {
-			return err
-		}
-		fmt.Print(preReceiveReportText)
-		err = logJSONReport(scanConfig.LogsFolderPath, preReceiveReportJson)
-		if err != nil {
-			return err
-		}
+// exitOnBlock ends the process with status 1 when the result of a scan blocks the push, as git
+// rejects the push on a non-zero status.
+func exitOnBlock(result *report.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if result.Blocked() {
 		os.Exit(1)
 	}
-	fmt.Print("No secrets detected by Cx Secret Scanner")
 	return nil
 }
 
-func runSecretScan(scanConfig PreReceiveConfig, refs []string) (*reporting.Report, map[string]*report.FileInfo, error) {
-	zerolog.SetGlobalLevel(zerolog.Disabled)
+// CommitRange selects the commits of a ref update to scan, as arguments of git log.
+type CommitRange struct {
+	RefName string
+	LogArgs []string
+}
 
-	// Create the scanner.
-	scanner := twoms.NewScanner()
-	itemsCh := make(chan twoms.ScanItem)
-	reportCh := make(chan *reporting.Report)
-	errScanCh := make(chan error, 1)
-
-	go func() {
-		scanReport, err := scanner.ScanDynamic(itemsCh, twoms.ScanConfig{
-			IgnoreResultIds: scanConfig.IgnoreSecret,
-			IgnoreRules:     scanConfig.IgnoreRule,
-		})
-		if err != nil {
-			errScanCh <- err
-			return
-		}
-		reportCh <- scanReport
-	}()
+// CommitScanOptions adapts the scan of commit ranges to the hook that runs it.
+type CommitScanOptions struct {
+	// Pusher and Refs are made available to the report template.
+	Pusher string
+	Refs   []report.RefUpdate
+	// Template is the report layout used when the configuration sets none.
+	Template *template.Template
+	// Ignore holds the entries of an ignore file, applied in addition to the configuration.
+	Ignore ignore.List
+	// Baseline holds the accepted findings when the configuration sets no baseline.
+	Baseline *baseline.Baseline
+	// Validity, when set, replaces the validity settings of the configuration: the pre-push hook reads
+	// the configuration of the repository, which must not choose where the secrets are sent.
+	Validity *verify.Config
+}
 
-	fileDiffs, err := runDiffParsing(itemsCh, scanConfig, refs)
+// ScanCommits scans the commit ranges with the configuration at configPath, which may be empty, and
+// prints the report like the pre-receive hook does. The process exits with status 1 when the findings
+// block the push. The scan stops when ctx is done or on SIGINT and SIGTERM.
+func ScanCommits(ctx context.Context, configPath string, ranges []CommitRange, opts CommitScanOptions) error {
+	ctx, stop := secretscanner.WithSignals(ctx)
+	defer stop()
+	return exitOnBlock(RunCommits(ctx, configPath, ranges, opts, os.Stdout))
+}
+
+// RunCommits scans the commit ranges like ScanCommits and writes the report to out, which may be nil.
+// It returns the result instead of exiting. The scan stops when ctx is done.
+func RunCommits(ctx context.Context, configPath string, ranges []CommitRange, opts CommitScanOptions, out io.Writer) (*report.Result, error) {
+	if out == nil {
+		out = io.Discard
+	}
+	scanConfig, err := loadScanConfig(configPath)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	close(itemsCh)
-
-	// Wait for the scanner to finish.
-	select {
-	case rep := <-reportCh:
-		return rep, fileDiffs, nil
-	case err = <-errScanCh:
-		return nil, nil, err
+	if err = validateLogsFolderPath(scanConfig.LogsFolderPath); err != nil {
+		return nil, err
 	}
+	return scanCommits(ctx, scanConfig, ranges, opts, out)
+}
+
+func scanCommits(ctx context.Context, scanConfig PreReceiveConfig, ranges []CommitRange, opts CommitScanOptions, out io.Writer) (*report.Result, error) {
+	return reportDiffs(ctx, scanConfig, func(ctx context.Context, itemsCh chan<- twoms.ScanItem, markers suppress.Index) (*diffIndex, error) {
+		return runDiffParsing(ctx, itemsCh, scanConfig, ranges, markers, true)
+	}, opts, out)
 }
 
-func runDiffParsing(itemsChan chan twoms.ScanItem, config PreReceiveConfig, refs []string) (map[string]*report.FileInfo, error) {
-	fileDiffs := make(map[string]*report.FileInfo)
-	scanner := bufio.NewScanner(os.Stdin)
+// reportDiffs scans the diffs sent by produce, writes the report of the findings to out and logs it
+// like the pre-receive hook.
+func reportDiffs(ctx context.Context, scanConfig PreReceiveConfig, produce diffProducer, opts CommitScanOptions, out io.Writer) (*report.Result, error) {
+	scanConfig.IgnoreSecret = append(scanConfig.IgnoreSecret, opts.Ignore.ResultIDs()...)
+	if opts.Validity != nil {
+		scanConfig.Validity = *opts.Validity
+	}
+	scanReport, index, suppressed, err := scanDiffs(ctx, scanConfig, produce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run scan: %w", err)
+	}
+	opts.Ignore.Apply(scanReport)
+	accepted := scanConfig.baseline
+	if accepted == nil {
+		accepted = opts.Baseline
+	}
+	result := &report.Result{Suppressed: suppressed, Baselined: accepted.Apply(scanReport)}
 
-	fileExclusion := configExcludesToGitExcludes(config.ExcludePath)
+	result.Decision = scanConfig.Severity.Apply(scanReport)
+	if scanReport.TotalSecretsFound > 0 {
+		err = updateResultsStartAndEndLine(scanReport, index)
+		if err != nil {
+			return nil, err
+		}
+		removeDuplicateResults(scanReport)
+		if scanConfig.Validity.Enabled {
+			verify.Apply(ctx, verify.NewHTTPVerifier(scanConfig.Validity), scanReport)
+			result.Decision = scanConfig.Validity.Decide(result.Decision, scanReport, refNames(opts.Refs))
+		}
+		result.Findings = report.RedactedFindings(scanReport, scanConfig.Redaction)
+		reportTemplate := scanConfig.reportTemplate
+		if reportTemplate == nil {
+			reportTemplate = opts.Template
+		}
+		result.Rendered, result.JSON, err = report.PreReceiveReport(scanReport, index.commits, report.Options{
+			Redaction:        scanConfig.Redaction,
+			Template:         reportTemplate,
+			Pusher:           opts.Pusher,
+			Refs:             opts.Refs,
+			Severity:         scanConfig.Severity,
+			Decision:         result.Decision,
+			RulesUsed:        scanConfig.ruleSelection().SelectedIDs(scanConfig.CustomRules),
+			SuppressedInline: suppressed,
+			Baselined:        result.Baselined,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(out, result.Rendered); err != nil {
+			return nil, err
+		}
+		if err = logJSONReport(scanConfig.LogsFolderPath, result.JSON); err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+	result.Rendered = "No secrets detected by Cx Secret Scanner"
+	var notes []string
+	if suppressed > 0 {
+		notes = append(notes, fmt.Sprintf("%d suppressed by inline allow-comments", suppressed))
+	}
+	if result.Baselined > 0 {
+		notes = append(notes, fmt.Sprintf("%d accepted by the baseline", result.Baselined))
+	}
+	if len(notes) > 0 {
+		result.Rendered += fmt.Sprintf(" (%s)", strings.Join(notes, ", "))
+	}
+	if _, err = io.WriteString(out, result.Rendered); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
 
+// commitRanges returns the commits to scan for each "oldRev newRev refName" line. Deleted refs have none.
+func commitRanges(refs []string) ([]CommitRange, error) {
+	var ranges []CommitRange
 	for _, line := range refs {
 		// Expect exactly three fields: oldRev, newRev, and refName.
 		parts := strings.Fields(line)
@@ -124,55 +239,174 @@ func runDiffParsing(itemsChan chan twoms.ScanItem, config PreReceiveConfig, refs
 		}
 		oldRev, newRev, refName := parts[0], parts[1], parts[2]
 
-		var diffCmd *exec.Cmd
-		var args []string
 		switch {
 		case oldRev == zeroRev && newRev != zeroRev:
 			// New ref — show the patch for the root commit.
-			args = []string{"log", "-p", "--root", newRev}
+			ranges = append(ranges, CommitRange{RefName: refName, LogArgs: []string{"--root", newRev}})
 		case newRev == zeroRev:
 			// Ref deletion — nothing to diff.
-			continue
 		default:
 			// Normal update: diffs between old and new revisions.
-			args = []string{"log", "-p", fmt.Sprintf("%s..%s", oldRev, newRev)}
+			ranges = append(ranges, CommitRange{RefName: refName, LogArgs: []string{fmt.Sprintf("%s..%s", oldRev, newRev)}})
 		}
+	}
+	return ranges, nil
+}
 
-		// Add pathspec separator and exclusions
-		args = append(args, "--", ".")
-		args = append(args, fileExclusion...)
+// runSecretScan scans the commits of the ranges. The removed lines are scanned when scanRemoved is set.
+func runSecretScan(ctx context.Context, scanConfig PreReceiveConfig, ranges []CommitRange, scanRemoved bool) (*reporting.Report, *diffIndex, int, error) {
+	return scanDiffs(ctx, scanConfig, func(ctx context.Context, itemsCh chan<- twoms.ScanItem, markers suppress.Index) (*diffIndex, error) {
+		return runDiffParsing(ctx, itemsCh, scanConfig, ranges, markers, scanRemoved)
+	})
+}
 
-		diffCmd = exec.Command("git", args...)
+// diffProducer sends the content of parsed diffs to scan to itemsCh and returns the index of the
+// scanned sources. The inline suppression markers are recorded in markers unless it is nil.
+type diffProducer func(ctx context.Context, itemsCh chan<- twoms.ScanItem, markers suppress.Index) (*diffIndex, error)
 
-		// Get the stdout pipe to parse the log output.
-		pipe, err := diffCmd.StdoutPipe()
-		if err != nil {
-			return nil, fmt.Errorf("failed to get stdout pipe for ref %s: %w", refName, err)
+// scanDiffs scans the diffs sent by produce. It returns the report, with the findings suppressed inline
+// removed, the index of the scanned sources and the number of suppressed findings.
+func scanDiffs(ctx context.Context, scanConfig PreReceiveConfig, produce diffProducer) (*reporting.Report, *diffIndex, int, error) {
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+
+	var markers suppress.Index
+	if !scanConfig.DisableInlineSuppression {
+		markers = suppress.Index{}
+	}
+
+	// Create the scanner.
+	scanner, err := newScanner(secretscanner.Config{
+		IgnoreResultIds: scanConfig.IgnoreSecret,
+		Selection:       scanConfig.ruleSelection(),
+		CustomRules:     scanConfig.CustomRules,
+		Entropy:         scanConfig.Entropy,
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	var index *diffIndex
+	rep, err := secretscanner.Run(ctx, scanner, func(ctx context.Context, itemsCh chan<- twoms.ScanItem) error {
+		var err error
+		index, err = produce(ctx, itemsCh, markers)
+		return err
+	})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	sortResults(rep)
+	suppressed := markers.Apply(rep)
+	return rep, index, suppressed, nil
+}
+
+// newScanner creates the secrets scanner; tests replace it to inject failures.
+var newScanner = func(config secretscanner.Config) (secretscanner.ItemScanner, error) {
+	return secretscanner.New(config)
+}
+
+// runDiffParsing sends the content of the commits of the ranges to the scanner, with the configured
+// number of workers running git log at the same time, and then their messages.
+func runDiffParsing(ctx context.Context, itemsChan chan<- twoms.ScanItem, config PreReceiveConfig, ranges []CommitRange, markers suppress.Index, scanRemoved bool) (*diffIndex, error) {
+	pathspecs := scanPathspecs(config.ExcludePath)
+	workers := config.workers()
+	jobs, err := diffJobs(ctx, config.gitDir, ranges, workers, pathspecs)
+	if err != nil {
+		return nil, err
+	}
+	index, err := runDiffJobs(ctx, config.gitDir, jobs, workers, itemsChan, pathspecs, markers, scanRemoved)
+	if err != nil {
+		return nil, err
+	}
+	for _, commitRange := range ranges {
+		if err = runMessageScan(ctx, config.gitDir, commitRange, itemsChan, index); err != nil {
+			return nil, err
 		}
-		if err = diffCmd.Start(); err != nil {
-			return nil, fmt.Errorf("failed to start log command for ref %s: %w", refName, err)
+	}
+	return index, nil
+}
+
+// runMessageScan sends the messages of the commits of the range to the scanner. They are listed apart
+// from the diffs and without the pathspecs, so that the commits without a scanned file diff, as empty
+// commits, merges and commits that only change excluded files, are scanned too.
+func runMessageScan(ctx context.Context, gitDir string, commitRange CommitRange, itemsChan chan<- twoms.ScanItem, index *diffIndex) error {
+	args := append([]string{"log", commitMessageFormat}, commitRange.LogArgs...)
+	logCmd := gitCommand(ctx, gitDir, append(args, "--")...)
+	pipe, err := logCmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout pipe for ref %s: %w", commitRange.RefName, err)
+	}
+	if err = logCmd.Start(); err != nil {
+		return fmt.Errorf("failed to start log command for ref %s: %w", commitRange.RefName, err)
+	}
+	if err = parseCommitMessages(ctx, pipe, itemsChan, index); err != nil {
+		_ = logCmd.Wait()
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
 		}
+		return fmt.Errorf("failed to read the commit messages of ref %s: %w", commitRange.RefName, err)
+	}
+	if err = logCmd.Wait(); err != nil {
+		return fmt.Errorf("log command failed for ref %s: %w", commitRange.RefName, err)
+	}
+	return nil
+}
 
-		diffs, err := gitdiff.Parse(pipe)
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse diff for ref %s: %w", refName, err)
+// parseCommitMessages sends the messages of a git log output printed with commitMessageFormat to the
+// scanner. The messages already scanned, of the commits of a previous range, are skipped.
+func parseCommitMessages(ctx context.Context, r io.Reader, itemsChan chan<- twoms.ScanItem, index *diffIndex) error {
+	reader := bufio.NewReader(r)
+	for {
+		var fields [commitMessageFields]string
+		for i := range fields {
+			field, err := reader.ReadString(0)
+			if err == io.EOF && i == 0 && strings.TrimSpace(field) == "" {
+				return nil
+			}
+			if err != nil {
+				if err == io.EOF {
+					return io.ErrUnexpectedEOF
+				}
+				return err
+			}
+			fields[i] = strings.TrimSuffix(field, "\x00")
 		}
-		for file := range diffs {
-			processFileDiff(file, itemsChan, fileDiffs)
+		// git ends each commit with a newline, before the SHA of the next one.
+		header := &gitdiff.PatchHeader{
+			SHA:    strings.TrimSpace(fields[0]),
+			Author: &gitdiff.PatchIdentity{Name: fields[1], Email: fields[2]},
 		}
-		if err = diffCmd.Wait(); err != nil {
-			return nil, fmt.Errorf("log command failed for ref %s: %w", refName, err)
+		header.AuthorDate, _ = time.Parse(time.RFC3339, fields[3])
+		// The message is the title and the body of the header, so that the lines of the findings are
+		// the lines of the message.
+		header.Title, header.Body, _ = strings.Cut(strings.TrimSpace(fields[4]), "\n\n")
+		if err := processCommitMessage(ctx, header, itemsChan, index); err != nil {
+			return err
 		}
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("failed reading input: %w", err)
+// parseDiffs sends the content of the commits of a "git log -p" output to the scanner. Each file is
+// released once sent: only its line maps are kept in the index. The commit messages are scanned
+// apart, by runMessageScan. Parsing stops when ctx is done.
+func parseDiffs(ctx context.Context, r io.Reader, itemsChan chan<- twoms.ScanItem, index *diffIndex, markers suppress.Index, scanRemoved bool) error {
+	diffs, err := gitdiff.Parse(r)
+	if err != nil {
+		return err
 	}
-
-	return fileDiffs, nil
+	for file := range diffs {
+		err = processFileDiff(ctx, file, itemsChan, index, markers, scanRemoved)
+		if err != nil {
+			// The parser blocks until its files are received. The rest of the input is short once
+			// the git process writing it is stopped with ctx.
+			for range diffs {
+			}
+			return err
+		}
+	}
+	return nil
 }
 
-func processFileDiff(file *gitdiff.File, itemsChan chan twoms.ScanItem, fileDiffs map[string]*report.FileInfo) {
+func processFileDiff(ctx context.Context, file *gitdiff.File, itemsChan chan<- twoms.ScanItem, index *diffIndex, markers suppress.Index, scanRemoved bool) error {
 	if file.PatchHeader == nil {
 		// When parsing the PatchHeader, the token size limit may be exceeded, resulting in a nil value.
 		// This scenario is unlikely but may cause the scan to never complete.
@@ -184,12 +418,9 @@ func processFileDiff(file *gitdiff.File, itemsChan chan twoms.ScanItem, fileDiff
 
 	// Skip binary files.
 	if file.IsBinary {
-		return
+		return nil
 	}
 
-	// Extract the changes (added and removed) from the text fragments.
-	addedChanges, removedChanges := extractChanges(file.TextFragments)
-
 	var fileName string
 	if file.IsDelete {
 		fileName = file.OldName
@@ -197,69 +428,138 @@ func processFileDiff(file *gitdiff.File, itemsChan chan twoms.ScanItem, fileDiff
 		fileName = file.NewName
 	}
 	id := fmt.Sprintf("hooks-%s", fileName)
+	addedSource := fmt.Sprintf("Added:%s:%s", file.PatchHeader.SHA, fileName)
+	removedSource := fmt.Sprintf("Deleted:%s:%s", file.PatchHeader.SHA, fileName)
+
+	// Extract the changes (added and removed) from the text fragments.
+	addedChanges, removedChanges := extractChanges(file.TextFragments, markers.NewTrack(addedSource), markers.NewTrack(removedSource))
+	addedLines, removedLines := fragmentLineMaps(file.TextFragments)
 
 	if addedChanges != "" {
-		source := fmt.Sprintf("Added:%s:%s", file.PatchHeader.SHA, fileName)
-		itemsChan <- twoms.ScanItem{
+		source := addedSource
+		err := secretscanner.Send(ctx, itemsChan, twoms.ScanItem{
 			Content: &addedChanges,
 			ID:      id,
 			Source:  source,
+		})
+		if err != nil {
+			return err
 		}
-		fileDiffs[source] = &report.FileInfo{File: file, ContentType: plugins.AddedContent}
+		index.add(source, file.PatchHeader, addedLines)
 	}
 
-	if removedChanges != "" {
-		source := fmt.Sprintf("Deleted:%s:%s", file.PatchHeader.SHA, fileName)
-		itemsChan <- twoms.ScanItem{
+	if removedChanges != "" && scanRemoved {
+		source := removedSource
+		err := secretscanner.Send(ctx, itemsChan, twoms.ScanItem{
 			Content: &removedChanges,
 			ID:      id,
 			Source:  source,
+		})
+		if err != nil {
+			return err
 		}
-		fileDiffs[source] = &report.FileInfo{File: file, ContentType: plugins.RemovedContent}
+		index.add(source, file.PatchHeader, removedLines)
+	}
+	return nil
+}
+
+// processCommitMessage sends the message of the commit to the scanner, once per commit, as the added
+// content of a pseudo-file. The files of a commit share its patch header.
+func processCommitMessage(ctx context.Context, header *gitdiff.PatchHeader, itemsChan chan<- twoms.ScanItem, index *diffIndex) error {
+	if header == nil {
+		return nil
+	}
+	source := fmt.Sprintf("Added:%s:%s", header.SHA, report.CommitMessageFile)
+	if _, seen := index.lines[source]; seen {
+		return nil
+	}
+	message := header.Message()
+	if message == "" {
+		return nil
 	}
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+
+	// The findings are on the lines of the message.
+	lines := strings.Count(message, "\n")
+	index.add(source, header, lineMap{{line: 1, count: int32(lines)}})
+	return secretscanner.Send(ctx, itemsChan, twoms.ScanItem{
+		Content: &message,
+		ID:      fmt.Sprintf("hooks-%s", report.CommitMessageFile),
+		Source:  source,
+	})
 }
 
-func extractChanges(fragments []*gitdiff.TextFragment) (added string, removed string) {
+// extractChanges returns the added and removed lines of the fragments. The inline suppression
+// markers of the lines are recorded by the added and removed tracks, which may be nil.
+func extractChanges(fragments []*gitdiff.TextFragment, addedTrack, removedTrack *suppress.Track) (added string, removed string) {
 	var addedBuilder, removedBuilder strings.Builder
 
 	for _, tf := range fragments {
 		if tf == nil {
 			continue
 		}
+		// Lines of different fragments are not adjacent.
+		addedTrack.Context("")
+		removedTrack.Context("")
 		for i := range tf.Lines {
 			switch tf.Lines[i].Op {
 			case gitdiff.OpAdd:
 				addedBuilder.WriteString(tf.Lines[i].Line)
+				addedTrack.Scanned(tf.Lines[i].Line)
 			case gitdiff.OpDelete:
 				removedBuilder.WriteString(tf.Lines[i].Line)
+				removedTrack.Scanned(tf.Lines[i].Line)
 			default:
+				addedTrack.Context(tf.Lines[i].Line)
+				removedTrack.Context(tf.Lines[i].Line)
 			}
-			// Clean up the line content to free memory.
-			tf.Lines[i].Line = ""
 		}
 	}
 
 	return addedBuilder.String(), removedBuilder.String()
 }
 
-func updateResultsStartAndEndLine(report *reporting.Report, fileDiffs map[string]*report.FileInfo) error {
-	for id, secrets := range report.Results {
-		for secretIndex, secret := range secrets {
-			fileDiff := fileDiffs[secret.Source]
-			newStartLine, newEndLine, err := plugins.GetGitStartAndEndLine(&plugins.GitInfo{
-				Hunks:       fileDiff.File.TextFragments,
-				ContentType: fileDiff.ContentType,
-			}, secret.StartLine, secret.EndLine)
+// updateResultsStartAndEndLine maps the lines of the findings, which are lines of the scanned
+// content, to the lines of their files.
+func updateResultsStartAndEndLine(report *reporting.Report, index *diffIndex) error {
+	for _, secrets := range report.Results {
+		for _, secret := range secrets {
+			// A match that ends with the newline of its line, as those of generic-api-key do, ends on
+			// the next scanned line, which need not follow it in the file.
+			end := min(secret.EndLine, secret.StartLine+strings.Count(strings.TrimSuffix(secret.Value, "\n"), "\n"))
+			startLine, endLine, err := index.lines[secret.Source].fileLines(secret.StartLine, max(end, secret.StartLine))
 			if err != nil {
 				return err
 			}
-			report.Results[id][secretIndex].StartLine = newStartLine
-			report.Results[id][secretIndex].EndLine = newEndLine
+			secret.StartLine = startLine
+			secret.EndLine = endLine
 		}
 	}
 	return nil
 }
 
+// sortResults orders the results of each ID by source and position, which the order the workers sent
+// the items in does not change.
+func sortResults(report *reporting.Report) {
+	for _, list := range report.Results {
+		sort.SliceStable(list, func(i, j int) bool {
+			a, b := list[i], list[j]
+			if a.Source != b.Source {
+				return a.Source < b.Source
+			}
+			if a.StartLine != b.StartLine {
+				return a.StartLine < b.StartLine
+			}
+			if a.StartColumn != b.StartColumn {
+				return a.StartColumn < b.StartColumn
+			}
+			return a.RuleID < b.RuleID
+		})
+	}
+}
+
 func removeDuplicateResults(report *reporting.Report) {
 	seenKeys := make(map[string]struct{})
 	newResults := make(map[string][]*secrets.Secret, len(report.Results))
@@ -310,9 +610,9 @@ func skipScan() bool {
 	return false
 }
 
-// readRefsFromStdin returns each “oldRev newRev refName” line as a slice.
-func readRefsFromStdin() ([]string, error) {
-	scanner := bufio.NewScanner(os.Stdin)
+// readRefs returns each “oldRev newRev refName” line read from in as a slice.
+func readRefs(in io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(in)
 	var refs []string
 	for scanner.Scan() {
 		refs = append(refs, scanner.Text())
@@ -322,17 +622,3 @@ func readRefsFromStdin() ([]string, error) {
 	}
 	return refs, nil
 }
-
-func commitInfoByCommitID(fileDiffs map[string]*report.FileInfo) map[string]report.CommitInfo {
-	infos := make(map[string]report.CommitInfo)
-	for _, fileInfo := range fileDiffs {
-		commitID := fileInfo.File.PatchHeader.SHA
-		author := fmt.Sprintf("%s (%s)", fileInfo.File.PatchHeader.Author.Name, fileInfo.File.PatchHeader.Author.Email)
-		commitInfo := report.CommitInfo{
-			Author: author,
-			Date:   fileInfo.File.PatchHeader.AuthorDate,
-		}
-		infos[commitID] = commitInfo
-	}
-	return infos
-}
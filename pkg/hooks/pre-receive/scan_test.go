@@ -0,0 +1,223 @@
+package pre_receive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/report"
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/Checkmarx/secret-detection/pkg/suppress"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+	"github.com/gitleaks/go-gitdiff/gitdiff"
+	"github.com/stretchr/testify/assert"
+)
+
+const inlineSuppressionPatch = `diff --git a/config.py b/config.py
+index 1111111..2222222 100644
+--- a/config.py
++++ b/config.py
+@@ -1,4 +1,7 @@
+ # cx:ignore-secret
+-OLD_TOKEN = "old"
++TEST_TOKEN = "fixture"
++PROD_TOKEN = "prod"
+ # cx-secret-allow rule=github-pat reason=sample
++GITHUB_TOKEN = "sample"
+ EXTRA = 1
++LAST = "x" # cx:ignore-secret
+`
+
+func parsePatch(t *testing.T, patch string) []*gitdiff.File {
+	diffs, err := gitdiff.Parse(strings.NewReader(patch))
+	assert.NoError(t, err)
+	var files []*gitdiff.File
+	for file := range diffs {
+		files = append(files, file)
+	}
+	return files
+}
+
+func TestExtractChangesInlineSuppression(t *testing.T) {
+	files := parsePatch(t, inlineSuppressionPatch)
+	assert.Len(t, files, 1)
+
+	markers := suppress.Index{}
+	added, removed := extractChanges(files[0].TextFragments, markers.NewTrack("added"), markers.NewTrack("removed"))
+
+	assert.Equal(t, "TEST_TOKEN = \"fixture\"\nPROD_TOKEN = \"prod\"\nGITHUB_TOKEN = \"sample\"\nLAST = \"x\" # cx:ignore-secret\n", added)
+	assert.Equal(t, "OLD_TOKEN = \"old\"\n", removed)
+	// Removed lines are not part of the new file, so the marker above them also covers the first added line.
+	assert.Equal(t, map[int][]suppress.Marker{0: {{}}}, markers["removed"])
+	assert.Equal(t, map[int][]suppress.Marker{0: {{}}, 2: {{Rule: "github-pat", Reason: "sample"}}, 3: {{}}}, markers["added"])
+}
+
+func TestExtractChangesWithoutSuppression(t *testing.T) {
+	files := parsePatch(t, inlineSuppressionPatch)
+
+	var markers suppress.Index
+	added, _ := extractChanges(files[0].TextFragments, markers.NewTrack("added"), markers.NewTrack("removed"))
+	assert.Contains(t, added, "GITHUB_TOKEN")
+	assert.Nil(t, markers)
+}
+
+func TestCommitRanges(t *testing.T) {
+	ranges, err := commitRanges([]string{
+		"1111111 2222222 refs/heads/main",
+		zeroRev + " 3333333 refs/heads/feature",
+		"4444444 " + zeroRev + " refs/heads/old",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []CommitRange{
+		{RefName: "refs/heads/main", LogArgs: []string{"1111111..2222222"}},
+		{RefName: "refs/heads/feature", LogArgs: []string{"--root", "3333333"}},
+	}, ranges)
+
+	_, err = commitRanges([]string{"1111111 2222222"})
+	assert.ErrorContains(t, err, "invalid input line")
+}
+
+func TestProcessCommitMessage(t *testing.T) {
+	header := &gitdiff.PatchHeader{SHA: "abc123", Title: "Fix auth", Body: "new key is ghp_token"}
+	itemsChan := make(chan twoms.ScanItem, 2)
+	index := newDiffIndex()
+
+	assert.NoError(t, processCommitMessage(context.Background(), header, itemsChan, index))
+	assert.NoError(t, processCommitMessage(context.Background(), header, itemsChan, index))
+	close(itemsChan)
+
+	var items []twoms.ScanItem
+	for item := range itemsChan {
+		items = append(items, item)
+	}
+	assert.Len(t, items, 1, "the message is scanned once per commit")
+	assert.Equal(t, "Added:abc123:commit message", items[0].Source)
+	assert.Equal(t, "Fix auth\n\nnew key is ghp_token\n", *items[0].Content)
+
+	// Findings are mapped to the lines of the message.
+	start, end, err := index.lines[items[0].Source].fileLines(2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, start)
+	assert.Equal(t, 3, end)
+}
+
+func TestSortResults(t *testing.T) {
+	first := &secrets.Secret{ID: "id", Source: "Added:aaa:a.go", StartLine: 3}
+	second := &secrets.Secret{ID: "id", Source: "Added:bbb:a.go", StartLine: 1}
+	third := &secrets.Secret{ID: "id", Source: "Added:bbb:a.go", StartLine: 1, StartColumn: 8}
+	rep := &reporting.Report{Results: map[string][]*secrets.Secret{"id": {third, second, first}}}
+
+	sortResults(rep)
+	assert.Equal(t, []*secrets.Secret{first, second, third}, rep.Results["id"])
+}
+
+const acmeKey = "acme_0123456789abcdef0123456789abcdef"
+
+// acmeConfig writes a configuration with a rule that matches acmeKey and returns its path.
+func acmeConfig(t *testing.T) string {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	config := "custom_rules:\n  - id: acme-key\n    regex: 'acme_[a-f0-9]{32}'\n"
+	assert.NoError(t, os.WriteFile(configPath, []byte(config), 0o644))
+	return configPath
+}
+
+// findingRules returns the rules of the findings, which are all in the given source.
+func findingRules(t *testing.T, findings []*secrets.Secret, source string) []string {
+	var rules []string
+	for _, finding := range findings {
+		rules = append(rules, finding.RuleID)
+		assert.Equal(t, source, finding.Source)
+	}
+	return rules
+}
+
+func TestRun(t *testing.T) {
+	git := gitRepo(t, 1)
+	clean := git("rev-parse", "HEAD")
+	assert.NoError(t, os.WriteFile("config.env", []byte("ACME_KEY="+acmeKey+"\n"), 0o644))
+	git("add", ".")
+	git("commit", "-qm", "Add the key")
+	leaked := git("rev-parse", "HEAD")
+
+	configPath := acmeConfig(t)
+
+	var out bytes.Buffer
+	result, err := Run(context.Background(), configPath, strings.NewReader(clean+" "+leaked+" refs/heads/main\n"), &out)
+	assert.NoError(t, err)
+	assert.True(t, result.Blocked())
+	assert.Contains(t, findingRules(t, result.Findings, "Added:"+leaked+":config.env"), "acme-key")
+	assert.Equal(t, out.String(), result.Rendered)
+	assert.Contains(t, string(result.JSON), leaked)
+
+	out.Reset()
+	result, err = Run(context.Background(), configPath, strings.NewReader(zeroRev+" "+clean+" refs/heads/main\n"), &out)
+	assert.NoError(t, err)
+	assert.Equal(t, severity.Pass, result.Decision)
+	assert.Empty(t, result.Findings)
+	assert.Equal(t, "No secrets detected by Cx Secret Scanner", out.String())
+
+	_, err = Run(context.Background(), configPath, strings.NewReader("invalid\n"), nil)
+	assert.ErrorContains(t, err, "invalid input line")
+}
+
+func TestRunCommitMessagesWithoutDiff(t *testing.T) {
+	git := gitRepo(t, 1)
+	git("branch", "-M", "main")
+	clean := git("rev-parse", "HEAD")
+	git("commit", "-q", "--allow-empty", "-m", "Rotate the key", "-m", "ACME_KEY="+acmeKey)
+	empty := git("rev-parse", "HEAD")
+	assert.NoError(t, os.MkdirAll("vendor", 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join("vendor", "lib.txt"), []byte("vendored\n"), 0o644))
+	git("add", ".")
+	git("commit", "-qm", "Vendor the library", "-m", "ACME_KEY="+acmeKey)
+	excluded := git("rev-parse", "HEAD")
+	git("checkout", "-qb", "side", clean)
+	git("commit", "-q", "--allow-empty", "-m", "Side work")
+	git("checkout", "-q", "main")
+	git("merge", "-q", "--no-ff", "side", "-m", "Merge side", "-m", "ACME_KEY="+acmeKey)
+	merge := git("rev-parse", "HEAD")
+
+	for _, concurrency := range []int{1, 4} {
+		t.Run(fmt.Sprintf("%d workers", concurrency), func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			config := fmt.Sprintf("concurrency: %d\nexclude_path:\n  - vendor\ncustom_rules:\n  - id: acme-key\n    regex: 'acme_[a-f0-9]{32}'\n", concurrency)
+			assert.NoError(t, os.WriteFile(configPath, []byte(config), 0o644))
+
+			result, err := Run(context.Background(), configPath, strings.NewReader(clean+" "+merge+" refs/heads/main\n"), nil)
+			assert.NoError(t, err)
+			assert.True(t, result.Blocked())
+			lines := map[string]int{}
+			for _, finding := range result.Findings {
+				lines[finding.Source] = finding.StartLine
+			}
+			assert.Equal(t, map[string]int{
+				"Added:" + empty + ":" + report.CommitMessageFile:    3,
+				"Added:" + excluded + ":" + report.CommitMessageFile: 3,
+				"Added:" + merge + ":" + report.CommitMessageFile:    3,
+			}, lines)
+		})
+	}
+}
+
+func TestUpdateResultsStartAndEndLine(t *testing.T) {
+	index := newDiffIndex()
+	var lines lineMap
+	// The added lines 4, 5 and 7 of the file are separated by a context or removed line.
+	for _, line := range []int{4, 5, 7} {
+		lines.add(line)
+	}
+	index.lines["Added:abc:key.pem"] = lines
+	multiLine := &secrets.Secret{Source: "Added:abc:key.pem", RuleID: "private-key", StartLine: 1, EndLine: 2, Value: "-----BEGIN KEY-----\nMIIE\n-----END KEY-----"}
+	trailingNewline := &secrets.Secret{Source: "Added:abc:key.pem", RuleID: "generic-api-key", StartLine: 1, EndLine: 2, Value: "acme_token"}
+	rep := &reporting.Report{Results: map[string][]*secrets.Secret{"a": {multiLine}, "b": {trailingNewline}}}
+
+	assert.NoError(t, updateResultsStartAndEndLine(rep, index))
+	assert.Equal(t, []int{5, 7}, []int{multiLine.StartLine, multiLine.EndLine}, "the end is mapped like the start")
+	assert.Equal(t, []int{5, 5}, []int{trailingNewline.StartLine, trailingNewline.EndLine}, "the newline ending the match is not a line of the finding")
+}
@@ -0,0 +1,440 @@
+package ignore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Checkmarx/secret-detection/pkg/fingerprint"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+)
+
+// FileName is the name of the ignore file read from the root of the repository.
+const FileName = ".checkmarx_ignore"
+
+// DateLayout is the layout of the date recorded with an entry.
+const DateLayout = "2006-01-02"
+
+// Kind is the type of an ignore entry.
+type Kind string
+
+const (
+	// KindResult ignores a single finding by its result ID.
+	KindResult Kind = "result"
+	// KindRule ignores every finding of a rule, optionally only in the files matching a path glob.
+	KindRule Kind = "rule"
+	// KindFingerprint ignores a secret value wherever it is found, by its fingerprint.Of.
+	KindFingerprint Kind = "fingerprint"
+)
+
+// Entry is a line of the ignore file.
+//
+// Entries are written as "<kind>:<value>" followed by optional key=value fields, for example:
+//
+//	result:6981a34c1d94db7b5465fbc8b8f4fb97c2c97426 reason="test fixture" author=dev@example.com date=2025-01-31
+//	rule:generic-api-key path="docs/**" reason="sample configuration"
+//	fingerprint:hmac-sha256:5e1f09c2a77b3d84:0c1a4d3b6f1e2a7c9b8d5e4f3a2b1c0d
+//
+// A line holding only a result ID, as written by earlier versions, is a result entry.
+type Entry struct {
+	Kind  Kind
+	Value string
+	// Path restricts a rule entry to the files matching the glob.
+	Path   string
+	Reason string
+	Author string
+	Date   string
+
+	// pathGlob is Path compiled by ParseEntry, so that matching the entry against every finding does
+	// not compile it again.
+	pathGlob *regexp.Regexp
+}
+
+// Key identifies the findings ignored by the entry; entries with the same key are duplicates.
+func (e Entry) Key() string {
+	return string(e.Kind) + ":" + e.Value + ":" + e.Path
+}
+
+// String returns the line of the entry in the ignore file. Result entries without any field keep
+// the legacy format.
+func (e Entry) String() string {
+	if e.Kind == KindResult && e.Path == "" && e.Reason == "" && e.Author == "" && e.Date == "" {
+		return e.Value
+	}
+	var builder strings.Builder
+	builder.WriteString(string(e.Kind) + ":" + e.Value)
+	for _, field := range []struct{ key, value string }{
+		{"path", e.Path},
+		{"reason", e.Reason},
+		{"author", e.Author},
+		{"date", e.Date},
+	} {
+		if field.value != "" {
+			builder.WriteString(" " + field.key + "=" + quote(field.value))
+		}
+	}
+	return builder.String()
+}
+
+// Validate checks that the entry is well-formed.
+func (e Entry) Validate() error {
+	if e.Value == "" {
+		return fmt.Errorf("%s entry has no value", e.Kind)
+	}
+	switch e.Kind {
+	case KindResult:
+	case KindRule:
+		if e.Path != "" {
+			if _, err := globRegex(e.Path); err != nil {
+				return fmt.Errorf("invalid path glob %q: %w", e.Path, err)
+			}
+		}
+	case KindFingerprint:
+		if err := fingerprint.Validate(e.Value); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown entry type %q", e.Kind)
+	}
+	if e.Path != "" && e.Kind != KindRule {
+		return fmt.Errorf("path is only supported on rule entries")
+	}
+	if e.Date != "" {
+		if _, err := time.Parse(DateLayout, e.Date); err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", e.Date)
+		}
+	}
+	return nil
+}
+
+// List is the content of an ignore file.
+type List []Entry
+
+// Load reads the ignore file at filePath. A missing file results in an empty list.
+func Load(filePath string) (List, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close() // nolint:errcheck
+
+	list, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+	return list, nil
+}
+
+// Parse reads the entries of an ignore file. Empty lines and lines starting with "#" are skipped.
+func Parse(r io.Reader) (List, error) {
+	var list List
+	scanner := bufio.NewScanner(r)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entry, err := ParseEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		list = append(list, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// ParseEntry parses a single line of the ignore file.
+func ParseEntry(line string) (Entry, error) {
+	tokens, err := tokenize(line)
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(tokens) == 0 {
+		return Entry{}, fmt.Errorf("empty entry")
+	}
+
+	var entry Entry
+	kind, value, found := strings.Cut(tokens[0], ":")
+	switch Kind(kind) {
+	case KindResult, KindRule, KindFingerprint:
+		entry.Kind, entry.Value = Kind(kind), value
+	default:
+		if found || len(tokens) > 1 {
+			return Entry{}, fmt.Errorf("unknown entry %q, expected result:, rule: or fingerprint:", tokens[0])
+		}
+		// A bare result ID, as written by earlier versions.
+		entry.Kind, entry.Value = KindResult, tokens[0]
+	}
+
+	for _, token := range tokens[1:] {
+		key, value, ok := strings.Cut(token, "=")
+		if !ok {
+			return Entry{}, fmt.Errorf("invalid field %q, expected key=value", token)
+		}
+		switch key {
+		case "path":
+			entry.Path = value
+		case "reason":
+			entry.Reason = value
+		case "author":
+			entry.Author = value
+		case "date":
+			entry.Date = value
+		default:
+			return Entry{}, fmt.Errorf("unknown field %q", key)
+		}
+	}
+	if err = entry.Validate(); err != nil {
+		return entry, err
+	}
+	if entry.Path != "" {
+		entry.pathGlob, err = globRegex(entry.Path)
+	}
+	return entry, err
+}
+
+// ResultIDs returns the IDs of the result entries.
+func (l List) ResultIDs() []string {
+	var ids []string
+	for _, entry := range l {
+		if entry.Kind == KindResult {
+			ids = append(ids, entry.Value)
+		}
+	}
+	return ids
+}
+
+// Contains reports whether the list has an entry with the same key.
+func (l List) Contains(entry Entry) bool {
+	for _, existing := range l {
+		if existing.Key() == entry.Key() {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether the entry ignores the finding. Rule paths are matched against the file of
+// the finding, the source of pre-commit findings and the last part of "<change>:<commit>:<file>"
+// commit findings.
+func (e Entry) Matches(secret *secrets.Secret) bool {
+	switch e.Kind {
+	case KindResult:
+		return secret.ID == e.Value
+	case KindRule:
+		if !strings.EqualFold(secret.RuleID, e.Value) {
+			return false
+		}
+		if e.Path == "" {
+			return true
+		}
+		glob := e.pathGlob
+		if glob == nil {
+			// An entry built without ParseEntry.
+			var err error
+			if glob, err = globRegex(e.Path); err != nil {
+				return false
+			}
+		}
+		return matchPath(glob, e.Path, SourceFile(secret.Source))
+	case KindFingerprint:
+		return fingerprint.Matches(e.Value, secret.Value)
+	default:
+		return false
+	}
+}
+
+// Prunable reports whether the entry ignores specific findings, and is obsolete once they are gone.
+// Rule entries are policies that apply to future findings as well.
+func (e Entry) Prunable() bool {
+	return e.Kind == KindResult || e.Kind == KindFingerprint
+}
+
+// Apply removes the findings matched by a rule or fingerprint entry from the report and returns how
+// many were removed. Result entries are applied by the scanner.
+func (l List) Apply(report *reporting.Report) int {
+	var entries List
+	for _, entry := range l {
+		if entry.Kind != KindResult {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return 0
+	}
+	removed := 0
+	for id, list := range report.Results {
+		kept := list[:0]
+		for _, secret := range list {
+			if entries.matchesAny(secret) {
+				removed++
+				continue
+			}
+			kept = append(kept, secret)
+		}
+		if len(kept) == 0 {
+			delete(report.Results, id)
+		} else {
+			report.Results[id] = kept
+		}
+	}
+	report.TotalSecretsFound -= removed
+	return removed
+}
+
+func (l List) matchesAny(secret *secrets.Secret) bool {
+	for _, entry := range l {
+		if entry.Matches(secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove rewrites the ignore file at filePath without the entries for which remove returns true,
+// keeping comments and the formatting of the other lines. It returns the removed entries.
+func Remove(filePath string, remove func(Entry) bool) (List, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var removed List
+	var kept []string
+	lines := strings.SplitAfter(string(data), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			entry, err := ParseEntry(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("%s: line %d: %w", filePath, i+1, err)
+			}
+			if remove(entry) {
+				removed = append(removed, entry)
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return removed, os.WriteFile(filePath, []byte(strings.Join(kept, "")), info.Mode().Perm())
+}
+
+// SourceFile returns the file of a finding source, stripping the "<change>:<commit>:" prefix of commit findings.
+func SourceFile(source string) string {
+	if parts := strings.SplitN(source, ":", 3); len(parts) == 3 && (parts[0] == "Added" || parts[0] == "Deleted") {
+		return parts[2]
+	}
+	return source
+}
+
+// matchPath matches the file against the glob. Like in .gitignore, a glob without a slash matches
+// the file name in any directory.
+func matchPath(glob *regexp.Regexp, pattern, file string) bool {
+	file = strings.TrimPrefix(strings.ReplaceAll(file, `\`, "/"), "./")
+	if !strings.Contains(pattern, "/") {
+		file = path.Base(file)
+	}
+	return glob.MatchString(file)
+}
+
+// globRegex converts a path glob to a regular expression. "**" matches any number of directories,
+// "*" and "?" do not match a slash.
+func globRegex(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimPrefix(strings.ReplaceAll(pattern, `\`, "/"), "/")
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if strings.HasPrefix(pattern[i:], "**/") {
+				builder.WriteString("(.*/)?")
+				i += 2
+			} else if strings.HasPrefix(pattern[i:], "**") {
+				builder.WriteString(".*")
+				i++
+			} else {
+				builder.WriteString("[^/]*")
+			}
+		case '?':
+			builder.WriteString("[^/]")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	builder.WriteString("$")
+	return regexp.Compile(builder.String())
+}
+
+// tokenize splits a line on whitespace, keeping double-quoted values together.
+func tokenize(line string) ([]string, error) {
+	var tokens []string
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		end := 0
+		var builder strings.Builder
+		for end < len(line) && line[end] != ' ' && line[end] != '\t' {
+			if line[end] != '"' {
+				builder.WriteByte(line[end])
+				end++
+				continue
+			}
+			unquoted, rest, err := unquotePrefix(line[end:])
+			if err != nil {
+				return nil, err
+			}
+			builder.WriteString(unquoted)
+			end = len(line) - len(rest)
+		}
+		tokens = append(tokens, builder.String())
+		line = line[end:]
+	}
+	return tokens, nil
+}
+
+// unquotePrefix unquotes the double-quoted string at the start of s and returns the remaining text.
+func unquotePrefix(s string) (string, string, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			unquoted, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", "", fmt.Errorf("invalid quoted value %s", s[:i+1])
+			}
+			return unquoted, s[i+1:], nil
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted value %s", s)
+}
+
+func quote(value string) string {
+	if strings.ContainsAny(value, " \t\"\\#") {
+		return strconv.Quote(value)
+	}
+	return value
+}
@@ -0,0 +1,200 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/fingerprint"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEntry(t *testing.T) {
+	valueFingerprint := fingerprint.Of("0123abcd", "secret")
+	tests := []struct {
+		name     string
+		line     string
+		expected Entry
+		err      string
+	}{
+		{"legacy result ID", "6981a34c1d94db7b5465fbc8b8f4fb97c2c97426", Entry{Kind: KindResult, Value: "6981a34c1d94db7b5465fbc8b8f4fb97c2c97426"}, ""},
+		{"result with fields", `result:abc reason="test fixture" author=dev@example.com date=2025-01-31`, Entry{Kind: KindResult, Value: "abc", Reason: "test fixture", Author: "dev@example.com", Date: "2025-01-31"}, ""},
+		{"rule", "rule:jwt", Entry{Kind: KindRule, Value: "jwt"}, ""},
+		{"rule with path", `rule:generic-api-key path="docs/**" reason="sample \"config\""`, Entry{Kind: KindRule, Value: "generic-api-key", Path: "docs/**", Reason: `sample "config"`}, ""},
+		{"fingerprint", "fingerprint:" + valueFingerprint, Entry{Kind: KindFingerprint, Value: valueFingerprint}, ""},
+		{"unknown kind", "secret:abc", Entry{}, "unknown entry"},
+		{"unknown field", "rule:jwt owner=me", Entry{}, "unknown field"},
+		{"field without value", "rule:jwt reason", Entry{}, "expected key=value"},
+		{"unterminated quote", `rule:jwt reason="oops`, Entry{}, "unterminated"},
+		{"path on result", "result:abc path=docs/*", Entry{}, "only supported on rule entries"},
+		{"fingerprint without prefix", "fingerprint:abcd", Entry{}, "must be hmac-sha256:"},
+		{"unsalted fingerprint", "fingerprint:sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", Entry{}, "must be hmac-sha256:"},
+		{"invalid date", "rule:jwt date=yesterday", Entry{}, "invalid date"},
+		{"empty value", "rule: reason=x", Entry{}, "has no value"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			entry, err := ParseEntry(tc.line)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected.Path != "", entry.pathGlob != nil, "the path glob is compiled once")
+			assert.Equal(t, tc.expected, withoutGlob(entry))
+		})
+	}
+}
+
+// withoutGlob drops the compiled path glob of a parsed entry, to compare it with a literal one.
+func withoutGlob(entry Entry) Entry {
+	entry.pathGlob = nil
+	return entry
+}
+
+func TestEntryStringRoundTrip(t *testing.T) {
+	entries := []Entry{
+		{Kind: KindResult, Value: "abc"},
+		{Kind: KindResult, Value: "abc", Reason: "false positive", Author: "dev@example.com", Date: "2025-01-31"},
+		{Kind: KindRule, Value: "jwt", Path: "test/**/*.json", Reason: `a "quoted" # reason`},
+		{Kind: KindFingerprint, Value: fingerprint.Of("0123abcd", "secret")},
+	}
+	for _, entry := range entries {
+		parsed, err := ParseEntry(entry.String())
+		assert.NoError(t, err)
+		assert.Equal(t, entry, withoutGlob(parsed))
+	}
+	assert.Equal(t, "abc", entries[0].String())
+}
+
+func TestParse(t *testing.T) {
+	content := strings.Join([]string{
+		"# Findings reviewed by the security team",
+		"",
+		"6981a34c1d94db7b5465fbc8b8f4fb97c2c97426",
+		"  rule:jwt path=test/** reason=fixtures",
+		"result:abc",
+	}, "\n")
+	list, err := Parse(strings.NewReader(content))
+	assert.NoError(t, err)
+	assert.Len(t, list, 3)
+	assert.Equal(t, []string{"6981a34c1d94db7b5465fbc8b8f4fb97c2c97426", "abc"}, list.ResultIDs())
+	assert.True(t, list.Contains(Entry{Kind: KindRule, Value: "jwt", Path: "test/**"}))
+	assert.False(t, list.Contains(Entry{Kind: KindRule, Value: "jwt"}))
+	assert.True(t, list[1].Matches(&secrets.Secret{RuleID: "jwt", Source: "test/fixtures/token.json"}))
+
+	_, err = Parse(strings.NewReader("abc\nrule:jwt bogus\n"))
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		matches bool
+	}{
+		{"docs/**", "docs/a/b.md", true},
+		{"docs/**", "src/docs/b.md", false},
+		{"**/fixtures/*.json", "fixtures/a.json", true},
+		{"**/fixtures/*.json", "test/fixtures/a.json", true},
+		{"**/fixtures/*.json", "test/fixtures/sub/a.json", false},
+		{"*.md", "docs/readme.md", true},
+		{"config.?ml", "config.yml", true},
+		{"/config.yml", "config.yml", true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.pattern+" "+tc.file, func(t *testing.T) {
+			glob, err := globRegex(tc.pattern)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.matches, matchPath(glob, tc.pattern, tc.file))
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	newReport := func() *reporting.Report {
+		return &reporting.Report{
+			TotalSecretsFound: 4,
+			Results: map[string][]*secrets.Secret{
+				"1": {{ID: "1", RuleID: "jwt", Source: "test/fixtures/token.json", Value: "a"}},
+				"2": {{ID: "2", RuleID: "jwt", Source: "src/main.go", Value: "b"}},
+				"3": {{ID: "3", RuleID: "github-pat", Source: "src/main.go", Value: "c"}},
+				"4": {{ID: "4", RuleID: "github-pat", Source: "src/other.go", Value: "d"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		list      List
+		remaining []string
+	}{
+		{"result entries are left to the scanner", List{{Kind: KindResult, Value: "1"}}, []string{"1", "2", "3", "4"}},
+		{"rule", List{{Kind: KindRule, Value: "GitHub-PAT"}}, []string{"1", "2"}},
+		{"rule in path", List{{Kind: KindRule, Value: "jwt", Path: "test/**"}}, []string{"2", "3", "4"}},
+		{"fingerprint", List{{Kind: KindFingerprint, Value: fingerprint.Of("0123abcd", "d")}}, []string{"1", "2", "3"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			report := newReport()
+			removed := tc.list.Apply(report)
+			var remaining []string
+			for id := range report.Results {
+				remaining = append(remaining, id)
+			}
+			assert.ElementsMatch(t, tc.remaining, remaining)
+			assert.Equal(t, 4-len(tc.remaining), removed)
+			assert.Equal(t, len(tc.remaining), report.TotalSecretsFound)
+		})
+	}
+}
+
+func TestEntryMatches(t *testing.T) {
+	secret := &secrets.Secret{ID: "abc", RuleID: "jwt", Source: "test/token.json", Value: "eyJtoken"}
+	tests := []struct {
+		entry   Entry
+		matches bool
+	}{
+		{Entry{Kind: KindResult, Value: "abc"}, true},
+		{Entry{Kind: KindResult, Value: "def"}, false},
+		{Entry{Kind: KindRule, Value: "JWT"}, true},
+		{Entry{Kind: KindRule, Value: "jwt", Path: "test/*"}, true},
+		{Entry{Kind: KindRule, Value: "jwt", Path: "src/**"}, false},
+		{Entry{Kind: KindRule, Value: "jwt", Path: "test/token.json"}, true},
+		{Entry{Kind: KindFingerprint, Value: fingerprint.Of("0123abcd", "eyJtoken")}, true},
+		{Entry{Kind: KindFingerprint, Value: fingerprint.Of("0123abcd", "other")}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.entry.String(), func(t *testing.T) {
+			assert.Equal(t, tc.matches, tc.entry.Matches(secret))
+			commitSecret := *secret
+			commitSecret.Source = "Added:0123abc:" + secret.Source
+			assert.Equal(t, tc.matches, tc.entry.Matches(&commitSecret))
+		})
+	}
+}
+
+func TestRemove(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), FileName)
+	content := "# reviewed findings\nabc\n\nrule:jwt reason=fixtures\nresult:def reason=\"false positive\"\n"
+	assert.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+
+	removed, err := Remove(filePath, func(entry Entry) bool { return entry.Prunable() && entry.Value != "abc" })
+	assert.NoError(t, err)
+	assert.Equal(t, List{{Kind: KindResult, Value: "def", Reason: "false positive"}}, removed)
+
+	data, err := os.ReadFile(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "# reviewed findings\nabc\n\nrule:jwt reason=fixtures\n", string(data))
+
+	removed, err = Remove(filePath, func(Entry) bool { return false })
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+
+	removed, err = Remove(filepath.Join(t.TempDir(), FileName), func(Entry) bool { return true })
+	assert.NoError(t, err)
+	assert.Empty(t, removed)
+}
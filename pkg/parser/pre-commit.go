@@ -9,10 +9,16 @@ import (
 	"strings"
 )
 
-var (
-	hunkRegex       = regexp.MustCompile(`@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
-	diffHeaderRegex = regexp.MustCompile(`^diff --git\s+"?a/(.+?)"?\s+"?b/(.+?)"?$`)
-	maxFileDiffSize = 10 * 1024 * 1024 // 10 MB per file
+// DefaultMaxFileDiffSize is the default limit of added bytes per file, above which a file is skipped.
+const DefaultMaxFileDiffSize = 10 * 1024 * 1024 // 10 MB per file
+
+var hunkRegex = regexp.MustCompile(`@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+const (
+	diffHeaderPrefix = "diff --git "
+	devNull          = "/dev/null"
+	// submoduleMode is the mode of the gitlinks that record the commit of a submodule.
+	submoduleMode = "160000"
 )
 
 // Hunk represents a diff hunk for secret scanning.
@@ -22,52 +28,101 @@ type Hunk struct {
 	Size      int
 }
 
+// FileDiff is the parsed diff of a file, as sent to DiffParser.Output.
+type FileDiff struct {
+	Path  string
+	Hunks []Hunk
+	// Content is the added content of the file, which the content of each hunk is a part of.
+	Content string
+}
+
 // DiffParser encapsulates state for parsing a git diff stream.
 type DiffParser struct {
 	currentFile      string
 	currentHunks     []Hunk
-	hunkContent      strings.Builder
+	fileContent      strings.Builder
 	hunkStart        int
+	hunkEnds         []int
 	hunkSize         int
 	inHunk           bool
 	currentFileBytes int
 	skipFile         bool
+	deletedFile      bool
+	binaryFile       bool
+	submoduleFile    bool
 	FileDiffs        map[string][]Hunk
+	// Output receives each file as soon as its diff is parsed, instead of FileDiffs, so that it can be
+	// scanned while the rest of the diff is parsed.
+	Output chan<- FileDiff
+	// Binary lists the changed binary files, which have no content to scan.
+	Binary []string
+	// Submodules lists the changed submodules, whose commits are recorded instead of content.
+	Submodules []string
+	// MaxFileDiffSize is the limit of added bytes per file, above which the file is skipped.
+	MaxFileDiffSize int
 }
 
 // NewDiffParser creates a new DiffParser.
 func NewDiffParser() *DiffParser {
 	return &DiffParser{
-		FileDiffs: make(map[string][]Hunk),
+		FileDiffs:       make(map[string][]Hunk),
+		MaxFileDiffSize: DefaultMaxFileDiffSize,
 	}
 }
 
-// flushHunk flushes the current hunk if one is in progress.
+// flushHunk flushes the current hunk if one is in progress. Its content is set by fileHunks, once
+// the content of the file is complete.
 func (dp *DiffParser) flushHunk() {
 	if dp.inHunk {
 		dp.currentHunks = append(dp.currentHunks, Hunk{
 			StartLine: dp.hunkStart,
-			Content:   dp.hunkContent.String(),
 			Size:      dp.hunkSize,
 		})
-		dp.hunkContent.Reset()
+		dp.hunkEnds = append(dp.hunkEnds, dp.fileContent.Len())
 		dp.inHunk = false
 	}
 }
 
+// fileHunks returns the hunks of the current file and its added content. The content of each hunk is
+// a part of the file content rather than a copy.
+func (dp *DiffParser) fileHunks() ([]Hunk, string) {
+	content := dp.fileContent.String()
+	start := 0
+	for i, end := range dp.hunkEnds {
+		dp.currentHunks[i].Content = content[start:end]
+		start = end
+	}
+	return dp.currentHunks, content
+}
+
 // flushFile flushes the current file and resets file-specific state.
 func (dp *DiffParser) flushFile() {
 	dp.flushHunk()
-	if dp.currentFile != "" && !dp.skipFile {
-		dp.FileDiffs[dp.currentFile] = dp.currentHunks
+	switch {
+	case dp.currentFile == "" || dp.skipFile:
+	case dp.submoduleFile:
+		dp.Submodules = append(dp.Submodules, dp.currentFile)
+	case dp.binaryFile:
+		dp.Binary = append(dp.Binary, dp.currentFile)
+	case dp.Output != nil:
+		hunks, content := dp.fileHunks()
+		dp.Output <- FileDiff{Path: dp.currentFile, Hunks: hunks, Content: content}
+	default:
+		dp.FileDiffs[dp.currentFile], _ = dp.fileHunks()
 	}
 	dp.currentFile = ""
 	dp.currentHunks = nil
+	dp.hunkEnds = nil
+	dp.fileContent.Reset()
 	dp.currentFileBytes = 0
 	dp.skipFile = false
+	dp.deletedFile = false
+	dp.binaryFile = false
+	dp.submoduleFile = false
 }
 
-// ParseDiffStream processes the git diff stream and builds the file-to-hunks mapping.
+// ParseDiffStream processes the git diff stream and builds the file-to-hunks mapping, or sends each
+// file to Output when it is set.
 func (dp *DiffParser) ParseDiffStream(r io.Reader) error {
 	reader := bufio.NewReader(r)
 	for {
@@ -86,10 +141,9 @@ func (dp *DiffParser) ParseDiffStream(r io.Reader) error {
 		}
 
 		// Check for a new file header.
-		if matches := diffHeaderRegex.FindStringSubmatch(line); matches != nil {
+		if strings.HasPrefix(line, diffHeaderPrefix) {
 			dp.flushFile()
-			// Use second capture group as file name.
-			dp.currentFile = matches[2]
+			dp.currentFile = headerPath(line[len(diffHeaderPrefix):])
 			continue
 		}
 
@@ -106,22 +160,23 @@ func (dp *DiffParser) ParseDiffStream(r io.Reader) error {
 			continue
 		}
 
-		// Process addition lines (only if inside a hunk).
-		if dp.inHunk && strings.HasPrefix(line, "+") {
-			if dp.skipFile {
-				continue
-			}
+		if !dp.inHunk {
+			// The extended header lines between the "diff --git" line and the first hunk.
+			dp.parseExtendedHeader(line)
+		} else if strings.HasPrefix(line, "+") && !dp.skipFile && !dp.submoduleFile {
+			// Process addition lines. Other lines, including "\ No newline at end of file", are ignored.
 			addLine := line[1:]
 			dp.currentFileBytes += len(addLine)
-			if dp.currentFileBytes > maxFileDiffSize {
+			if dp.currentFileBytes > dp.MaxFileDiffSize {
 				dp.skipFile = true
 				dp.currentHunks = nil
-				dp.hunkContent.Reset()
+				dp.hunkEnds = nil
+				dp.fileContent.Reset()
 				dp.inHunk = false
-				continue
+			} else {
+				dp.fileContent.WriteString(addLine)
+				dp.fileContent.WriteByte('\n')
 			}
-			dp.hunkContent.WriteString(addLine)
-			dp.hunkContent.WriteByte('\n')
 		}
 
 		if err == io.EOF {
@@ -132,6 +187,106 @@ func (dp *DiffParser) ParseDiffStream(r io.Reader) error {
 	return nil
 }
 
+// parseExtendedHeader reads a header line of the current file. The paths of the "rename to",
+// "copy to" and "+++" lines are exact, unlike those of the "diff --git" line, which are ambiguous
+// when they contain " b/".
+func (dp *DiffParser) parseExtendedHeader(line string) {
+	switch {
+	case strings.HasPrefix(line, "rename to "):
+		dp.currentFile = unquotePath(strings.TrimPrefix(line, "rename to "))
+	case strings.HasPrefix(line, "copy to "):
+		dp.currentFile = unquotePath(strings.TrimPrefix(line, "copy to "))
+	case strings.HasPrefix(line, "deleted file mode "):
+		dp.deletedFile = true
+		dp.submoduleFile = dp.submoduleFile || strings.HasSuffix(line, submoduleMode)
+	case strings.HasPrefix(line, "new file mode "), strings.HasPrefix(line, "new mode "), strings.HasPrefix(line, "index "):
+		dp.submoduleFile = dp.submoduleFile || strings.HasSuffix(line, " "+submoduleMode)
+	case strings.HasPrefix(line, "--- "):
+		// The old path names a deleted file, whose new path is /dev/null.
+		if path := markerPath(line); path != devNull && dp.currentFile == "" {
+			dp.currentFile = strings.TrimPrefix(unquotePath(path), "a/")
+		}
+	case strings.HasPrefix(line, "+++ "):
+		if path := markerPath(line); path != devNull && !dp.deletedFile {
+			dp.currentFile = strings.TrimPrefix(unquotePath(path), "b/")
+		}
+	case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+		dp.binaryFile = true
+	}
+}
+
+// markerPath returns the path of a "---" or "+++" line. git ends the line with a tab when the path
+// has a space, for the patch tool.
+func markerPath(line string) string {
+	return strings.TrimSuffix(line[len("+++ "):], "\t")
+}
+
+// headerPath returns the new path of a "diff --git a/<old> b/<new>" line. Quoted paths are exact.
+// Unquoted paths are split in the middle when both are the same, as they are unless the file was
+// renamed or copied, and at the last " b/" otherwise; the "rename to" and "copy to" lines that
+// follow then give the exact path.
+func headerPath(paths string) string {
+	if strings.HasSuffix(paths, `"`) {
+		if start := quotedStart(paths); start >= 0 {
+			return strings.TrimPrefix(unquotePath(paths[start:]), "b/")
+		}
+	}
+	if strings.HasPrefix(paths, `"`) {
+		if end := quotedEnd(paths); end > 0 && end < len(paths) {
+			return strings.TrimPrefix(strings.TrimSpace(paths[end:]), "b/")
+		}
+	}
+	if half := len(paths) / 2; len(paths)%2 == 1 && paths[half] == ' ' &&
+		strings.HasPrefix(paths, "a/") && strings.HasPrefix(paths[half+1:], "b/") && paths[2:half] == paths[half+3:] {
+		return paths[half+3:]
+	}
+	if i := strings.LastIndex(paths, " b/"); i >= 0 {
+		return paths[i+3:]
+	}
+	return ""
+}
+
+// quotedEnd returns the index following the closing quote of the quoted path at the start of s.
+func quotedEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// quotedStart returns the index of the opening quote of the quoted path at the end of s.
+func quotedStart(s string) int {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '"' {
+			continue
+		}
+		if end := quotedEnd(s[i:]); i+end == len(s) {
+			return i
+		} else if end > 0 {
+			i += end - 1
+		}
+	}
+	return -1
+}
+
+// unquotePath decodes a path that git quoted because it has special or non-ASCII characters, which
+// are C-style escapes, octal for the bytes of UTF-8 sequences. Other paths are returned as they are.
+func unquotePath(path string) string {
+	if len(path) < 2 || path[0] != '"' || path[len(path)-1] != '"' {
+		return path
+	}
+	unquoted, err := strconv.Unquote(path)
+	if err != nil {
+		return path
+	}
+	return unquoted
+}
+
 // parseHunkHeader extracts the start line and size from a hunk header.
 func parseHunkHeader(header string) (int, int, error) {
 	matches := hunkRegex.FindStringSubmatch(header)
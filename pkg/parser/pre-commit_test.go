@@ -225,3 +225,232 @@ index abc1234..0000000
 		})
 	}
 }
+
+func TestParseDiffStreamMaxFileDiffSize(t *testing.T) {
+	diff := `diff --git a/big.txt b/big.txt
+--- a/big.txt
++++ b/big.txt
+@@ -0,0 +1,2 @@
++0123456789
++0123456789
+diff --git a/small.txt b/small.txt
+--- a/small.txt
++++ b/small.txt
+@@ -0,0 +1 @@
++0123
+`
+	parser := NewDiffParser()
+	assert.Equal(t, DefaultMaxFileDiffSize, parser.MaxFileDiffSize)
+	parser.MaxFileDiffSize = 15
+	assert.NoError(t, parser.ParseDiffStream(strings.NewReader(diff)))
+	assert.NotContains(t, parser.FileDiffs, "big.txt")
+	assert.Equal(t, []Hunk{{StartLine: 1, Content: "0123\n", Size: 1}}, parser.FileDiffs["small.txt"])
+}
+
+func TestParseDiffStreamOutput(t *testing.T) {
+	diff := `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-old
++new
+@@ -10,0 +11,2 @@
++first
++second
+diff --git a/image.png b/image.png
+Binary files a/image.png and b/image.png differ
+diff --git a/b.txt b/b.txt
+--- a/b.txt
++++ b/b.txt
+@@ -0,0 +1 @@
++other
+`
+	output := make(chan FileDiff)
+	parser := NewDiffParser()
+	parser.Output = output
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- parser.ParseDiffStream(strings.NewReader(diff))
+		close(output)
+	}()
+
+	var files []FileDiff
+	for file := range output {
+		files = append(files, file)
+	}
+	assert.NoError(t, <-errCh)
+	assert.Equal(t, []FileDiff{
+		{
+			Path: "a.txt",
+			Hunks: []Hunk{
+				{StartLine: 1, Content: "new\n", Size: 1},
+				{StartLine: 11, Content: "first\nsecond\n", Size: 2},
+			},
+			Content: "new\nfirst\nsecond\n",
+		},
+		{Path: "b.txt", Hunks: []Hunk{{StartLine: 1, Content: "other\n", Size: 1}}, Content: "other\n"},
+	}, files)
+	assert.Empty(t, parser.FileDiffs, "files are sent to the output instead")
+	assert.Equal(t, []string{"image.png"}, parser.Binary)
+}
+
+func TestParseDiffStreamHeaders(t *testing.T) {
+	tests := []struct {
+		name       string
+		diff       string
+		fileDiffs  map[string][]Hunk
+		binary     []string
+		submodules []string
+	}{
+		{
+			name: "path containing b/",
+			diff: `diff --git a/dir b/file.txt b/dir b/file.txt
+index abc1234..def5678 100644
+--- a/dir b/file.txt	
++++ b/dir b/file.txt	
+@@ -0,0 +1 @@
++token`,
+			fileDiffs: map[string][]Hunk{"dir b/file.txt": {{StartLine: 1, Content: "token\n", Size: 1}}},
+		},
+		{
+			name: "rename of a path containing b/",
+			diff: `diff --git a/x b/old.txt b/y b/new.txt
+similarity index 100%
+rename from x b/old.txt
+rename to y b/new.txt`,
+			fileDiffs: map[string][]Hunk{"y b/new.txt": nil},
+		},
+		{
+			name: "copy",
+			diff: `diff --git a/template.txt b/copy.txt
+similarity index 90%
+copy from template.txt
+copy to copy.txt
+index abc1234..def5678 100644
+--- a/template.txt
++++ b/copy.txt
+@@ -2,0 +3 @@
++token`,
+			fileDiffs: map[string][]Hunk{"copy.txt": {{StartLine: 3, Content: "token\n", Size: 1}}},
+		},
+		{
+			name: "quoted non-ASCII path",
+			diff: `diff --git "a/\303\244 \"q\".txt" "b/\303\244 \"q\".txt"
+new file mode 100644
+index 0000000..def5678
+--- /dev/null
++++ "b/\303\244 \"q\".txt"
+@@ -0,0 +1 @@
++token`,
+			fileDiffs: map[string][]Hunk{`ä "q".txt`: {{StartLine: 1, Content: "token\n", Size: 1}}},
+		},
+		{
+			name: "rename to a quoted path",
+			diff: `diff --git a/plain.txt "b/t\303\251st.txt"
+similarity index 100%
+rename from plain.txt
+rename to "t\303\251st.txt"`,
+			fileDiffs: map[string][]Hunk{"tést.txt": nil},
+		},
+		{
+			name: "no newline at end of file",
+			diff: `diff --git a/file.txt b/file.txt
+index abc1234..def5678 100644
+--- a/file.txt
++++ b/file.txt
+@@ -1 +1 @@
+-old
+\ No newline at end of file
++new
+\ No newline at end of file`,
+			fileDiffs: map[string][]Hunk{"file.txt": {{StartLine: 1, Content: "new\n", Size: 1}}},
+		},
+		{
+			name: "deleted file",
+			diff: `diff --git a/file.txt b/file.txt
+deleted file mode 100644
+index abc1234..0000000
+--- a/file.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-token`,
+			fileDiffs: map[string][]Hunk{"file.txt": {{StartLine: 0, Content: "", Size: 0}}},
+		},
+		{
+			name: "binary files",
+			diff: `diff --git "a/\303\244.png" "b/\303\244.png"
+new file mode 100644
+index 0000000..def5678
+Binary files /dev/null and "b/\303\244.png" differ
+diff --git a/logo.png b/logo.png
+index abc1234..def5678 100644
+GIT binary patch
+literal 4
+LcmZQzWMT#Y01f~L`,
+			fileDiffs: map[string][]Hunk{},
+			binary:    []string{"ä.png", "logo.png"},
+		},
+		{
+			name: "submodules",
+			diff: `diff --git a/lib/dep b/lib/dep
+new file mode 160000
+index 0000000..abc1234
+--- /dev/null
++++ b/lib/dep
+@@ -0,0 +1 @@
++Subproject commit abc1234abc1234abc1234abc1234abc1234abcd
+diff --git a/vendor/tool b/vendor/tool
+index abc1234..def5678 160000
+--- a/vendor/tool
++++ b/vendor/tool
+@@ -1 +1 @@
+-Subproject commit abc1234abc1234abc1234abc1234abc1234abcd
++Subproject commit def5678def5678def5678def5678def5678def5
+diff --git a/file.txt b/file.txt
+index abc1234..def5678 100644
+--- a/file.txt
++++ b/file.txt
+@@ -0,0 +1 @@
++token`,
+			fileDiffs:  map[string][]Hunk{"file.txt": {{StartLine: 1, Content: "token\n", Size: 1}}},
+			submodules: []string{"lib/dep", "vendor/tool"},
+		},
+		{
+			name: "mode change",
+			diff: `diff --git a/run.sh b/run.sh
+old mode 100644
+new mode 100755`,
+			fileDiffs: map[string][]Hunk{"run.sh": nil},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := NewDiffParser()
+			assert.NoError(t, parser.ParseDiffStream(strings.NewReader(tc.diff)))
+			assert.Equal(t, tc.fileDiffs, parser.FileDiffs)
+			assert.Equal(t, tc.binary, parser.Binary)
+			assert.Equal(t, tc.submodules, parser.Submodules)
+		})
+	}
+}
+
+func TestHeaderPath(t *testing.T) {
+	tests := []struct {
+		paths    string
+		expected string
+	}{
+		{"a/file.txt b/file.txt", "file.txt"},
+		{"a/dir b/file.txt b/dir b/file.txt", "dir b/file.txt"},
+		{"a/old.txt b/new.txt", "new.txt"},
+		{`"a/\303\244.txt" "b/\303\244.txt"`, "ä.txt"},
+		{`a/plain.txt "b/t\303\251st.txt"`, "tést.txt"},
+		{`"a/t\303\251st.txt" b/plain.txt`, "plain.txt"},
+		{`"a/x \"y\" b/z" "b/x \"y\" b/z"`, `x "y" b/z`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.paths, func(t *testing.T) {
+			assert.Equal(t, tc.expected, headerPath(tc.paths))
+		})
+	}
+}
@@ -1,5 +1,8 @@
 package report
 
+// CommitMessageFile is the pseudo-file name under which the findings in a commit message are reported.
+const CommitMessageFile = "commit message"
+
 func pluralize(count int, singular, plural string) string {
 	if count == 1 {
 		return singular
@@ -4,19 +4,18 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/Checkmarx/secret-detection/pkg/parser"
+	"github.com/Checkmarx/secret-detection/pkg/verify"
 	"github.com/checkmarx/2ms/v3/lib/reporting"
 	"github.com/checkmarx/2ms/v3/lib/secrets"
 	"github.com/fatih/color"
+	"io"
 	"sort"
 	"strings"
-	"unicode"
 )
 
-// PrintGitDiffReport formats and prints the report to the console.
-func PrintGitDiffReport(report *reporting.Report, fileDiffs map[string][]parser.Hunk) {
-	const contextBefore = 2
-	const contextAfter = 2
-	const maxDisplayedResults = 100
+// PrintGitDiffReport formats the report and writes it to w.
+func PrintGitDiffReport(w io.Writer, report *reporting.Report, fileDiffs map[string][]parser.Hunk, opts Options) {
+	maxDisplayedResults := opts.Settings.maxDisplayed()
 
 	// Group secrets by file.
 	secretsByFile := groupSecretsByFile(report.Results)
@@ -31,13 +30,13 @@ func PrintGitDiffReport(report *reporting.Report, fileDiffs map[string][]parser.
 	totalFiles := len(secretsByFile)
 	totalSecrets := report.TotalSecretsFound
 
-	color.New(color.FgWhite).Printf("Commit scanned for secrets:\n\n")                                  // nolint:errcheck
-	color.New(color.FgWhite).Printf("Detected ")                                                        // nolint:errcheck
-	color.New(color.FgRed).Printf("%d %s ", totalSecrets, pluralize(totalSecrets, "secret", "secrets")) // nolint:errcheck
-	color.New(color.FgWhite).Printf("in ")                                                              // nolint:errcheck
-	color.New(color.FgRed).Printf("%d %s\n\n", totalFiles, pluralize(totalFiles, "file", "files"))      // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "Commit scanned for secrets:\n\n")                                  // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "Detected ")                                                        // nolint:errcheck
+	color.New(color.FgRed).Fprintf(w, "%d %s ", totalSecrets, pluralize(totalSecrets, "secret", "secrets")) // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "in ")                                                              // nolint:errcheck
+	color.New(color.FgRed).Fprintf(w, "%d %s\n\n", totalFiles, pluralize(totalFiles, "file", "files"))      // nolint:errcheck
 	if totalSecrets > maxDisplayedResults {
-		color.New(color.FgWhite).Printf("Presenting first %d results\n\n", maxDisplayedResults) // nolint:errcheck
+		color.New(color.FgWhite).Fprintf(w, "Presenting first %d results\n\n", maxDisplayedResults) // nolint:errcheck
 	}
 
 	printedSecrets := 0
@@ -49,58 +48,94 @@ resultsLoop:
 		sortSecrets(secretsInFile)
 		numSecrets := len(secretsInFile)
 
-		printFileHeader(fileIndex, file, numSecrets)
+		printFileHeader(w, fileIndex, file, numSecrets)
 
 		hunks := fileDiffs[file]
 		secretGroups, err := groupSecretsByHunk(hunks, secretsInFile)
 		if err != nil {
-			color.New(color.FgRed).Printf("Error grouping secrets by diff hunk for file %s: %v\n", file, err) // nolint:errcheck
+			color.New(color.FgRed).Fprintf(w, "Error grouping secrets by diff hunk for file %s: %v\n", file, err) // nolint:errcheck
 			fileIndex++
 			continue
 		}
 
 		// Process each hunk group.
 		for _, hunkIndex := range sortedKeys(secretGroups) {
-			secretsInHunk := secretGroups[hunkIndex]
-			hunk := hunks[hunkIndex]
-
-			// Compute cumulative offset once for this hunk group.
-			_, cumulative := findHunkIndex(hunks, secretsInHunk[0].StartLine)
-			for secretIdx, secret := range secretsInHunk {
-				localSecretLine := secret.StartLine - cumulative
-				globalSecretLine := getSecretGlobalStartLine(secret.StartLine, hunks, hunkIndex)
-
-				color.New(color.FgWhite).Println("")                              // nolint:errcheck
-				color.New(color.FgWhite).Printf("\tSecret detected: ")            // nolint:errcheck
-				color.New(color.FgHiYellow).Printf("%s\n", secret.RuleID)         // nolint:errcheck
-				color.New(color.FgWhite).Printf("\tResult ID: ")                  // nolint:errcheck
-				color.New(color.FgHiYellow).Printf("%s\n", secret.ID)             // nolint:errcheck
-				color.New(color.FgWhite).Printf("\tRisk Score: ")                 // nolint:errcheck
-				color.New(color.FgHiYellow).Printf("%.1f\n", secret.CvssScore)    // nolint:errcheck
-				color.New(color.FgWhite).Printf("\tLocation: ")                   // nolint:errcheck
-				color.New(color.FgHiYellow).Printf("Line %d\n", globalSecretLine) // nolint:errcheck
-
-				secretLinesCount := countSecretLines(secret.Value)
-				startIndex := localSecretLine - contextBefore
-				endIndex := localSecretLine + secretLinesCount + contextAfter
-
-				contextContent := ProcessContent(hunk.Content, secretsInFile, secretIdx, hunk.StartLine)
-				secretContext := extractLineRange(contextContent, startIndex, endIndex)
-				fmt.Print(secretContext)
+			for _, secret := range secretGroups[hunkIndex] {
+				globalSecretLine, secretContext := FindingContext(secret, secretsInFile, hunks, opts)
+
+				color.New(color.FgWhite).Fprintln(w, "")                                 // nolint:errcheck
+				color.New(color.FgWhite).Fprintf(w, "\tSecret detected: ")               // nolint:errcheck
+				color.New(color.FgHiYellow).Fprintf(w, "%s\n", secret.RuleID)            // nolint:errcheck
+				color.New(color.FgWhite).Fprintf(w, "\tResult ID: ")                     // nolint:errcheck
+				color.New(color.FgHiYellow).Fprintf(w, "%s\n", secret.ID)                // nolint:errcheck
+				color.New(color.FgWhite).Fprintf(w, "\tRisk Score: ")                    // nolint:errcheck
+				color.New(color.FgHiYellow).Fprintf(w, "%.1f\n", secret.CvssScore)       // nolint:errcheck
+				color.New(color.FgWhite).Fprintf(w, "\tSeverity: ")                      // nolint:errcheck
+				color.New(color.FgHiYellow).Fprintf(w, "%s\n", opts.Severity.Of(secret)) // nolint:errcheck
+				if validity := verify.FromValidationResult(secret.ValidationStatus); validity != "" {
+					color.New(color.FgWhite).Fprintf(w, "\tValidity: ")      // nolint:errcheck
+					color.New(color.FgHiYellow).Fprintf(w, "%s\n", validity) // nolint:errcheck
+				}
+				color.New(color.FgWhite).Fprintf(w, "\tLocation: ")                   // nolint:errcheck
+				color.New(color.FgHiYellow).Fprintf(w, "Line %d\n", globalSecretLine) // nolint:errcheck
+				fmt.Fprint(w, secretContext)                                          // nolint:errcheck
 
 				printedSecrets++
-				// If we've already printed 100 secrets, break out of all loops.
+				// If we've already printed the maximum number of secrets, break out of all loops.
 				if printedSecrets >= maxDisplayedResults {
-					fmt.Println()
+					fmt.Fprintln(w) // nolint:errcheck
 					break resultsLoop
 				}
 			}
 		}
 		fileIndex++
-		color.New(color.FgWhite).Println("") // nolint:errcheck
+		color.New(color.FgWhite).Fprintln(w, "") // nolint:errcheck
+	}
+
+	printOptions(w)
+}
+
+// FindingContext returns the line of a finding in the staged file and its redacted context, with the
+// finding highlighted. fileSecrets are the findings of the file, sorted by position.
+func FindingContext(secret *secrets.Secret, fileSecrets []*secrets.Secret, hunks []parser.Hunk, opts Options) (int, string) {
+	contextBefore := opts.Settings.contextLines()
+	contextAfter := opts.Settings.contextLines()
+
+	hunkIndex, cumulative := findHunkIndex(hunks, secret.StartLine)
+	if hunkIndex >= len(hunks) {
+		return secret.StartLine + 1, ""
+	}
+	hunk := hunks[hunkIndex]
+
+	// The finding is highlighted by its position among the findings of its hunk.
+	secretIdx := 0
+	for _, other := range fileSecrets {
+		if other == secret {
+			break
+		}
+		if index, _ := findHunkIndex(hunks, other.StartLine); index == hunkIndex {
+			secretIdx++
+		}
 	}
 
-	printOptions()
+	localSecretLine := secret.StartLine - cumulative
+	globalSecretLine := getSecretGlobalStartLine(secret.StartLine, hunks, hunkIndex)
+
+	secretLinesCount := countSecretLines(secret.Value)
+	startIndex := localSecretLine - contextBefore
+	endIndex := localSecretLine + secretLinesCount + contextAfter
+
+	contextContent := ProcessContent(hunk.Content, fileSecrets, secretIdx, hunk.StartLine, opts.Redaction)
+	return globalSecretLine, extractLineRange(contextContent, startIndex, endIndex)
+}
+
+// SortedSecretsByFile groups the findings of the report by file, sorted by position.
+func SortedSecretsByFile(results map[string][]*secrets.Secret) map[string][]*secrets.Secret {
+	groups := groupSecretsByFile(results)
+	for _, list := range groups {
+		sortSecrets(list)
+	}
+	return groups
 }
 
 // groupSecretsByFile groups secrets by their source file.
@@ -115,11 +150,11 @@ func groupSecretsByFile(results map[string][]*secrets.Secret) map[string][]*secr
 }
 
 // printFileHeader prints the header for each file's report.
-func printFileHeader(fileIndex int, file string, numSecrets int) {
-	color.New(color.FgWhite).Printf("#%d File: ", fileIndex)                                             // nolint:errcheck
-	color.New(color.FgHiYellow).Printf("%s\n", file)                                                     // nolint:errcheck
-	color.New(color.FgRed).Printf("%d ", numSecrets)                                                     // nolint:errcheck
-	color.New(color.FgWhite).Printf("%s detected in file\n", pluralize(numSecrets, "Secret", "Secrets")) // nolint:errcheck
+func printFileHeader(w io.Writer, fileIndex int, file string, numSecrets int) {
+	color.New(color.FgWhite).Fprintf(w, "#%d File: ", fileIndex)                                             // nolint:errcheck
+	color.New(color.FgHiYellow).Fprintf(w, "%s\n", file)                                                     // nolint:errcheck
+	color.New(color.FgRed).Fprintf(w, "%d ", numSecrets)                                                     // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "%s detected in file\n", pluralize(numSecrets, "Secret", "Secrets")) // nolint:errcheck
 }
 
 // sortedKeys returns sorted keys of a map.
@@ -133,36 +168,36 @@ func sortedKeys(m map[int][]*secrets.Secret) []int {
 }
 
 // printOptions prints available options for the commit.
-func printOptions() {
-	color.New(color.FgWhite).Printf("Options for proceeding with the commit:\n\n")                                     // nolint:errcheck
-	color.New(color.FgWhite).Printf("  - Remediate detected secrets using the following workflow (")                   // nolint:errcheck
-	color.New(color.FgGreen).Printf("recommended")                                                                     // nolint:errcheck
-	color.New(color.FgWhite).Printf("):\n")                                                                            // nolint:errcheck
-	color.New(color.FgWhite).Printf("      1. Remove detected secrets from files and store them securely. Options:\n") // nolint:errcheck
-	color.New(color.FgWhite).Printf("         - Use environmental variables\n")                                        // nolint:errcheck
-	color.New(color.FgWhite).Printf("         - Use a secret management service\n")                                    // nolint:errcheck
-	color.New(color.FgWhite).Printf("         - Use a configuration management tool\n")                                // nolint:errcheck
-	color.New(color.FgWhite).Printf("         - Encrypt files containing secrets (least secure method)\n")             // nolint:errcheck
-	color.New(color.FgWhite).Printf("      2. Commit fixed code.\n\n")                                                 // nolint:errcheck
-
-	color.New(color.FgWhite).Printf("  - Ignore detected secrets (")                                        // nolint:errcheck
-	color.New(color.FgYellow).Printf("not recommended")                                                     // nolint:errcheck
-	color.New(color.FgWhite).Printf("):\n")                                                                 // nolint:errcheck
-	color.New(color.FgWhite).Printf("      Use one of the following commands:\n")                           // nolint:errcheck
-	color.New(color.FgHiBlue).Print("          cx hooks pre-commit secrets-ignore --all\n")                 // nolint:errcheck
-	color.New(color.FgHiBlue).Print("          cx hooks pre-commit secrets-ignore --resultIds=id1,id2\n\n") // nolint:errcheck
-
-	color.New(color.FgWhite).Printf("  - Bypass the pre-commit secret detection scanner (")                            // nolint:errcheck
-	color.New(color.FgRed).Printf("not recommended")                                                                   // nolint:errcheck
-	color.New(color.FgWhite).Printf("):\n")                                                                            // nolint:errcheck
-	color.New(color.FgWhite).Printf("      Use one of the following commands based on your OS:\n\n")                   // nolint:errcheck
-	color.New(color.FgWhite).Printf("        Bash/Zsh:\n")                                                             // nolint:errcheck
-	color.New(color.FgHiBlue).Printf("          SKIP=cx-secret-detection git commit -m \"<your message>\"\n\n")        // nolint:errcheck
-	color.New(color.FgWhite).Printf("        Windows CMD:\n")                                                          // nolint:errcheck
-	color.New(color.FgHiBlue).Printf("          set SKIP=cx-secret-detection && git commit -m \"<your message>\"\n\n") // nolint:errcheck
-	color.New(color.FgWhite).Printf("        PowerShell:\n")                                                           // nolint:errcheck
-	color.New(color.FgHiBlue).Printf("          $env:SKIP=\"cx-secret-detection\"\n")                                  // nolint:errcheck
-	color.New(color.FgHiBlue).Printf("          git commit -m \"<your message>\"\n")                                   // nolint:errcheck
+func printOptions(w io.Writer) {
+	color.New(color.FgWhite).Fprintf(w, "Options for proceeding with the commit:\n\n")                                     // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "  - Remediate detected secrets using the following workflow (")                   // nolint:errcheck
+	color.New(color.FgGreen).Fprintf(w, "recommended")                                                                     // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "):\n")                                                                            // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "      1. Remove detected secrets from files and store them securely. Options:\n") // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "         - Use environmental variables\n")                                        // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "         - Use a secret management service\n")                                    // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "         - Use a configuration management tool\n")                                // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "         - Encrypt files containing secrets (least secure method)\n")             // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "      2. Commit fixed code.\n\n")                                                 // nolint:errcheck
+
+	color.New(color.FgWhite).Fprintf(w, "  - Ignore detected secrets (")                                        // nolint:errcheck
+	color.New(color.FgYellow).Fprintf(w, "not recommended")                                                     // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "):\n")                                                                 // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "      Use one of the following commands:\n")                           // nolint:errcheck
+	color.New(color.FgHiBlue).Fprint(w, "          cx hooks pre-commit secrets-ignore --all\n")                 // nolint:errcheck
+	color.New(color.FgHiBlue).Fprint(w, "          cx hooks pre-commit secrets-ignore --resultIds=id1,id2\n\n") // nolint:errcheck
+
+	color.New(color.FgWhite).Fprintf(w, "  - Bypass the pre-commit secret detection scanner (")                            // nolint:errcheck
+	color.New(color.FgRed).Fprintf(w, "not recommended")                                                                   // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "):\n")                                                                            // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "      Use one of the following commands based on your OS:\n\n")                   // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "        Bash/Zsh:\n")                                                             // nolint:errcheck
+	color.New(color.FgHiBlue).Fprintf(w, "          SKIP=cx-secret-detection git commit -m \"<your message>\"\n\n")        // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "        Windows CMD:\n")                                                          // nolint:errcheck
+	color.New(color.FgHiBlue).Fprintf(w, "          set SKIP=cx-secret-detection && git commit -m \"<your message>\"\n\n") // nolint:errcheck
+	color.New(color.FgWhite).Fprintf(w, "        PowerShell:\n")                                                           // nolint:errcheck
+	color.New(color.FgHiBlue).Fprintf(w, "          $env:SKIP=\"cx-secret-detection\"\n")                                  // nolint:errcheck
+	color.New(color.FgHiBlue).Fprintf(w, "          git commit -m \"<your message>\"\n")                                   // nolint:errcheck
 }
 
 func getSecretGlobalStartLine(secretLine int, hunks []parser.Hunk, hunkIndex int) int {
@@ -213,7 +248,7 @@ func extractLineRange(content string, from, to int) string {
 	return builder.String()
 }
 
-func ObfuscateSecrets(content string, secretsList []*secrets.Secret, highlightIdx int) string {
+func ObfuscateSecrets(content string, secretsList []*secrets.Secret, highlightIdx int, redaction RedactionConfig) string {
 	var builder strings.Builder
 	start := 0
 	for i, sec := range secretsList {
@@ -224,7 +259,7 @@ func ObfuscateSecrets(content string, secretsList []*secrets.Secret, highlightId
 		}
 		idx += start
 		builder.WriteString(content[start:idx])
-		obf := getObfuscatedSecret(sec.Value)
+		obf := redaction.RedactInline(sec.Value)
 		if i == highlightIdx {
 			if strings.Contains(obf, "\n") {
 				lines := strings.Split(obf, "\n")
@@ -266,32 +301,11 @@ func AddLineNumbers(content string, startLine int) string {
 	return builder.String()
 }
 
-func ProcessContent(content string, secretsList []*secrets.Secret, highlightIdx, startLine int) string {
-	highlighted := ObfuscateSecrets(content, secretsList, highlightIdx)
+func ProcessContent(content string, secretsList []*secrets.Secret, highlightIdx, startLine int, redaction RedactionConfig) string {
+	highlighted := ObfuscateSecrets(content, secretsList, highlightIdx, redaction)
 	return AddLineNumbers(highlighted, startLine)
 }
 
-func getObfuscatedSecret(secret string) string {
-	if len(secret) == 0 {
-		return secret
-	}
-	var builder strings.Builder
-	visibleCount := 0
-	for _, r := range secret {
-		if unicode.IsSpace(r) {
-			builder.WriteRune(r)
-			continue
-		}
-		if visibleCount < 4 {
-			builder.WriteRune(r)
-			visibleCount++
-		} else {
-			builder.WriteRune('*')
-		}
-	}
-	return builder.String()
-}
-
 func countSecretLines(secret string) int {
 	lines := strings.Split(secret, "\n")
 	if len(lines) > 0 && lines[len(lines)-1] == "" {
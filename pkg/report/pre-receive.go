@@ -2,35 +2,47 @@ package report
 
 import (
 	"encoding/json"
-	"fmt"
 	"sort"
-	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/Checkmarx/secret-detection/pkg/verify"
 	"github.com/checkmarx/2ms/v3/lib/reporting"
 	"github.com/checkmarx/2ms/v3/lib/secrets"
-	"github.com/checkmarx/2ms/v3/plugins"
-	"github.com/gitleaks/go-gitdiff/gitdiff"
 )
 
 const (
-	secretMaxCharacters    = 4
-	obfuscatedSecretString = "***"
-	beginPrivateKeyString  = "-----BEGIN"
-	privateKeySeparator    = "-----"
-	maxDisplayedResults    = 100
-	commitDateLayout       = "Mon Jan 2 15:04:05 2006 -0700 UTC"
+	maxDisplayedResults = 100
+	commitDateLayout    = "Mon Jan 2 15:04:05 2006 -0700 UTC"
 )
 
+// Options controls how scan results are rendered by the hook reports.
+type Options struct {
+	Redaction RedactionConfig
+	Settings  Settings
+	// Template replaces the default pre-receive report layout when set.
+	Template *template.Template
+	// Pusher and Refs are made available to the report template.
+	Pusher string
+	Refs   []RefUpdate
+	// Severity classifies the findings; Decision is the outcome of applying its thresholds.
+	Severity severity.Config
+	Decision severity.Decision
+	// RulesUsed lists the IDs of the rules applied by the scan.
+	RulesUsed []string
+	// SuppressedInline is the number of findings suppressed by inline allow-comments.
+	SuppressedInline int
+	// Baselined is the number of findings accepted by the baseline.
+	Baselined int
+}
+
 type CommitInfo struct {
 	Author string
 	Date   time.Time
-}
-
-type FileInfo struct {
-	File        *gitdiff.File
-	ContentType plugins.DiffType
+	// Subject is the subject of a scanned patch mail; it is not set for pushed commits.
+	Subject string
 }
 
 type SecretInfo struct {
@@ -47,11 +59,15 @@ type SourceInfo struct {
 
 type ReportOutput struct {
 	TotalSecretsFound int             `json:"total_secrets_found"`
+	RulesUsed         []string        `json:"rules_used,omitempty"`
+	SuppressedInline  int             `json:"suppressed_inline,omitempty"`
+	Baselined         int             `json:"baselined,omitempty"`
 	Commits           []CommitSummary `json:"commits"`
 }
 
 type CommitSummary struct {
 	CommitID string        `json:"commit_id"`
+	Subject  string        `json:"subject,omitempty"`
 	Author   string        `json:"author"`
 	Date     time.Time     `json:"date"`
 	Files    []FileSummary `json:"files"`
@@ -63,30 +79,38 @@ type FileSummary struct {
 }
 
 type SecretEntry struct {
-	ID          string `json:"id"`
-	Value       string `json:"value"`
-	RuleID      string `json:"rule_id"`
-	StartLine   int    `json:"start_line"`
-	ContentType string `json:"content_type"`
+	ID          string  `json:"id"`
+	Value       string  `json:"value"`
+	RuleID      string  `json:"rule_id"`
+	StartLine   int     `json:"start_line"`
+	ContentType string  `json:"content_type"`
+	CvssScore   float64 `json:"cvss_score"`
+	Severity    string  `json:"severity"`
+	Validity    string  `json:"validity,omitempty"`
 }
 
 func PreReceiveReportTextFromJSON(jsonData []byte) (string, error) {
+	return preReceiveReportText(jsonData, Options{})
+}
+
+func preReceiveReportText(jsonData []byte, opts Options) (string, error) {
 	var data ReportOutput
 	if err := json.Unmarshal(jsonData, &data); err != nil {
 		return "", err
 	}
-	return buildReportString(&data), nil
+	return renderReport(&data, opts)
 }
 
 func PreReceiveReport(
 	report *reporting.Report,
 	commitInfo map[string]CommitInfo,
+	opts Options,
 ) (string, []byte, error) {
-	jsonBlob, err := generateReportJSON(report, commitInfo)
+	jsonBlob, err := generateReportJSON(report, commitInfo, opts)
 	if err != nil {
 		return "", nil, err
 	}
-	text, err := PreReceiveReportTextFromJSON(jsonBlob)
+	text, err := preReceiveReportText(jsonBlob, opts)
 	if err != nil {
 		return "", nil, err
 	}
@@ -96,21 +120,38 @@ func PreReceiveReport(
 func generateReportJSON(
 	report *reporting.Report,
 	commitInfo map[string]CommitInfo,
+	opts Options,
 ) ([]byte, error) {
+	return json.MarshalIndent(NewReportOutput(report, commitInfo, opts), "", "  ")
+}
+
+// NewReportOutput groups the findings of the scan report by commit and file, with their values redacted.
+func NewReportOutput(
+	report *reporting.Report,
+	commitInfo map[string]CommitInfo,
+	opts Options,
+) *ReportOutput {
 	// Group results by commit
-	secretsByCommit := groupReportResultsByCommitID(report)
+	secretsByCommit := groupReportResultsByCommitID(report, opts.Redaction)
 
-	// Sort commit IDs by date desc
+	// Sort commit IDs by date desc, then by ID for commits of the same date
 	commitIDs := make([]string, 0, len(secretsByCommit))
 	for cid := range secretsByCommit {
 		commitIDs = append(commitIDs, cid)
 	}
 	sort.Slice(commitIDs, func(i, j int) bool {
-		return commitInfo[commitIDs[i]].Date.After(commitInfo[commitIDs[j]].Date)
+		di, dj := commitInfo[commitIDs[i]].Date, commitInfo[commitIDs[j]].Date
+		if !di.Equal(dj) {
+			return di.After(dj)
+		}
+		return commitIDs[i] < commitIDs[j]
 	})
 
 	reportOutput := ReportOutput{
 		TotalSecretsFound: report.TotalSecretsFound,
+		RulesUsed:         opts.RulesUsed,
+		SuppressedInline:  opts.SuppressedInline,
+		Baselined:         opts.Baselined,
 		Commits:           make([]CommitSummary, 0, len(commitIDs)),
 	}
 
@@ -146,6 +187,9 @@ func generateReportJSON(
 					RuleID:      s.secret.RuleID,
 					StartLine:   s.secret.StartLine,
 					ContentType: s.source.contentType,
+					CvssScore:   s.secret.CvssScore,
+					Severity:    string(opts.Severity.Of(s.secret)),
+					Validity:    string(verify.FromValidationResult(s.secret.ValidationStatus)),
 				}
 			}
 			files = append(files, FileSummary{FileName: filename, Secrets: entries})
@@ -153,103 +197,14 @@ func generateReportJSON(
 
 		reportOutput.Commits = append(reportOutput.Commits, CommitSummary{
 			CommitID: cid,
+			Subject:  ci.Subject,
 			Author:   ci.Author,
 			Date:     ci.Date,
 			Files:    files,
 		})
 	}
 
-	return json.MarshalIndent(reportOutput, "", "  ")
-}
-
-func buildReportString(data *ReportOutput) string {
-	var sb strings.Builder
-	// Preallocate based on secrets count
-	sb.Grow(512 * data.TotalSecretsFound)
-
-	sb.WriteString("\n----- Cx Secret Scanner Report -----\n")
-	sb.WriteString("\nDetected ")
-	sb.WriteString(strconv.Itoa(data.TotalSecretsFound))
-	sb.WriteString(pluralize(data.TotalSecretsFound, " secret", " secrets"))
-	sb.WriteString(" across ")
-	sb.WriteString(strconv.Itoa(len(data.Commits)))
-	sb.WriteString(pluralize(len(data.Commits), " commit", " commits"))
-
-	if data.TotalSecretsFound > maxDisplayedResults {
-		sb.WriteString("\n\nPresenting first ")
-		sb.WriteString(strconv.Itoa(maxDisplayedResults))
-		sb.WriteString(" results")
-	}
-
-	sb.WriteString("\n\n")
-
-	printed := 0
-
-	// Label to break out when maxDisplayedResults reached
-outer:
-	for idx, commit := range data.Commits {
-		numSecrets := countSecrets(commit)
-		sb.WriteString(fmt.Sprintf("Commit #%d (%s): %d%s in %d%s\n",
-			idx+1,
-			commit.CommitID,
-			numSecrets,
-			pluralize(numSecrets, " secret", " secrets"),
-			len(commit.Files),
-			pluralize(len(commit.Files), " file", " files"),
-		))
-		sb.WriteString("Author: " + commit.Author + "\n")
-		sb.WriteString("Date: " + commit.Date.Format(commitDateLayout) + "\n\n")
-
-		for _, file := range commit.Files {
-			sb.WriteString(fmt.Sprintf("    File: %s (%d%s)\n",
-				file.FileName,
-				len(file.Secrets),
-				pluralize(len(file.Secrets), " secret", " secrets"),
-			))
-
-			for _, secret := range file.Secrets {
-				if printed >= maxDisplayedResults {
-					break outer
-				}
-
-				sb.WriteString("        Result ID       : " + secret.ID + "\n")
-				sb.WriteString("        Secret Detected : " + secret.Value + "\n")
-				sb.WriteString("        Rule ID         : " + secret.RuleID + "\n")
-				sb.WriteString("        Location        : Line " + strconv.Itoa(secret.StartLine) + "\n")
-				sb.WriteString("        Content Type    : " + secret.ContentType + "\n\n")
-				printed++
-			}
-		}
-	}
-
-	sb.WriteString(
-		`A pre-receive hook set server side prevented you from push secrets.
-To proceed, choose one of the following workflows:
-
-  - Sanitize and Push:
-      1. Rewrite your local Git history to remove all exposed secrets.
-      2. Store secrets securely using one of these methods:
-         - Use environmental variables
-         - Use a secret management service
-         - Use a configuration management tool
-         - Encrypt files containing secrets (the least secure method)
-      3. Push code.
-
-  - Ignore detected secrets:
-      1. Contact your system administrator to update the server-side secret scanner
-          configuration to ignore the detected secret.
-      2. Once the new ignore rules are in place, retry pushing your code.
-
-  - Bypass the secret scanner:
-      1. Run ` + "`git push -o skip-secret-scanner`" + `
-      2. If that does not work, ask your system administrator to update the server-side
-          configuration to allow skipping the secret scanner.
-
-You can set up pre-commit secret scanning to avoid rewriting git history in the future:
- - https://docs.checkmarx.com/en/34965-364702-pre-commit-secret-scanning.html
-
-`)
-	return sb.String()
+	return &reportOutput
 }
 
 func countSecrets(c CommitSummary) int {
@@ -260,22 +215,7 @@ func countSecrets(c CommitSummary) int {
 	return total
 }
 
-func obfuscateSecret(snippet string) string {
-	truncatedSecret := snippet
-
-	if strings.HasPrefix(truncatedSecret, beginPrivateKeyString) {
-		truncatedSecret = strings.Split(truncatedSecret, privateKeySeparator)[2]
-		truncatedSecret = strings.TrimPrefix(truncatedSecret, "\n")
-		truncatedSecret = strings.TrimPrefix(truncatedSecret, "\\n")
-	}
-
-	if len(truncatedSecret) > secretMaxCharacters {
-		truncatedSecret = truncatedSecret[:secretMaxCharacters] + obfuscatedSecretString
-	}
-	return truncatedSecret
-}
-
-func groupReportResultsByCommitID(report *reporting.Report) map[string][]*SecretInfo {
+func groupReportResultsByCommitID(report *reporting.Report, redaction RedactionConfig) map[string][]*SecretInfo {
 	secretsByCommitID := make(map[string][]*SecretInfo)
 
 	for _, results := range report.Results {
@@ -290,7 +230,7 @@ func groupReportResultsByCommitID(report *reporting.Report) map[string][]*Secret
 			fileName := parts[2]
 
 			resultCopy := *result
-			resultCopy.Value = obfuscateSecret(result.Value)
+			resultCopy.Value = redaction.Redact(result.Value)
 
 			secretInfo := &SecretInfo{secret: &resultCopy}
 			secretInfo.source = SourceInfo{contentType: contentType, fileName: fileName}
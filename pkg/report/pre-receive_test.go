@@ -1,6 +1,7 @@
 package report
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/checkmarx/2ms/v3/lib/reporting"
 	"github.com/checkmarx/2ms/v3/lib/secrets"
@@ -249,7 +250,7 @@ func TestGroupReportResultsByCommitID(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got := groupReportResultsByCommitID(tc.report)
+			got := groupReportResultsByCommitID(tc.report, DefaultRedactionConfig())
 
 			// check number of distinct commit IDs
 			assert.Lenf(t, got, len(tc.wantKeys),
@@ -257,6 +258,12 @@ func TestGroupReportResultsByCommitID(t *testing.T) {
 				len(tc.wantKeys), len(got),
 			)
 
+			originals := map[string]string{}
+			for _, results := range tc.report.Results {
+				for _, result := range results {
+					originals[result.ID] = result.Value
+				}
+			}
 			for _, commitID := range tc.wantKeys {
 				list, found := got[commitID]
 				assert.Truef(t, found, "expected commit ID %q to be present", commitID)
@@ -268,8 +275,8 @@ func TestGroupReportResultsByCommitID(t *testing.T) {
 				for _, si := range list {
 					// ID is preserved
 					assert.NotNil(t, si.secret, "secret pointer should not be nil")
-					// Value should be obfuscated
-					expectedOb := obfuscateSecret(si.secret.Value)
+					// Value should be obfuscated, on a copy of the reported secret
+					expectedOb := DefaultRedactionConfig().Redact(originals[si.secret.ID])
 					assert.Equalf(t, expectedOb, si.secret.Value,
 						"secret ID=%s: Value should be obfuscated", si.secret.ID,
 					)
@@ -303,17 +310,17 @@ func TestObfuscateSecret(t *testing.T) {
 		{
 			name:     "short string under max length",
 			input:    "abc",
-			expected: "abc",
+			expected: "a***", // at least half of a short value is masked
 		},
 		{
 			name:     "string exactly max length",
-			input:    "abcd", // length == secretMaxCharacters
-			expected: "abcd",
+			input:    "abcd", // length == defaultVisiblePrefix
+			expected: "ab***",
 		},
 		{
 			name:     "string longer than max length",
 			input:    "abcdef",
-			expected: "abcd***", // truncated to 4 + obfuscatedSecretString
+			expected: "abc***", // capped to half of the value + obfuscatedSecretString
 		},
 		{
 			name: "private key with newline prefix",
@@ -338,14 +345,14 @@ func TestObfuscateSecret(t *testing.T) {
 			input: `-----BEGIN KEY-----` +
 				"\n" +
 				"AB",
-			// after trim => "AB" (length < max), no truncation
-			expected: "AB",
+			// after trim => "AB" (length < max), half of it stays masked
+			expected: "A***",
 		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			got := obfuscateSecret(tc.input)
+			got := DefaultRedactionConfig().Redact(tc.input)
 			assert.Equal(t, tc.expected, got, "unexpected obfuscated value")
 		})
 	}
@@ -394,7 +401,7 @@ func TestPreReceiveReport(t *testing.T) {
 			assert.NoError(t, err)
 			expected := strings.ReplaceAll(string(wantText), "\r", "")
 
-			text, jsonBlob, err := PreReceiveReport(report, info)
+			text, jsonBlob, err := PreReceiveReport(report, info, Options{Redaction: DefaultRedactionConfig()})
 			assert.NoError(t, err)
 			assert.Equal(t, expected, text)
 
@@ -407,3 +414,31 @@ func TestPreReceiveReport(t *testing.T) {
 		})
 	}
 }
+
+func TestPreReceiveReportRulesUsed(t *testing.T) {
+	report, info := makeReport(1, 1, 1)
+
+	_, jsonBlob, err := PreReceiveReport(report, info, Options{RulesUsed: []string{"github-pat", "acme-key"}})
+	assert.NoError(t, err)
+	var output ReportOutput
+	assert.NoError(t, json.Unmarshal(jsonBlob, &output))
+	assert.Equal(t, []string{"github-pat", "acme-key"}, output.RulesUsed)
+
+	_, jsonBlob, err = PreReceiveReport(report, info, Options{})
+	assert.NoError(t, err)
+	assert.NotContains(t, string(jsonBlob), "rules_used")
+}
+
+func TestPreReceiveReportBaselined(t *testing.T) {
+	report, info := makeReport(1, 1, 1)
+
+	text, jsonBlob, err := PreReceiveReport(report, info, Options{Baselined: 3})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "3 findings accepted by the baseline")
+	assert.Contains(t, string(jsonBlob), `"baselined": 3`)
+
+	text, jsonBlob, err = PreReceiveReport(report, info, Options{})
+	assert.NoError(t, err)
+	assert.NotContains(t, text, "baseline")
+	assert.NotContains(t, string(jsonBlob), "baselined")
+}
@@ -0,0 +1,297 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/Checkmarx/secret-detection/pkg/fingerprint"
+)
+
+// RedactionMode selects how secret values are rendered in reports.
+type RedactionMode string
+
+const (
+	// RedactionPartial shows a configurable number of leading and trailing characters.
+	RedactionPartial RedactionMode = "partial"
+	// RedactionFull masks every character of the secret.
+	RedactionFull RedactionMode = "full"
+	// RedactionFingerprint replaces the secret with its fingerprint, keyed by the configured salt.
+	RedactionFingerprint RedactionMode = "fingerprint"
+)
+
+const (
+	defaultVisiblePrefix   = 4
+	obfuscatedSecretString = "***"
+	maskRune               = '*'
+	beginPrivateKeyString  = "-----BEGIN"
+	endPrivateKeyString    = "-----END"
+	privateKeySeparator    = "-----"
+)
+
+var (
+	jwtRegex              = regexp.MustCompile(`^(eyJ[A-Za-z0-9_-]*)\.([A-Za-z0-9_-]*)\.([A-Za-z0-9_-]*)$`)
+	urlCredentialsRegex   = regexp.MustCompile(`^([a-zA-Z][a-zA-Z0-9+.-]*://[^:/@\s]*:)([^@\s]+)(@.*)$`)
+	keyValuePasswordRegex = regexp.MustCompile(`(?i)((?:password|passwd|pwd)\s*=\s*)([^;]+)`)
+)
+
+// RedactionConfig describes how secret values are redacted in the console output and in the JSON reports.
+type RedactionConfig struct {
+	Mode          RedactionMode `yaml:"mode"`
+	VisiblePrefix int           `yaml:"visible_prefix"`
+	VisibleSuffix int           `yaml:"visible_suffix"`
+	Salt          string        `yaml:"salt"`
+}
+
+// valueKind identifies the structure of a secret value.
+type valueKind int
+
+const (
+	plainValue valueKind = iota
+	privateKeyValue
+	jwtValue
+	connectionStringValue
+)
+
+// valuePart is a piece of a secret value; only secret parts are subject to redaction.
+type valuePart struct {
+	text   string
+	secret bool
+}
+
+// DefaultRedactionConfig returns the policy used when no redaction is configured: the first 4 characters are shown.
+func DefaultRedactionConfig() RedactionConfig {
+	return RedactionConfig{
+		Mode:          RedactionPartial,
+		VisiblePrefix: defaultVisiblePrefix,
+	}
+}
+
+// Validate checks the redaction settings, returning an error describing the first invalid field.
+func (c RedactionConfig) Validate() error {
+	switch c.Mode {
+	case "", RedactionPartial, RedactionFull, RedactionFingerprint:
+	default:
+		return fmt.Errorf("unknown redaction mode %q", c.Mode)
+	}
+	if c.VisiblePrefix < 0 || c.VisibleSuffix < 0 {
+		return fmt.Errorf("redaction visible_prefix and visible_suffix must not be negative")
+	}
+	if err := fingerprint.ValidateSalt(c.Salt); err != nil {
+		return fmt.Errorf("redaction salt: %w", err)
+	}
+	return nil
+}
+
+// normalized returns the effective policy, falling back to the default when no mode is set.
+func (c RedactionConfig) normalized() RedactionConfig {
+	if c.Mode == "" {
+		defaults := DefaultRedactionConfig()
+		defaults.Salt = c.Salt
+		return defaults
+	}
+	return c
+}
+
+// Fingerprint returns the fingerprint of the value keyed by the salt, as shown by the fingerprint
+// redaction mode. It is the one recorded by the fingerprint entries of the ignore file.
+func (c RedactionConfig) Fingerprint(value string) string {
+	return fingerprint.Of(c.Salt, value)
+}
+
+// Redact returns a compact, single-value representation of the secret suitable for text and JSON reports.
+func (c RedactionConfig) Redact(value string) string {
+	if len(value) == 0 {
+		return value
+	}
+	c = c.normalized()
+	if c.Mode == RedactionFingerprint {
+		return c.Fingerprint(value)
+	}
+
+	kind, parts := splitValue(value)
+	if kind == plainValue {
+		return c.maskCompact(value, true)
+	}
+	if kind == privateKeyValue {
+		// Only the key material is meaningful in a compact report line.
+		return c.maskCompact(privateKeyBody(parts), true)
+	}
+
+	var builder strings.Builder
+	secretIndex := 0
+	for _, part := range parts {
+		if !part.secret {
+			builder.WriteString(part.text)
+			continue
+		}
+		policy := c
+		if secretIndex > 0 {
+			policy.VisiblePrefix = 0
+		}
+		if kind == jwtValue {
+			// Never reveal any part of the signature or payload.
+			policy.VisibleSuffix = 0
+		}
+		builder.WriteString(policy.maskCompact(part.text, false))
+		secretIndex++
+	}
+	return builder.String()
+}
+
+// RedactInline masks the secret in place, preserving its whitespace and line structure so that
+// it can be displayed within its surrounding content.
+func (c RedactionConfig) RedactInline(value string) string {
+	if len(value) == 0 {
+		return value
+	}
+	c = c.normalized()
+
+	kind, parts := splitValue(value)
+	totalSecret := 0
+	for _, part := range parts {
+		if part.secret {
+			totalSecret += countNonSpace(part.text)
+		}
+	}
+
+	prefix, suffix := c.visible(totalSecret)
+	if c.Mode != RedactionPartial {
+		prefix, suffix = 0, 0
+	}
+	if kind == jwtValue {
+		suffix = 0
+	}
+
+	var builder strings.Builder
+	position := 0
+	for _, part := range parts {
+		if !part.secret {
+			builder.WriteString(part.text)
+			continue
+		}
+		for _, r := range part.text {
+			if unicode.IsSpace(r) {
+				builder.WriteRune(r)
+				continue
+			}
+			if position < prefix || position >= totalSecret-suffix {
+				builder.WriteRune(r)
+			} else {
+				builder.WriteRune(maskRune)
+			}
+			position++
+		}
+	}
+	masked := builder.String()
+
+	if c.Mode == RedactionFingerprint {
+		// Keep the line count intact: the fingerprint replaces the first line only.
+		lines := strings.SplitN(masked, "\n", 2)
+		lines[0] = c.Fingerprint(value)
+		return strings.Join(lines, "\n")
+	}
+	return masked
+}
+
+// maskCompact shows the visible prefix and suffix around the obfuscated marker. Values that are too
+// short to hide anything behind the configured characters keep at most half of them visible when
+// keepShort is set, and are fully masked otherwise.
+func (c RedactionConfig) maskCompact(value string, keepShort bool) string {
+	if c.Mode == RedactionFull {
+		return obfuscatedSecretString
+	}
+	runes := []rune(value)
+	if len(runes) <= c.VisiblePrefix+c.VisibleSuffix && !keepShort {
+		return obfuscatedSecretString
+	}
+	prefix, suffix := c.visible(len(runes))
+	return string(runes[:prefix]) + obfuscatedSecretString + string(runes[len(runes)-suffix:])
+}
+
+// visible returns the numbers of leading and trailing characters shown of a value of the given length:
+// the configured ones, capped so that at least half of the value is always masked.
+func (c RedactionConfig) visible(length int) (prefix, suffix int) {
+	budget := length / 2
+	prefix = min(c.VisiblePrefix, budget)
+	suffix = min(c.VisibleSuffix, budget-prefix)
+	return prefix, suffix
+}
+
+// splitValue breaks a secret value into visible structural parts and secret parts.
+func splitValue(value string) (valueKind, []valuePart) {
+	if strings.HasPrefix(value, beginPrivateKeyString) {
+		return privateKeyValue, splitPrivateKey(value)
+	}
+	if m := jwtRegex.FindStringSubmatch(value); m != nil {
+		return jwtValue, []valuePart{
+			{text: m[1], secret: true},
+			{text: "."},
+			{text: m[2], secret: true},
+			{text: "."},
+			{text: m[3], secret: true},
+		}
+	}
+	if m := urlCredentialsRegex.FindStringSubmatch(value); m != nil {
+		return connectionStringValue, []valuePart{
+			{text: m[1]},
+			{text: m[2], secret: true},
+			{text: m[3]},
+		}
+	}
+	if locs := keyValuePasswordRegex.FindAllStringSubmatchIndex(value, -1); locs != nil {
+		var parts []valuePart
+		last := 0
+		for _, loc := range locs {
+			parts = append(parts, valuePart{text: value[last:loc[4]]})
+			parts = append(parts, valuePart{text: value[loc[4]:loc[5]], secret: true})
+			last = loc[5]
+		}
+		parts = append(parts, valuePart{text: value[last:]})
+		return connectionStringValue, parts
+	}
+	return plainValue, []valuePart{{text: value, secret: true}}
+}
+
+// splitPrivateKey keeps the PEM header and footer lines visible and marks the key material as secret.
+func splitPrivateKey(value string) []valuePart {
+	headerEnd := strings.Index(value[len(privateKeySeparator):], privateKeySeparator)
+	if headerEnd == -1 {
+		return []valuePart{{text: value, secret: true}}
+	}
+	headerEnd += 2 * len(privateKeySeparator)
+	header, rest := value[:headerEnd], value[headerEnd:]
+
+	footer := ""
+	if idx := strings.Index(rest, endPrivateKeyString); idx != -1 {
+		rest, footer = rest[:idx], rest[idx:]
+	}
+	return []valuePart{
+		{text: header},
+		{text: rest, secret: true},
+		{text: footer},
+	}
+}
+
+// privateKeyBody returns the key material of a PEM block without surrounding line breaks.
+func privateKeyBody(parts []valuePart) string {
+	for _, part := range parts {
+		if part.secret {
+			body := strings.TrimPrefix(part.text, "\n")
+			body = strings.TrimPrefix(body, "\\n")
+			return strings.TrimRight(body, "\n")
+		}
+	}
+	return ""
+}
+
+func countNonSpace(s string) int {
+	count := 0
+	for _, r := range s {
+		if !unicode.IsSpace(r) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,75 @@
+package report
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+)
+
+// Result is the outcome of a scan run by a hook, for the programs that embed the hooks. The hooks
+// themselves only turn it into an exit status.
+type Result struct {
+	// Findings are the reported secrets, sorted by source and position. Their values are redacted
+	// like in the report. Ignored, baselined and suppressed findings are not included.
+	Findings []*secrets.Secret
+	// Suppressed counts the findings removed by inline allow-comments, Baselined those accepted by
+	// the baseline.
+	Suppressed int
+	Baselined  int
+	// Decision is the outcome of the severity policy and of the validity checks.
+	Decision severity.Decision
+	// Rendered is the report as written to the output of the scan.
+	Rendered string
+	// JSON is the JSON report logged by the pre-receive hook; it is empty when nothing was found.
+	JSON []byte
+	// Skipped is set when the scan was bypassed by the skip-secret-scanner push option.
+	Skipped bool
+}
+
+// Blocked reports whether the findings block the commit or push.
+func (r *Result) Blocked() bool {
+	return r.Decision == severity.Block
+}
+
+// SortedFindings returns the findings of the report sorted by source, position and rule.
+func SortedFindings(rep *reporting.Report) []*secrets.Secret {
+	var findings []*secrets.Secret
+	for _, results := range rep.Results {
+		findings = append(findings, results...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.StartLine != b.StartLine {
+			return a.StartLine < b.StartLine
+		}
+		if a.StartColumn != b.StartColumn {
+			return a.StartColumn < b.StartColumn
+		}
+		if a.RuleID != b.RuleID {
+			return a.RuleID < b.RuleID
+		}
+		return a.ID < b.ID
+	})
+	return findings
+}
+
+// RedactedFindings returns copies of the sorted findings of the report, whose value and line content
+// are redacted with the configuration, so that the result of a scan never holds the secrets.
+func RedactedFindings(rep *reporting.Report, redaction RedactionConfig) []*secrets.Secret {
+	findings := SortedFindings(rep)
+	for i, finding := range findings {
+		redacted := *finding
+		if finding.Value != "" {
+			redacted.Value = redaction.Redact(finding.Value)
+			redacted.LineContent = strings.ReplaceAll(finding.LineContent, finding.Value, redaction.RedactInline(finding.Value))
+		}
+		findings[i] = &redacted
+	}
+	return findings
+}
@@ -0,0 +1,155 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+)
+
+const (
+	sarifSchema    = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion   = "2.1.0"
+	sarifToolName  = "Cx Secret Detection"
+	sarifToolURI   = "https://checkmarx.com"
+	sarifIDKey     = "cxSecretId/v1"
+	shortCommitLen = 8
+)
+
+// SARIF 2.1.0 serialization schema, limited to the properties the reports use.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string                 `json:"ruleId"`
+	Level               string                 `json:"level"`
+	Message             sarifMessage           `json:"message"`
+	Locations           []sarifLocation        `json:"locations"`
+	PartialFingerprints map[string]string      `json:"partialFingerprints"`
+	Properties          map[string]interface{} `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// SARIFReport renders the findings of the report as a SARIF 2.1.0 log. Each result carries the commit
+// that introduced the secret and its author as properties, and the secret ID as a partial fingerprint.
+func SARIFReport(data *ReportOutput) ([]byte, error) {
+	rules := map[string]bool{}
+	results := []sarifResult{}
+	for _, commit := range data.Commits {
+		for _, file := range commit.Files {
+			for _, secret := range file.Secrets {
+				rules[secret.RuleID] = true
+				results = append(results, newSarifResult(commit, file.FileName, secret))
+			}
+		}
+	}
+
+	ruleIDs := make([]string, 0, len(rules))
+	for id := range rules {
+		ruleIDs = append(ruleIDs, id)
+	}
+	sort.Strings(ruleIDs)
+	driver := sarifDriver{Name: sarifToolName, InformationURI: sarifToolURI, Rules: make([]sarifRule, len(ruleIDs))}
+	for i, id := range ruleIDs {
+		driver.Rules[i] = sarifRule{ID: id, ShortDescription: sarifMessage{Text: fmt.Sprintf("Secret detected by the %s rule", id)}}
+	}
+
+	return json.MarshalIndent(sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}, "", "  ")
+}
+
+func newSarifResult(commit CommitSummary, fileName string, secret SecretEntry) sarifResult {
+	location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: (&url.URL{Path: fileName}).String()}}
+	if secret.StartLine > 0 {
+		location.Region = &sarifRegion{StartLine: secret.StartLine}
+	}
+	shortCommit := commit.CommitID
+	if len(shortCommit) > shortCommitLen {
+		shortCommit = shortCommit[:shortCommitLen]
+	}
+
+	properties := map[string]interface{}{
+		"commitId":    commit.CommitID,
+		"author":      commit.Author,
+		"date":        commit.Date.Format(time.RFC3339),
+		"contentType": secret.ContentType,
+		"severity":    secret.Severity,
+	}
+	if secret.Validity != "" {
+		properties["validity"] = secret.Validity
+	}
+	if secret.CvssScore > 0 {
+		properties["cvssScore"] = secret.CvssScore
+	}
+
+	return sarifResult{
+		RuleID:              secret.RuleID,
+		Level:               sarifLevel(severity.Level(secret.Severity)),
+		Message:             sarifMessage{Text: fmt.Sprintf("Secret %s introduced in commit %s by %s", secret.Value, shortCommit, commit.Author)},
+		Locations:           []sarifLocation{{PhysicalLocation: location}},
+		PartialFingerprints: map[string]string{sarifIDKey: secret.ID},
+		Properties:          properties,
+	}
+}
+
+// sarifLevel maps a severity to the level of a SARIF result.
+func sarifLevel(level severity.Level) string {
+	switch level {
+	case severity.Critical, severity.High:
+		return "error"
+	case severity.Medium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
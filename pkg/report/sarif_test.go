@@ -0,0 +1,58 @@
+package report
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSARIFReport(t *testing.T) {
+	data := &ReportOutput{
+		TotalSecretsFound: 2,
+		Commits: []CommitSummary{{
+			CommitID: "0123456789abcdef",
+			Author:   "Dev (dev@example.com)",
+			Date:     time.Date(2025, 1, 31, 10, 0, 0, 0, time.UTC),
+			Files: []FileSummary{
+				{FileName: "config/app.yaml", Secrets: []SecretEntry{{ID: "id-1", Value: "ghp_****", RuleID: "github-pat", StartLine: 3, ContentType: "Added", Severity: "high"}}},
+				{FileName: CommitMessageFile, Secrets: []SecretEntry{{ID: "id-2", Value: "eyJ****", RuleID: "jwt", StartLine: 1, ContentType: "Added", Severity: "low"}}},
+			},
+		}},
+	}
+
+	output, err := SARIFReport(data)
+	assert.NoError(t, err)
+
+	var log sarifLog
+	assert.NoError(t, json.Unmarshal(output, &log))
+	assert.Equal(t, sarifVersion, log.Version)
+	assert.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	assert.Equal(t, []sarifRule{
+		{ID: "github-pat", ShortDescription: sarifMessage{Text: "Secret detected by the github-pat rule"}},
+		{ID: "jwt", ShortDescription: sarifMessage{Text: "Secret detected by the jwt rule"}},
+	}, run.Tool.Driver.Rules)
+
+	assert.Len(t, run.Results, 2)
+	first := run.Results[0]
+	assert.Equal(t, "github-pat", first.RuleID)
+	assert.Equal(t, "error", first.Level)
+	assert.Equal(t, "Secret ghp_**** introduced in commit 01234567 by Dev (dev@example.com)", first.Message.Text)
+	assert.Equal(t, "config/app.yaml", first.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, &sarifRegion{StartLine: 3}, first.Locations[0].PhysicalLocation.Region)
+	assert.Equal(t, map[string]string{sarifIDKey: "id-1"}, first.PartialFingerprints)
+	assert.Equal(t, "0123456789abcdef", first.Properties["commitId"])
+	assert.Equal(t, "2025-01-31T10:00:00Z", first.Properties["date"])
+
+	second := run.Results[1]
+	assert.Equal(t, "note", second.Level)
+	assert.Equal(t, "commit%20message", second.Locations[0].PhysicalLocation.ArtifactLocation.URI)
+}
+
+func TestSARIFReportWithoutFindings(t *testing.T) {
+	output, err := SARIFReport(&ReportOutput{})
+	assert.NoError(t, err)
+	assert.Contains(t, string(output), `"results": []`)
+}
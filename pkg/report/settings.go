@@ -0,0 +1,38 @@
+package report
+
+import "fmt"
+
+const defaultContextLines = 2
+
+// Settings holds the report options read from the hook configuration.
+type Settings struct {
+	// MaxDisplayedResults limits the findings printed by the reports; the JSON report keeps all of them.
+	MaxDisplayedResults int `yaml:"max_displayed_results"`
+	// ContextLines is the number of lines printed around each finding by the pre-commit report.
+	ContextLines *int `yaml:"context_lines"`
+}
+
+// Validate checks the report settings.
+func (s Settings) Validate() error {
+	if s.MaxDisplayedResults < 0 {
+		return fmt.Errorf("report max_displayed_results must not be negative")
+	}
+	if s.ContextLines != nil && *s.ContextLines < 0 {
+		return fmt.Errorf("report context_lines must not be negative")
+	}
+	return nil
+}
+
+func (s Settings) maxDisplayed() int {
+	if s.MaxDisplayedResults == 0 {
+		return maxDisplayedResults
+	}
+	return s.MaxDisplayedResults
+}
+
+func (s Settings) contextLines() int {
+	if s.ContextLines == nil {
+		return defaultContextLines
+	}
+	return *s.ContextLines
+}
@@ -0,0 +1,226 @@
+package report
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/Checkmarx/secret-detection/pkg/verify"
+)
+
+// reportTemplateName is the entry point of the pre-receive report template set.
+const reportTemplateName = "report"
+
+//go:embed templates/pre-receive.tmpl templates/pre-push.tmpl templates/patch.tmpl
+var templateFiles embed.FS
+
+var defaultTemplate = template.Must(
+	template.New("pre-receive.tmpl").Funcs(templateFuncs).ParseFS(templateFiles, "templates/pre-receive.tmpl"),
+)
+
+// prePushTemplate is the pre-receive layout with the footer of the local pre-push hook.
+var prePushTemplate = template.Must(
+	template.Must(defaultTemplate.Clone()).ParseFS(templateFiles, "templates/pre-push.tmpl"),
+)
+
+// patchTemplate is the pre-receive layout for patches read from files or mails instead of pushed commits.
+var patchTemplate = template.Must(
+	template.Must(defaultTemplate.Clone()).ParseFS(templateFiles, "templates/patch.tmpl"),
+)
+
+var templateFuncs = template.FuncMap{
+	"pluralize": func(count int, singular, plural string) string {
+		return pluralize(count, singular, plural)
+	},
+	"formatDate": func(date time.Time) string {
+		return date.Format(commitDateLayout)
+	},
+	"severityName": func(level string) string {
+		return severity.Level(level).String()
+	},
+	"validityName": func(status string) string {
+		return verify.Status(status).String()
+	},
+}
+
+// RefUpdate describes a single ref update received by the pre-receive hook.
+type RefUpdate struct {
+	OldObject string
+	NewObject string
+	RefName   string
+}
+
+// TemplateData is the data passed to the pre-receive report template.
+//
+// Report holds every finding, while Commits holds only the commits, files and findings that fit
+// within MaxDisplayedResults. The "commit", "file" and "finding" templates are executed with the
+// elements of Commits, Commits[].Displayed and Commits[].Displayed[].Displayed respectively.
+type TemplateData struct {
+	Report              *ReportOutput
+	Pusher              string
+	Refs                []RefUpdate
+	Decision            severity.Decision
+	MaxDisplayedResults int
+	Commits             []CommitView
+}
+
+// CommitView is a commit of the report together with the files that are displayed for it.
+type CommitView struct {
+	CommitSummary
+	Number     int
+	NumSecrets int
+	Displayed  []FileView
+}
+
+// FileView is a file of the report together with the findings that are displayed for it.
+type FileView struct {
+	FileSummary
+	Displayed []SecretEntry
+}
+
+// LoadTemplate parses the report template at path. The file may redefine any of the "header",
+// "commit", "file", "finding" and "footer" templates, in which case the remaining ones keep the
+// default layout, or provide a complete layout as top-level content.
+func LoadTemplate(path string) (*template.Template, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read report template at %s: %w", path, err)
+	}
+
+	set, err := defaultTemplate.Clone()
+	if err != nil {
+		return nil, err
+	}
+	custom, err := set.New(filepath.Base(path)).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("report template at %s is invalid: %w", path, err)
+	}
+	if !hasContent(custom) {
+		custom = custom.Lookup(reportTemplateName)
+	}
+
+	// Render a sample report to catch references to unknown fields before the template is needed,
+	// including those in the bodies of the commits, files and findings.
+	if err = custom.Execute(&strings.Builder{}, sampleTemplateData()); err != nil {
+		return nil, fmt.Errorf("report template at %s is invalid: %w", path, err)
+	}
+	return custom, nil
+}
+
+// sampleTemplateData returns the data of a report with one commit, file and finding of every field,
+// pushed to one ref, that a template is validated with.
+func sampleTemplateData() *TemplateData {
+	data := &ReportOutput{
+		TotalSecretsFound: 1,
+		RulesUsed:         []string{"generic-api-key"},
+		SuppressedInline:  1,
+		Baselined:         1,
+		Commits: []CommitSummary{{
+			CommitID: "0123456789abcdef0123456789abcdef01234567",
+			Subject:  "Add the configuration",
+			Author:   "Jane Dev (jane@example.com)",
+			Date:     time.Date(2024, time.January, 2, 15, 4, 5, 0, time.UTC),
+			Files: []FileSummary{{
+				FileName: "config.env",
+				Secrets: []SecretEntry{{
+					ID:          "sample",
+					Value:       "abcd***",
+					RuleID:      "generic-api-key",
+					StartLine:   1,
+					ContentType: "Added",
+					CvssScore:   8.2,
+					Severity:    string(severity.High),
+					Validity:    string(verify.Live),
+				}},
+			}},
+		}},
+	}
+	return newTemplateData(data, Options{
+		Pusher:   "jane",
+		Refs:     []RefUpdate{{OldObject: "0123456", NewObject: "89abcde", RefName: "refs/heads/main"}},
+		Decision: severity.Block,
+	})
+}
+
+// PrePushTemplate returns the report layout of the pre-push hook, whose footer gives the remediation
+// options available before the commits reach the server.
+func PrePushTemplate() *template.Template {
+	return prePushTemplate.Lookup(reportTemplateName)
+}
+
+// PatchTemplate returns the report layout of the patch scan, which shows the subject of each patch and
+// whose footer addresses the reviewer of the patches.
+func PatchTemplate() *template.Template {
+	return patchTemplate.Lookup(reportTemplateName)
+}
+
+// renderReport executes the configured template, or the default layout when none is set.
+func renderReport(data *ReportOutput, opts Options) (string, error) {
+	tmpl := opts.Template
+	if tmpl == nil {
+		tmpl = defaultTemplate.Lookup(reportTemplateName)
+	}
+
+	var sb strings.Builder
+	// Preallocate based on secrets count
+	sb.Grow(512 * data.TotalSecretsFound)
+	if err := tmpl.Execute(&sb, newTemplateData(data, opts)); err != nil {
+		return "", fmt.Errorf("failed to render report: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// newTemplateData builds the template data, keeping only the first findings up to the display limit.
+func newTemplateData(data *ReportOutput, opts Options) *TemplateData {
+	templateData := &TemplateData{
+		Report:              data,
+		Pusher:              opts.Pusher,
+		Refs:                opts.Refs,
+		Decision:            opts.Decision,
+		MaxDisplayedResults: opts.Settings.maxDisplayed(),
+	}
+
+	printed := 0
+	// Label to break out when MaxDisplayedResults reached
+outer:
+	for idx, commit := range data.Commits {
+		templateData.Commits = append(templateData.Commits, CommitView{
+			CommitSummary: commit,
+			Number:        idx + 1,
+			NumSecrets:    countSecrets(commit),
+		})
+		commitView := &templateData.Commits[len(templateData.Commits)-1]
+		for _, file := range commit.Files {
+			commitView.Displayed = append(commitView.Displayed, FileView{FileSummary: file})
+			fileView := &commitView.Displayed[len(commitView.Displayed)-1]
+			for _, secret := range file.Secrets {
+				if printed >= templateData.MaxDisplayedResults {
+					break outer
+				}
+				fileView.Displayed = append(fileView.Displayed, secret)
+				printed++
+			}
+		}
+	}
+	return templateData
+}
+
+// hasContent reports whether the template has top-level content besides whitespace.
+func hasContent(tmpl *template.Template) bool {
+	if tmpl.Tree == nil || tmpl.Tree.Root == nil {
+		return false
+	}
+	for _, node := range tmpl.Tree.Root.Nodes {
+		if text, ok := node.(*parse.TextNode); ok && strings.TrimSpace(string(text.Text)) == "" {
+			continue
+		}
+		return true
+	}
+	return false
+}
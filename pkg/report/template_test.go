@@ -0,0 +1,139 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTemplate(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "report.tmpl")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoadTemplate(t *testing.T) {
+	report, info := makeReport(8, 4, 2)
+	refs := []RefUpdate{{OldObject: "old", NewObject: "new", RefName: "refs/heads/main"}}
+
+	t.Run("override footer keeps default header and findings", func(t *testing.T) {
+		tmpl, err := LoadTemplate(writeTemplate(t, `{{define "footer"}}Ask in #security, {{.Pusher}}.
+{{end}}`))
+		assert.NoError(t, err)
+
+		text, _, err := PreReceiveReport(report, info, Options{Template: tmpl, Pusher: "alice", Refs: refs})
+		assert.NoError(t, err)
+
+		defaultText, _, err := PreReceiveReport(report, info, Options{})
+		assert.NoError(t, err)
+		body := defaultText[:strings.Index(defaultText, "A pre-receive hook")]
+		assert.Equal(t, body+"Ask in #security, alice.\n", text)
+	})
+
+	t.Run("complete layout", func(t *testing.T) {
+		tmpl, err := LoadTemplate(writeTemplate(t, `{{.Report.TotalSecretsFound}} secrets pushed by {{.Pusher}}
+{{- range .Refs}} to {{.RefName}}{{end}}
+{{range .Commits}}{{range .Displayed}}{{range .Displayed}}{{.ID}} {{.RuleID}}
+{{end}}{{end}}{{end}}`))
+		assert.NoError(t, err)
+
+		text, _, err := PreReceiveReport(report, info, Options{Template: tmpl, Pusher: "bob", Refs: refs})
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(text), "\n")
+		assert.Equal(t, "8 secrets pushed by bob to refs/heads/main", lines[0])
+		assert.Len(t, lines, 9)
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		_, err := LoadTemplate(writeTemplate(t, `{{define "footer"}}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := LoadTemplate(writeTemplate(t, `{{.Nope}}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("unknown field in a finding", func(t *testing.T) {
+		_, err := LoadTemplate(writeTemplate(t, `{{define "finding"}}{{.Nope}}{{end}}`))
+		assert.ErrorContains(t, err, "Nope")
+	})
+
+	t.Run("unknown field in a range body", func(t *testing.T) {
+		_, err := LoadTemplate(writeTemplate(t, `{{range .Commits}}{{.Hash}}{{end}}`))
+		assert.ErrorContains(t, err, "Hash")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := LoadTemplate(filepath.Join(t.TempDir(), "missing.tmpl"))
+		assert.Error(t, err)
+	})
+}
+
+func TestPreReceiveReportWarnFooter(t *testing.T) {
+	report, info := makeReport(1, 1, 1)
+	text, _, err := PreReceiveReport(report, info, Options{Decision: severity.Warn})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "The push was accepted")
+	assert.NotContains(t, text, "prevented you from push secrets")
+}
+
+func TestPrePushTemplate(t *testing.T) {
+	report, info := makeReport(1, 1, 1)
+	text, _, err := PreReceiveReport(report, info, Options{Template: PrePushTemplate(), Decision: severity.Block})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "Detected 1 secret across 1 commit")
+	assert.Contains(t, text, "The pre-push secret scanner stopped the push")
+	assert.NotContains(t, text, "pre-receive hook")
+
+	text, _, err = PreReceiveReport(report, info, Options{Decision: severity.Block})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "A pre-receive hook set server side prevented you from push secrets.")
+}
+
+func TestPatchTemplate(t *testing.T) {
+	report, info := makeReport(2, 1, 2)
+	patch := info["COMMIT000"]
+	patch.Subject = "[PATCH 1/2] Add the key"
+	info["COMMIT000"] = patch
+	info["COMMIT001"] = CommitInfo{}
+
+	text, jsonReport, err := PreReceiveReport(report, info, Options{Template: PatchTemplate(), Decision: severity.Block})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "Detected 2 secrets across 2 patches")
+	assert.Contains(t, text, "(COMMIT000): 1 secret in 1 file\nSubject: [PATCH 1/2] Add the key\nAuthor: "+patch.Author+"\nDate: ")
+	assert.Contains(t, text, "(COMMIT001): 1 secret in 1 file\n\n", "a patch without headers has no subject, author or date")
+	assert.Contains(t, text, "The patches contain secrets and should not be applied.")
+	assert.Contains(t, string(jsonReport), `"subject": "[PATCH 1/2] Add the key"`)
+	assert.Equal(t, 1, strings.Count(string(jsonReport), `"subject"`))
+}
+
+func TestPreReceiveReportValidity(t *testing.T) {
+	report, info := makeReport(1, 1, 1)
+	text, _, err := PreReceiveReport(report, info, Options{})
+	assert.NoError(t, err)
+	assert.NotContains(t, text, "Validity")
+
+	for _, list := range report.Results {
+		for _, secret := range list {
+			secret.ValidationStatus = secrets.ValidResult
+		}
+	}
+	text, jsonBlob, err := PreReceiveReport(report, info, Options{})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "        Severity        : Low\n        Validity        : Confirmed live\n        Location")
+	assert.Contains(t, string(jsonBlob), `"validity": "live"`)
+}
+
+func TestPreReceiveReportSuppressedInline(t *testing.T) {
+	report, info := makeReport(1, 1, 1)
+	text, jsonBlob, err := PreReceiveReport(report, info, Options{SuppressedInline: 2})
+	assert.NoError(t, err)
+	assert.Contains(t, text, "Detected 1 secret across 1 commit\n2 findings suppressed by inline allow-comments\n")
+	assert.Contains(t, string(jsonBlob), `"suppressed_inline": 2`)
+}
@@ -0,0 +1,104 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/zricethezav/gitleaks/v8/config"
+)
+
+const customRuleDescription = "Custom rule from configuration"
+
+// CustomRule is a detection rule defined in the hook configuration, applied next to the default rules.
+type CustomRule struct {
+	ID          string         `yaml:"id"`
+	Description string         `yaml:"description"`
+	Regex       string         `yaml:"regex"`
+	SecretGroup int            `yaml:"secret_group"`
+	Keywords    []string       `yaml:"keywords"`
+	Entropy     float64        `yaml:"entropy"`
+	Tags        []string       `yaml:"tags"`
+	Severity    severity.Level `yaml:"severity"`
+}
+
+// ValidateCustomRules checks that every rule has a unique ID, a valid regex and a known severity.
+// Severities are normalized in place.
+func ValidateCustomRules(customRules []CustomRule) error {
+	defaultIDs := make(map[string]struct{})
+	for _, rule := range (Selection{Entropy: true}).Available(nil) {
+		defaultIDs[strings.ToLower(rule.ID)] = struct{}{}
+	}
+
+	seen := make(map[string]struct{}, len(customRules))
+	for i := range customRules {
+		rule := &customRules[i]
+		if strings.TrimSpace(rule.ID) == "" {
+			return fmt.Errorf("custom rule #%d has no id", i+1)
+		}
+		key := strings.ToLower(rule.ID)
+		if _, exists := defaultIDs[key]; exists {
+			return fmt.Errorf("custom rule %s conflicts with a built-in rule", rule.ID)
+		}
+		if _, exists := seen[key]; exists {
+			return fmt.Errorf("custom rule %s is defined more than once", rule.ID)
+		}
+		seen[key] = struct{}{}
+
+		if _, err := Compile(*rule); err != nil {
+			return err
+		}
+		if rule.Severity != "" {
+			level, err := severity.Parse(string(rule.Severity))
+			if err != nil {
+				return fmt.Errorf("custom rule %s: %w", rule.ID, err)
+			}
+			rule.Severity = level
+		}
+	}
+	return nil
+}
+
+// Compile converts the rule into a gitleaks rule, the format used by the 2ms detection engine.
+func Compile(r CustomRule) (config.Rule, error) {
+	if r.Regex == "" {
+		return config.Rule{}, fmt.Errorf("custom rule %s has no regex", r.ID)
+	}
+	regex, err := regexp.Compile(r.Regex)
+	if err != nil {
+		return config.Rule{}, fmt.Errorf("custom rule %s has an invalid regex: %w", r.ID, err)
+	}
+	if r.SecretGroup < 0 || r.SecretGroup > regex.NumSubexp() {
+		return config.Rule{}, fmt.Errorf("custom rule %s: secret_group %d does not exist in the regex", r.ID, r.SecretGroup)
+	}
+	if r.Entropy < 0 {
+		return config.Rule{}, fmt.Errorf("custom rule %s: entropy must not be negative", r.ID)
+	}
+
+	description := r.Description
+	if description == "" {
+		description = customRuleDescription
+	}
+	keywords := make([]string, 0, len(r.Keywords))
+	for _, keyword := range r.Keywords {
+		keywords = append(keywords, strings.ToLower(keyword))
+	}
+	return config.Rule{
+		Description: description,
+		RuleID:      r.ID,
+		Regex:       regex,
+		SecretGroup: r.SecretGroup,
+		Entropy:     r.Entropy,
+		Keywords:    keywords,
+		Tags:        r.Tags,
+	}, nil
+}
+
+// Score returns the CVSS score assigned to the findings of the rule.
+func (r CustomRule) Score() float64 {
+	if r.Severity == "" {
+		return severity.High.Score()
+	}
+	return r.Severity.Score()
+}
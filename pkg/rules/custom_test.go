@@ -0,0 +1,53 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCustomRules(t *testing.T) {
+	acme := CustomRule{ID: "acme-key", Regex: `acme_[a-z0-9]{16}`, Keywords: []string{"acme_"}, Severity: "Critical"}
+
+	tests := []struct {
+		name    string
+		rules   []CustomRule
+		wantErr bool
+	}{
+		{"no rules", nil, false},
+		{"valid rule", []CustomRule{acme}, false},
+		{"missing id", []CustomRule{{Regex: `acme`}}, true},
+		{"missing regex", []CustomRule{{ID: "acme-key"}}, true},
+		{"invalid regex", []CustomRule{{ID: "acme-key", Regex: `acme_(`}}, true},
+		{"unknown secret group", []CustomRule{{ID: "acme-key", Regex: `acme_(x)`, SecretGroup: 2}}, true},
+		{"negative entropy", []CustomRule{{ID: "acme-key", Regex: `acme`, Entropy: -1}}, true},
+		{"unknown severity", []CustomRule{{ID: "acme-key", Regex: `acme`, Severity: "urgent"}}, true},
+		{"duplicate id", []CustomRule{acme, {ID: "ACME-KEY", Regex: `acme`}}, true},
+		{"default rule id", []CustomRule{{ID: "github-pat", Regex: `acme`}}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateCustomRules(tc.rules)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	customRules := []CustomRule{acme}
+	assert.NoError(t, ValidateCustomRules(customRules))
+	assert.Equal(t, severity.Critical, customRules[0].Severity)
+}
+
+func TestCompile(t *testing.T) {
+	rule, err := Compile(CustomRule{ID: "acme-key", Regex: `acme_[a-z0-9]{16}`, Keywords: []string{"ACME_"}, Entropy: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, "acme-key", rule.RuleID)
+	assert.Equal(t, customRuleDescription, rule.Description)
+	assert.Equal(t, []string{"acme_"}, rule.Keywords)
+	assert.Equal(t, 3.0, rule.Entropy)
+	assert.True(t, rule.Regex.MatchString("acme_0123456789abcdef"))
+}
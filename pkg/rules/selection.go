@@ -0,0 +1,139 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+
+	twomsrules "github.com/checkmarx/2ms/v3/engine/rules"
+)
+
+// EntropyRuleID is the rule ID of the findings reported by the generic high-entropy detector.
+const EntropyRuleID = "generic-high-entropy"
+
+// Kind tells which detector applies a rule.
+type Kind int
+
+const (
+	// KindDefault is a default 2ms rule.
+	KindDefault Kind = iota
+	// KindEntropy is the generic high-entropy detector.
+	KindEntropy
+	// KindCustom is a custom rule from the configuration.
+	KindCustom
+)
+
+// Info identifies a rule available to the scanner.
+type Info struct {
+	ID   string
+	Tags []string
+	Kind Kind
+}
+
+// Selection narrows the rules applied by a scan.
+//
+// When SelectRules and SelectTags are both empty every rule is selected; otherwise a rule is selected
+// when its ID is in SelectRules or one of its tags is in SelectTags. Selected rules are then dropped
+// when IgnoreRules holds their ID or one of their tags, or when IgnoreTags holds one of their tags.
+type Selection struct {
+	SelectRules []string
+	SelectTags  []string
+	IgnoreRules []string
+	IgnoreTags  []string
+	// Entropy makes the generic high-entropy detector available.
+	Entropy bool
+}
+
+// Available returns the default rules, the entropy detector when enabled, and the custom rules.
+func (s Selection) Available(customRules []CustomRule) []Info {
+	defaults := *twomsrules.GetDefaultRules()
+	available := make([]Info, 0, len(defaults)+len(customRules)+1)
+	for _, rule := range defaults {
+		available = append(available, Info{ID: rule.Rule.RuleID, Tags: rule.Tags})
+	}
+	if s.Entropy {
+		available = append(available, Info{ID: EntropyRuleID, Tags: []string{"entropy"}, Kind: KindEntropy})
+	}
+	for _, rule := range customRules {
+		available = append(available, Info{ID: rule.ID, Tags: rule.Tags, Kind: KindCustom})
+	}
+	return available
+}
+
+// Select returns the rules kept by the selection, in the order of Available.
+func (s Selection) Select(customRules []CustomRule) []Info {
+	var selected []Info
+	for _, rule := range s.Available(customRules) {
+		if s.selects(rule) && !s.ignores(rule) {
+			selected = append(selected, rule)
+		}
+	}
+	return selected
+}
+
+// SelectedIDs returns the IDs of the rules kept by the selection.
+func (s Selection) SelectedIDs(customRules []CustomRule) []string {
+	var ids []string
+	for _, rule := range s.Select(customRules) {
+		ids = append(ids, rule.ID)
+	}
+	return ids
+}
+
+// Validate checks that the selected rule IDs and tags exist and that at least one rule remains selected.
+func (s Selection) Validate(customRules []CustomRule) error {
+	available := s.Available(customRules)
+	ids := make(map[string]struct{}, len(available))
+	tags := make(map[string]struct{})
+	for _, rule := range available {
+		ids[strings.ToLower(rule.ID)] = struct{}{}
+		for _, tag := range rule.Tags {
+			tags[strings.ToLower(tag)] = struct{}{}
+		}
+	}
+
+	for _, id := range s.SelectRules {
+		if _, ok := ids[strings.ToLower(id)]; !ok {
+			return fmt.Errorf("selected rule %s does not exist", id)
+		}
+	}
+	for _, tag := range s.SelectTags {
+		if _, ok := tags[strings.ToLower(tag)]; !ok {
+			return fmt.Errorf("selected tag %s is not used by any rule", tag)
+		}
+	}
+	if len(s.Select(customRules)) == 0 {
+		return fmt.Errorf("the rule selection does not leave any rule to apply")
+	}
+	return nil
+}
+
+func (s Selection) selects(rule Info) bool {
+	if len(s.SelectRules) == 0 && len(s.SelectTags) == 0 {
+		return true
+	}
+	return containsFold(s.SelectRules, rule.ID) || anyContainsFold(s.SelectTags, rule.Tags)
+}
+
+func (s Selection) ignores(rule Info) bool {
+	return containsFold(s.IgnoreRules, rule.ID) ||
+		anyContainsFold(s.IgnoreRules, rule.Tags) ||
+		anyContainsFold(s.IgnoreTags, rule.Tags)
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func anyContainsFold(list, values []string) bool {
+	for _, value := range values {
+		if containsFold(list, value) {
+			return true
+		}
+	}
+	return false
+}
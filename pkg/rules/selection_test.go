@@ -0,0 +1,68 @@
+package rules
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectionSelect(t *testing.T) {
+	customRules := []CustomRule{
+		{ID: "acme-key", Regex: `acme`, Tags: []string{"internal", "api-key"}},
+		{ID: "acme-password", Regex: `acme`, Tags: []string{"internal", "password"}},
+	}
+	total := len(Selection{}.Available(customRules))
+
+	tests := []struct {
+		name      string
+		selection Selection
+		contains  []string
+		excludes  []string
+		count     int
+	}{
+		{"everything by default", Selection{}, []string{"github-pat", "acme-key"}, nil, total},
+		{"select by id", Selection{SelectRules: []string{"GITHUB-PAT", "acme-key"}}, []string{"github-pat", "acme-key"}, []string{"acme-password"}, 2},
+		{"select by tag", Selection{SelectTags: []string{"internal"}}, []string{"acme-key", "acme-password"}, []string{"github-pat"}, 2},
+		{"ignore tag after select", Selection{SelectTags: []string{"internal"}, IgnoreTags: []string{"password"}}, []string{"acme-key"}, []string{"acme-password"}, 1},
+		{"entropy is opt-in", Selection{SelectTags: []string{"entropy"}, Entropy: true}, []string{EntropyRuleID}, nil, 1},
+		{"ignore rule by id or tag", Selection{SelectTags: []string{"internal"}, IgnoreRules: []string{"api-key"}}, []string{"acme-password"}, []string{"acme-key"}, 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ids := tc.selection.SelectedIDs(customRules)
+			assert.Len(t, ids, tc.count)
+			for _, id := range tc.contains {
+				assert.Contains(t, ids, id)
+			}
+			for _, id := range tc.excludes {
+				assert.NotContains(t, ids, id)
+			}
+		})
+	}
+}
+
+func TestSelectionValidate(t *testing.T) {
+	customRules := []CustomRule{{ID: "acme-key", Regex: `acme`, Tags: []string{"internal"}}}
+
+	tests := []struct {
+		name      string
+		selection Selection
+		wantErr   bool
+	}{
+		{"empty", Selection{}, false},
+		{"known rule and tag", Selection{SelectRules: []string{"github-pat"}, SelectTags: []string{"internal"}}, false},
+		{"unknown rule", Selection{SelectRules: []string{"nope"}}, true},
+		{"unknown tag", Selection{SelectTags: []string{"nope"}}, true},
+		{"nothing left", Selection{SelectTags: []string{"internal"}, IgnoreTags: []string{"internal"}}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.selection.Validate(customRules)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/rules"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+	"github.com/zricethezav/gitleaks/v8/config"
+	"github.com/zricethezav/gitleaks/v8/detect"
+)
+
+// customRulesDetector applies the custom rules of the configuration with the gitleaks engine used by 2ms.
+type customRulesDetector struct {
+	detector *detect.Detector
+	scores   map[string]float64
+}
+
+func newCustomRulesDetector(customRules []rules.CustomRule) (*customRulesDetector, error) {
+	cfg := config.Config{Rules: make(map[string]config.Rule, len(customRules))}
+	scores := make(map[string]float64, len(customRules))
+	for _, customRule := range customRules {
+		rule, err := rules.Compile(customRule)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Rules[rule.RuleID] = rule
+		cfg.Keywords = append(cfg.Keywords, rule.Keywords...)
+		scores[rule.RuleID] = customRule.Score()
+	}
+	return &customRulesDetector{detector: detect.NewDetector(cfg), scores: scores}, nil
+}
+
+func (d *customRulesDetector) detect(item twoms.ScanItem) []*secrets.Secret {
+	if item.Content == nil || *item.Content == "" {
+		return nil
+	}
+
+	// Like 2ms, terminate the content so that rules anchored on a line end match the last line.
+	findings := d.detector.Detect(detect.Fragment{Raw: *item.Content + "\n", FilePath: item.Source})
+	result := make([]*secrets.Secret, 0, len(findings))
+	for _, finding := range findings {
+		startColumn, endColumn := finding.StartColumn, finding.EndColumn
+		if strings.HasPrefix(finding.Line, "\n") {
+			startColumn--
+			endColumn--
+		}
+		result = append(result, &secrets.Secret{
+			ID:              findingID(item.ID, finding.RuleID, finding.Secret),
+			Source:          item.Source,
+			RuleID:          finding.RuleID,
+			StartLine:       finding.StartLine,
+			EndLine:         finding.EndLine,
+			StartColumn:     startColumn,
+			EndColumn:       endColumn,
+			Value:           finding.Secret,
+			LineContent:     lineContent(finding.Line, finding.Secret),
+			RuleDescription: finding.Description,
+			CvssScore:       d.scores[finding.RuleID],
+		})
+	}
+	return result
+}
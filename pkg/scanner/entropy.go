@@ -0,0 +1,207 @@
+package scanner
+
+import (
+	"fmt"
+	"math"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/rules"
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+)
+
+const (
+	entropyRuleDescription = "High-entropy string that may be a credential"
+
+	defaultEntropyMinLength       = 20
+	defaultEntropyBase64Threshold = 4.5
+	defaultEntropyHexThreshold    = 3.0
+
+	// credentialContextBoost is added to the entropy of a string assigned to a credential-like name.
+	credentialContextBoost = 0.5
+	// lengthMargin keeps the threshold of short strings this far below the highest entropy a string of
+	// their length can reach, log2 of its length, so that strings down to the minimum length can be
+	// reported.
+	lengthMargin = 0.4
+	// lowConfidencePenalty is subtracted from the entropy of strings that look like hashes,
+	// UUIDs or lockfile checksums.
+	lowConfidencePenalty = 1.5
+)
+
+// EntropyConfig configures the generic high-entropy string detector.
+type EntropyConfig struct {
+	Enabled         bool    `yaml:"enabled"`
+	MinLength       int     `yaml:"min_length"`
+	Base64Threshold float64 `yaml:"base64_threshold"`
+	HexThreshold    float64 `yaml:"hex_threshold"`
+}
+
+// Validate checks the configuration and fills in the defaults of the unset values.
+func (c *EntropyConfig) Validate() error {
+	if c.MinLength < 0 || c.Base64Threshold < 0 || c.HexThreshold < 0 {
+		return fmt.Errorf("entropy settings must not be negative")
+	}
+	if c.Base64Threshold > 6 || c.HexThreshold > 4 {
+		return fmt.Errorf("entropy thresholds must not exceed the maximum entropy of their charset (6 for base64, 4 for hex)")
+	}
+	if c.MinLength == 0 {
+		c.MinLength = defaultEntropyMinLength
+	}
+	if c.Base64Threshold == 0 {
+		c.Base64Threshold = defaultEntropyBase64Threshold
+	}
+	if c.HexThreshold == 0 {
+		c.HexThreshold = defaultEntropyHexThreshold
+	}
+	return nil
+}
+
+var (
+	entropyCandidateRegex = regexp.MustCompile(`[A-Za-z0-9+/_\-]+={0,2}`)
+	hexRegex              = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+	uuidRegex             = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	// credentialContextRegex matches a credential-like key or variable name right before an assignment.
+	credentialContextRegex = regexp.MustCompile(`(?i)(pass(word|wd)?|pwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|auth|credential|client[_-]?secret)[a-z0-9_\-.]*["']?\s*(:=|=>|=|:)\s*["']?$`)
+	// checksumContextRegex matches lines holding dependency checksums.
+	checksumContextRegex = regexp.MustCompile(`(?i)(integrity|checksum|sha1|sha256|sha512|shasum|resolved|h1:)`)
+)
+
+var lockfiles = map[string]struct{}{
+	"package-lock.json":   {},
+	"npm-shrinkwrap.json": {},
+	"yarn.lock":           {},
+	"pnpm-lock.yaml":      {},
+	"go.sum":              {},
+	"Cargo.lock":          {},
+	"Gemfile.lock":        {},
+	"poetry.lock":         {},
+	"Pipfile.lock":        {},
+	"composer.lock":       {},
+	"packages.lock.json":  {},
+}
+
+// entropyDetector reports random-looking strings that no rule matched.
+type entropyDetector struct {
+	config EntropyConfig
+}
+
+func newEntropyDetector(config EntropyConfig) *entropyDetector {
+	return &entropyDetector{config: config}
+}
+
+func (d *entropyDetector) detect(item twoms.ScanItem) []*secrets.Secret {
+	if item.Content == nil {
+		return nil
+	}
+	lockfile := isLockfile(item.Source)
+
+	var result []*secrets.Secret
+	for lineNumber, line := range strings.Split(*item.Content, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		for _, match := range entropyCandidateRegex.FindAllStringIndex(line, -1) {
+			value := strings.Trim(line[match[0]:match[1]], "=-_")
+			if len(value) < d.config.MinLength {
+				continue
+			}
+			start := match[0] + strings.Index(line[match[0]:match[1]], value)
+
+			credentialContext := credentialContextRegex.MatchString(line[:start])
+			if !d.isSecret(value, line, credentialContext, lockfile) {
+				continue
+			}
+
+			score := severity.Medium.Score()
+			if credentialContext {
+				score = severity.High.Score()
+			}
+			result = append(result, &secrets.Secret{
+				ID:              findingID(item.ID, rules.EntropyRuleID, value),
+				Source:          item.Source,
+				RuleID:          rules.EntropyRuleID,
+				StartLine:       lineNumber,
+				EndLine:         lineNumber,
+				StartColumn:     start + 1,
+				EndColumn:       start + len(value),
+				Value:           value,
+				LineContent:     lineContent(line, value),
+				RuleDescription: entropyRuleDescription,
+				CvssScore:       score,
+			})
+		}
+	}
+	return result
+}
+
+// isSecret scores the entropy of value against the threshold of its charset, adjusted by its context.
+func (d *entropyDetector) isSecret(value, line string, credentialContext, lockfile bool) bool {
+	if !hasLettersAndDigits(value) {
+		return false
+	}
+
+	threshold, charsetSize := d.config.Base64Threshold, 64
+	isHex := hexRegex.MatchString(value)
+	if isHex {
+		threshold, charsetSize = d.config.HexThreshold, 16
+	}
+	threshold = math.Min(threshold, math.Log2(float64(min(len(value), charsetSize)))-lengthMargin)
+
+	score := shannonEntropy(value)
+	if credentialContext {
+		score += credentialContextBoost
+	}
+	if uuidRegex.MatchString(value) || (isHex && isHashLength(len(value)) && !credentialContext) {
+		score -= lowConfidencePenalty
+	}
+	if lockfile || checksumContextRegex.MatchString(line) {
+		score -= lowConfidencePenalty
+	}
+	return score >= threshold
+}
+
+// shannonEntropy returns the Shannon entropy of value in bits per character.
+func shannonEntropy(value string) float64 {
+	if value == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range value {
+		counts[r]++
+	}
+	var entropy float64
+	length := float64(len(value))
+	for _, count := range counts {
+		p := float64(count) / length
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func hasLettersAndDigits(value string) bool {
+	hasLetter, hasDigit := false, false
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z'):
+			hasLetter = true
+		}
+	}
+	return hasLetter && hasDigit
+}
+
+// isHashLength reports whether length matches the hex digest of MD5, SHA-1, SHA-256 or SHA-512.
+func isHashLength(length int) bool {
+	return length == 32 || length == 40 || length == 64 || length == 128
+}
+
+func isLockfile(source string) bool {
+	// Pre-receive sources are formatted as "<change>:<commit>:<file>".
+	if idx := strings.LastIndex(source, ":"); idx >= 0 {
+		source = source[idx+1:]
+	}
+	_, ok := lockfiles[path.Base(source)]
+	return ok
+}
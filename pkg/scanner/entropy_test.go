@@ -0,0 +1,143 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/rules"
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShannonEntropy(t *testing.T) {
+	assert.Equal(t, 0.0, shannonEntropy(""))
+	assert.Equal(t, 0.0, shannonEntropy("aaaa"))
+	assert.Equal(t, 1.0, shannonEntropy("abab"))
+	assert.Equal(t, 4.0, shannonEntropy("0123456789abcdef"))
+}
+
+func TestEntropyThresholdOfShortStrings(t *testing.T) {
+	cfg := EntropyConfig{Enabled: true}
+	assert.NoError(t, cfg.Validate())
+	d := newEntropyDetector(cfg)
+	// 20 distinct characters reach the highest entropy of their length, log2(20) ≈ 4.32, below the
+	// base64 threshold.
+	value := "Xk9vP2qL7mN4rT8w1zY3"
+	assert.Less(t, shannonEntropy(value), cfg.Base64Threshold)
+	assert.True(t, d.isSecret(value, value, false, false))
+	assert.False(t, d.isSecret("Xk9vP2qLXk9vP2qLXk9v", value, false, false))
+	shorter := value[:19]
+	assert.Empty(t, d.detect(twoms.ScanItem{Content: &shorter}), "shorter strings are not reported")
+}
+
+func TestEntropyConfigValidate(t *testing.T) {
+	cfg := EntropyConfig{Enabled: true}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, EntropyConfig{
+		Enabled:         true,
+		MinLength:       defaultEntropyMinLength,
+		Base64Threshold: defaultEntropyBase64Threshold,
+		HexThreshold:    defaultEntropyHexThreshold,
+	}, cfg)
+
+	assert.Error(t, (&EntropyConfig{MinLength: -1}).Validate())
+	assert.Error(t, (&EntropyConfig{HexThreshold: 4.5}).Validate())
+}
+
+func TestEntropyDetector(t *testing.T) {
+	cfg := EntropyConfig{Enabled: true}
+	assert.NoError(t, cfg.Validate())
+	d := newEntropyDetector(cfg)
+
+	tests := []struct {
+		name     string
+		source   string
+		content  string
+		expected []string
+	}{
+		{"random base64 string", "app.yaml", "value: 9fKx2LmQ7vR4tY8wZ1cN6bH3jP5sD0gAqE", []string{"9fKx2LmQ7vR4tY8wZ1cN6bH3jP5sD0gAqE"}},
+		{"short string in credential context", "app.env", "DB_PASSWORD=Xk9vP2qL7mN4rT8w1zY3", []string{"Xk9vP2qL7mN4rT8w1zY3"}},
+		{"random string of the minimum length", "app.env", "DB_NAME=Xk9vP2qL7mN4rT8w1zY3", []string{"Xk9vP2qL7mN4rT8w1zY3"}},
+		{"identifier of the minimum length", "main.go", "new(AbstractFactoryBean2)", nil},
+		{"repeated string of the minimum length", "app.env", "DB_NAME=Xk9vP2qLXk9vP2qLXk9v", nil},
+		{"hex token in credential context", "app.env", "api_key: \"4f9a7c2e1b8d6f3a5c0e9b7d2a4f6c8e\"", []string{"4f9a7c2e1b8d6f3a5c0e9b7d2a4f6c8e"}},
+		{"sha1 hash", "notes.md", "fixed in 4f9a7c2e1b8d6f3a5c0e9b7d2a4f6c8e1b8d6f3a", nil},
+		{"uuid", "app.yaml", "id: 3f2b8c4e-9a1d-4e7f-b6c5-2d8a9e1f0b3c", nil},
+		{"lockfile checksum", "Added:abc:web/package-lock.json", "\"integrity\": \"sha512-9fKx2LmQ7vR4tY8wZ1cN6bH3jP5sD0gAqE\"", nil},
+		{"go.sum entry", "go.sum", "github.com/acme/lib v1.0.0 h1:9fKx2LmQ7vR4tY8wZ1cN6bH3jP5sD0gAqE=", nil},
+		{"words", "main.go", "func processIncomingRequestHandlerFactory() {}", nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			content := tc.content
+			var values []string
+			for _, secret := range d.detect(twoms.ScanItem{Content: &content, ID: "hooks-" + tc.source, Source: tc.source}) {
+				values = append(values, secret.Value)
+				assert.Equal(t, rules.EntropyRuleID, secret.RuleID)
+				assert.Equal(t, secret.Value, content[secret.StartColumn-1:secret.EndColumn])
+			}
+			assert.Equal(t, tc.expected, values)
+		})
+	}
+}
+
+func TestScanDynamicEntropy(t *testing.T) {
+	content := "first line\nowner " + acmeKey + "\nvalue: 9fKx2LmQ7vR4tY8wZ1cN6bH3jP5sD0gAqE\nDB_PASSWORD=Xk9vP2qL7mN4rT8w1zY3\n"
+	config := Config{CustomRules: []rules.CustomRule{acmeRule}, Entropy: EntropyConfig{Enabled: true}}
+	assert.NoError(t, config.Entropy.Validate())
+
+	report := scan(t, config, content)
+	var found []string
+	for _, list := range report.Results {
+		for _, secret := range list {
+			found = append(found, secret.RuleID+":"+secret.Value)
+			if secret.RuleID == rules.EntropyRuleID {
+				assert.Equal(t, 2, secret.StartLine)
+				assert.Equal(t, severity.Medium.Score(), secret.CvssScore)
+			}
+		}
+	}
+	// Strings matched by a rule are not reported again by the entropy detector.
+	assert.ElementsMatch(t, []string{
+		acmeRule.ID + ":" + acmeKey,
+		rules.EntropyRuleID + ":9fKx2LmQ7vR4tY8wZ1cN6bH3jP5sD0gAqE",
+		"generic-api-key:Xk9vP2qL7mN4rT8w1zY3",
+	}, found)
+	assert.Equal(t, 3, report.TotalSecretsFound)
+
+	config.Selection = rules.Selection{IgnoreRules: []string{rules.EntropyRuleID}}
+	report = scan(t, config, content)
+	assert.Equal(t, 2, report.TotalSecretsFound)
+}
+
+func TestNewEntropySelection(t *testing.T) {
+	s, err := New(Config{Entropy: EntropyConfig{Enabled: true}})
+	assert.NoError(t, err)
+	assert.True(t, s.config.Selection.Entropy)
+}
+
+func TestOverlapsAny(t *testing.T) {
+	results := map[string][]*secrets.Secret{
+		"rule":    {{RuleID: "generic-api-key", Source: "a.txt", StartLine: 2, EndLine: 2, Value: "KEY=Xk9vP2qL7mN4"}},
+		"entropy": {{RuleID: rules.EntropyRuleID, Source: "b.txt", StartLine: 2, EndLine: 2, Value: "Xk9vP2qL7mN4"}},
+	}
+	index := bySource(results)
+	assert.Len(t, index, 1, "entropy findings are not indexed")
+
+	tests := []struct {
+		name   string
+		secret *secrets.Secret
+		want   bool
+	}{
+		{"same source and line", &secrets.Secret{Source: "a.txt", StartLine: 2, Value: "Xk9vP2qL7mN4"}, true},
+		{"other line", &secrets.Secret{Source: "a.txt", StartLine: 3, Value: "Xk9vP2qL7mN4"}, false},
+		{"other source", &secrets.Secret{Source: "b.txt", StartLine: 2, Value: "Xk9vP2qL7mN4"}, false},
+		{"other value", &secrets.Secret{Source: "a.txt", StartLine: 2, Value: "9fKx2LmQ7vR4"}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, overlapsAny(tc.secret, index[tc.secret.Source]))
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package scanner
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+)
+
+// ItemScanner scans the items received on itemsCh until it is closed. Scanner implements it; tests
+// replace it to inject failures.
+type ItemScanner interface {
+	ScanDynamic(itemsCh <-chan twoms.ScanItem) (*reporting.Report, error)
+}
+
+// Producer sends the items to scan. It must stop and return when ctx is done, which happens when the
+// scan is canceled or the scanner fails; Send does both.
+type Producer func(ctx context.Context, itemsCh chan<- twoms.ScanItem) error
+
+// Run scans the items sent by produce. The scan stops at the first error of the scanner or of the
+// producer, or when ctx is done, and the error is returned. The items channel is always closed, so
+// the scanner ends, and Run returns only once the producer has returned.
+func Run(ctx context.Context, scanner ItemScanner, produce Producer) (*reporting.Report, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	itemsCh := make(chan twoms.ScanItem)
+	type result struct {
+		report *reporting.Report
+		err    error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		report, err := scanner.ScanDynamic(itemsCh)
+		if err != nil {
+			// Stop the producer, which may be blocked on a send that nothing receives anymore.
+			cancel(err)
+		}
+		resultCh <- result{report, err}
+	}()
+
+	produceErr := produce(ctx, itemsCh)
+	close(itemsCh)
+	res := <-resultCh
+
+	switch {
+	case res.err != nil:
+		return nil, res.err
+	case produceErr != nil:
+		return nil, produceErr
+	case ctx.Err() != nil:
+		return nil, context.Cause(ctx)
+	}
+	return res.report, nil
+}
+
+// Send sends the item to the scanner, unless ctx is done first.
+func Send(ctx context.Context, itemsCh chan<- twoms.ScanItem, item twoms.ScanItem) error {
+	select {
+	case itemsCh <- item:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// WithSignals returns a context that is canceled on SIGINT and SIGTERM, so that a hook stops its git
+// processes and reports the interruption instead of being killed midway. stop restores the default
+// handling of the signals.
+func WithSignals(ctx context.Context) (_ context.Context, stop context.CancelFunc) {
+	return signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+}
@@ -0,0 +1,117 @@
+package scanner
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Checkmarx/secret-detection/pkg/rules"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+var errScanner = errors.New("scanner failed")
+
+// fakeScanner reads the given number of items, all of them when negative, then fails or returns an
+// empty report.
+type fakeScanner struct {
+	reads int
+	fail  bool
+}
+
+func (s fakeScanner) ScanDynamic(itemsCh <-chan twoms.ScanItem) (*reporting.Report, error) {
+	for i := 0; s.reads < 0 || i < s.reads; i++ {
+		if _, ok := <-itemsCh; !ok {
+			break
+		}
+	}
+	if s.fail {
+		return nil, errScanner
+	}
+	return reporting.Init(), nil
+}
+
+// endlessProducer sends items until the send fails.
+func endlessProducer(ctx context.Context, itemsCh chan<- twoms.ScanItem) error {
+	content := "content"
+	for {
+		if err := Send(ctx, itemsCh, twoms.ScanItem{Content: &content, ID: "id", Source: "source"}); err != nil {
+			return err
+		}
+	}
+}
+
+// runWithTimeout fails the test when Run does not return, which means the pipeline is stuck.
+func runWithTimeout(t *testing.T, ctx context.Context, scanner ItemScanner, produce Producer) (*reporting.Report, error) {
+	type result struct {
+		report *reporting.Report
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		report, err := Run(ctx, scanner, produce)
+		done <- result{report, err}
+	}()
+	select {
+	case res := <-done:
+		return res.report, res.err
+	case <-time.After(5 * time.Second):
+		t.Fatal("the scan pipeline did not terminate")
+		return nil, nil
+	}
+}
+
+func TestRun(t *testing.T) {
+	errProducer := errors.New("producer failed")
+	oneItem := func(ctx context.Context, itemsCh chan<- twoms.ScanItem) error {
+		content := "content"
+		return Send(ctx, itemsCh, twoms.ScanItem{Content: &content})
+	}
+
+	tests := []struct {
+		name    string
+		scanner ItemScanner
+		produce Producer
+		err     error
+	}{
+		{"success", fakeScanner{reads: -1}, oneItem, nil},
+		{"scanner fails before reading", fakeScanner{fail: true}, endlessProducer, errScanner},
+		{"scanner fails while reading", fakeScanner{reads: 3, fail: true}, endlessProducer, errScanner},
+		{"scanner fails after the items", fakeScanner{reads: -1, fail: true}, oneItem, errScanner},
+		{"producer fails", fakeScanner{reads: -1}, func(context.Context, chan<- twoms.ScanItem) error { return errProducer }, errProducer},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			report, err := runWithTimeout(t, context.Background(), tc.scanner, tc.produce)
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+				assert.Nil(t, report)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, report)
+		})
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	report, err := runWithTimeout(t, ctx, fakeScanner{reads: -1}, endlessProducer)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, report)
+}
+
+func TestRunCanceledScan(t *testing.T) {
+	s, err := New(Config{CustomRules: []rules.CustomRule{acmeRule}})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := runWithTimeout(t, ctx, s, endlessProducer)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, report)
+}
@@ -0,0 +1,215 @@
+package scanner
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"strings"
+
+	"github.com/Checkmarx/secret-detection/pkg/rules"
+	"github.com/checkmarx/2ms/v3/engine/linecontent"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+)
+
+// Config holds the settings of a secret scan.
+type Config struct {
+	IgnoreResultIds []string
+	Selection       rules.Selection
+	CustomRules     []rules.CustomRule
+	Entropy         EntropyConfig
+}
+
+// detector finds secrets in a scan item, next to the default 2ms rules.
+type detector interface {
+	detect(item twoms.ScanItem) []*secrets.Secret
+}
+
+// Scanner runs the 2ms scanner together with the detectors built from the hook configuration.
+type Scanner struct {
+	config    Config
+	detectors []detector
+	// defaultRulesUsed is false when the selection leaves out every default rule, in which case
+	// 2ms is not run at all.
+	defaultRulesUsed    bool
+	ignoredDefaultRules []string
+}
+
+// New creates a scanner for the given configuration. The custom rules are expected to be validated.
+func New(config Config) (*Scanner, error) {
+	config.Selection.Entropy = config.Entropy.Enabled
+	s := &Scanner{config: config}
+
+	selected := make(map[string]struct{})
+	for _, rule := range config.Selection.Select(config.CustomRules) {
+		selected[rule.ID] = struct{}{}
+	}
+
+	for _, rule := range config.Selection.Available(nil) {
+		if _, ok := selected[rule.ID]; !ok {
+			if rule.Kind == rules.KindDefault {
+				s.ignoredDefaultRules = append(s.ignoredDefaultRules, rule.ID)
+			}
+			continue
+		}
+		switch rule.Kind {
+		case rules.KindDefault:
+			s.defaultRulesUsed = true
+		case rules.KindEntropy:
+			s.detectors = append(s.detectors, newEntropyDetector(config.Entropy))
+		}
+	}
+
+	var customRules []rules.CustomRule
+	for _, rule := range config.CustomRules {
+		if _, ok := selected[rule.ID]; ok {
+			customRules = append(customRules, rule)
+		}
+	}
+	if len(customRules) > 0 {
+		d, err := newCustomRulesDetector(customRules)
+		if err != nil {
+			return nil, err
+		}
+		s.detectors = append(s.detectors, d)
+	}
+	return s, nil
+}
+
+// ScanDynamic scans the items received on itemsCh until it is closed and returns the merged report.
+func (s *Scanner) ScanDynamic(itemsCh <-chan twoms.ScanItem) (*reporting.Report, error) {
+	if !s.defaultRulesUsed {
+		return s.scanWithDetectors(itemsCh), nil
+	}
+
+	twomsConfig := twoms.ScanConfig{
+		IgnoreResultIds: s.config.IgnoreResultIds,
+		IgnoreRules:     s.ignoredDefaultRules,
+	}
+	if len(s.detectors) == 0 {
+		return twoms.NewScanner().ScanDynamic(itemsCh, twomsConfig)
+	}
+
+	twomsItemsCh := make(chan twoms.ScanItem)
+	detectorItemsCh := make(chan twoms.ScanItem, 1)
+	// twomsFailed is closed when 2ms returns early, so that the items it no longer reads are dropped.
+	twomsFailed := make(chan struct{})
+	go func() {
+		for item := range itemsCh {
+			select {
+			case twomsItemsCh <- item:
+			case <-twomsFailed:
+			}
+			detectorItemsCh <- item
+		}
+		close(twomsItemsCh)
+		close(detectorItemsCh)
+	}()
+
+	findingsCh := make(chan *reporting.Report, 1)
+	go func() {
+		findingsCh <- s.scanWithDetectors(detectorItemsCh)
+	}()
+
+	report, err := twoms.NewScanner().ScanDynamic(twomsItemsCh, twomsConfig)
+	if err != nil {
+		close(twomsFailed)
+		return nil, err
+	}
+	findings := <-findingsCh
+	ruleFindings := bySource(report.Results)
+	for id, list := range findings.Results {
+		for _, secret := range list {
+			if secret.RuleID == rules.EntropyRuleID && overlapsAny(secret, ruleFindings[secret.Source]) {
+				continue
+			}
+			report.Results[id] = append(report.Results[id], secret)
+			report.TotalSecretsFound++
+		}
+	}
+	return report, nil
+}
+
+// scanWithDetectors scans the items with the detectors only.
+func (s *Scanner) scanWithDetectors(itemsCh <-chan twoms.ScanItem) *reporting.Report {
+	report := reporting.Init()
+	ruleFindings := make(map[string][]*secrets.Secret)
+	for item := range itemsCh {
+		report.TotalItemsScanned++
+		var entropyFindings []*secrets.Secret
+		for _, d := range s.detectors {
+			for _, secret := range d.detect(item) {
+				if s.isIgnored(secret) {
+					continue
+				}
+				if secret.RuleID == rules.EntropyRuleID {
+					entropyFindings = append(entropyFindings, secret)
+					continue
+				}
+				report.Results[secret.ID] = append(report.Results[secret.ID], secret)
+				report.TotalSecretsFound++
+				ruleFindings[secret.Source] = append(ruleFindings[secret.Source], secret)
+			}
+		}
+		// The entropy detector only reports strings that no rule matched.
+		for _, secret := range entropyFindings {
+			if !overlapsAny(secret, ruleFindings[secret.Source]) {
+				report.Results[secret.ID] = append(report.Results[secret.ID], secret)
+				report.TotalSecretsFound++
+			}
+		}
+	}
+	return report
+}
+
+// bySource indexes the results found by rules other than the entropy rule by their source.
+func bySource(results map[string][]*secrets.Secret) map[string][]*secrets.Secret {
+	index := make(map[string][]*secrets.Secret)
+	for _, list := range results {
+		for _, secret := range list {
+			if secret.RuleID != rules.EntropyRuleID {
+				index[secret.Source] = append(index[secret.Source], secret)
+			}
+		}
+	}
+	return index
+}
+
+// overlapsAny reports whether a result found by another rule in the same source, on the same line,
+// shares its value with secret. Values are compared rather than columns, as the columns of 2ms results
+// span the whole rule match.
+func overlapsAny(secret *secrets.Secret, sameSource []*secrets.Secret) bool {
+	for _, other := range sameSource {
+		if other.StartLine <= secret.StartLine && secret.StartLine <= other.EndLine &&
+			(strings.Contains(secret.Value, other.Value) || strings.Contains(other.Value, secret.Value)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scanner) isIgnored(secret *secrets.Secret) bool {
+	for _, id := range s.config.IgnoreResultIds {
+		if secret.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// findingID returns the result ID of a finding, computed the same way as 2ms does so that
+// ignore_result_id entries work for every detector.
+func findingID(itemID, ruleID, value string) string {
+	sum := sha1.Sum([]byte(strings.Join([]string{itemID, ruleID, value}, "-")))
+	return fmt.Sprintf("%x", sum)
+}
+
+// lineContent returns the context around the secret shown in reports, as 2ms does.
+func lineContent(line, value string) string {
+	line = strings.ReplaceAll(strings.TrimPrefix(line, "\n"), "\r", "")
+	content, err := linecontent.GetLineContent(line, value)
+	if err != nil {
+		return ""
+	}
+	return content
+}
@@ -0,0 +1,112 @@
+package scanner
+
+import (
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/rules"
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	twoms "github.com/checkmarx/2ms/v3/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+const acmeKey = "acme_4f9a7c2e1b8d6f3a5c0e9b7d2a4f6c8e"
+
+var acmeRule = rules.CustomRule{
+	ID:       "acme-service-account-key",
+	Regex:    `acme_[a-f0-9]{32}`,
+	Keywords: []string{"acme_"},
+	Tags:     []string{"acme"},
+	Severity: severity.Critical,
+}
+
+func scan(t *testing.T, config Config, contents ...string) *reporting.Report {
+	s, err := New(config)
+	assert.NoError(t, err)
+
+	itemsCh := make(chan twoms.ScanItem)
+	go func() {
+		for i := range contents {
+			itemsCh <- twoms.ScanItem{Content: &contents[i], ID: "hooks-config.env", Source: "config.env"}
+		}
+		close(itemsCh)
+	}()
+	report, err := s.ScanDynamic(itemsCh)
+	assert.NoError(t, err)
+	return report
+}
+
+func TestScanDynamicCustomRules(t *testing.T) {
+	content := "first line\nowner " + acmeKey + "\n"
+	id := findingID("hooks-config.env", acmeRule.ID, acmeKey)
+
+	t.Run("custom rule finding", func(t *testing.T) {
+		report := scan(t, Config{CustomRules: []rules.CustomRule{acmeRule}}, content)
+		assert.Equal(t, 1, report.TotalSecretsFound)
+		if assert.Len(t, report.Results[id], 1) {
+			secret := report.Results[id][0]
+			assert.Equal(t, acmeRule.ID, secret.RuleID)
+			assert.Equal(t, acmeKey, secret.Value)
+			assert.Equal(t, "config.env", secret.Source)
+			assert.Equal(t, 1, secret.StartLine)
+			assert.Equal(t, severity.Critical.Score(), secret.CvssScore)
+			assert.Contains(t, secret.LineContent, acmeKey)
+		}
+	})
+
+	t.Run("keywords prefilter", func(t *testing.T) {
+		rule := acmeRule
+		rule.Keywords = []string{"unrelated"}
+		report := scan(t, Config{CustomRules: []rules.CustomRule{rule}}, content)
+		assert.Equal(t, 0, report.TotalSecretsFound)
+	})
+
+	t.Run("ignored result id", func(t *testing.T) {
+		report := scan(t, Config{CustomRules: []rules.CustomRule{acmeRule}, IgnoreResultIds: []string{id}}, content)
+		assert.Equal(t, 0, report.TotalSecretsFound)
+	})
+
+	t.Run("ignored rule tag", func(t *testing.T) {
+		report := scan(t, Config{CustomRules: []rules.CustomRule{acmeRule}, Selection: rules.Selection{IgnoreRules: []string{"acme"}}}, content)
+		assert.Equal(t, 0, report.TotalSecretsFound)
+	})
+
+	t.Run("without custom rules", func(t *testing.T) {
+		report := scan(t, Config{}, content)
+		assert.Equal(t, 0, report.TotalSecretsFound)
+	})
+}
+
+func TestScanDynamicRuleSelection(t *testing.T) {
+	content := "owner " + acmeKey + "\napi_key = \"Zx8vQ2mK7pL4nR9tW3yB6cF1hJ5sD0gA\"\n"
+	customRules := []rules.CustomRule{acmeRule}
+
+	ruleIDs := func(report *reporting.Report) []string {
+		var ids []string
+		for _, list := range report.Results {
+			for _, secret := range list {
+				ids = append(ids, secret.RuleID)
+			}
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name      string
+		selection rules.Selection
+		expected  []string
+	}{
+		{"all rules", rules.Selection{}, []string{acmeRule.ID, "generic-api-key"}},
+		{"select custom rule only", rules.Selection{SelectRules: []string{acmeRule.ID}}, []string{acmeRule.ID}},
+		{"select default rule only", rules.Selection{SelectRules: []string{"generic-api-key"}}, []string{"generic-api-key"}},
+		{"select tag", rules.Selection{SelectTags: []string{"acme"}}, []string{acmeRule.ID}},
+		{"ignore tag", rules.Selection{IgnoreTags: []string{"acme"}}, []string{"generic-api-key"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			report := scan(t, Config{Selection: tc.selection, CustomRules: customRules}, content)
+			assert.ElementsMatch(t, tc.expected, ruleIDs(report))
+			assert.Equal(t, len(tc.expected), report.TotalSecretsFound)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package severity
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+)
+
+// Level is the severity of a finding.
+type Level string
+
+const (
+	Low      Level = "low"
+	Medium   Level = "medium"
+	High     Level = "high"
+	Critical Level = "critical"
+)
+
+// Decision is the outcome of applying the severity thresholds to a scan report.
+type Decision string
+
+const (
+	// Pass means no finding reached the warning threshold.
+	Pass Decision = "pass"
+	// Warn means findings were reported but none reached the blocking threshold.
+	Warn Decision = "warn"
+	// Block means at least one finding reached the blocking threshold.
+	Block Decision = "block"
+)
+
+// levels lists the severities from the lowest to the highest.
+var levels = []Level{Low, Medium, High, Critical}
+
+// Config maps rules to severities and defines the thresholds used to decide whether a scan blocks.
+// Findings at or above BlockAt block, findings between WarnAt and BlockAt only warn, and findings
+// below WarnAt are ignored. Unset thresholds default to the lowest severity, so every finding blocks;
+// when only WarnAt is set, the findings above it block and those at WarnAt warn.
+type Config struct {
+	RuleSeverity map[string]Level `yaml:"rule_severity"`
+	BlockAt      Level            `yaml:"block_at"`
+	WarnAt       Level            `yaml:"warn_at"`
+}
+
+// Parse returns the Level named by s, case-insensitively.
+func Parse(s string) (Level, error) {
+	for _, level := range levels {
+		if strings.EqualFold(s, string(level)) {
+			return level, nil
+		}
+	}
+	return "", fmt.Errorf("unknown severity %q", s)
+}
+
+// FromScore maps a CVSS score to a severity, following the CVSS v3 qualitative ratings.
+func FromScore(score float64) Level {
+	switch {
+	case score >= 9.0:
+		return Critical
+	case score >= 7.0:
+		return High
+	case score >= 4.0:
+		return Medium
+	default:
+		return Low
+	}
+}
+
+// Score returns a representative CVSS score for the severity.
+func (l Level) Score() float64 {
+	switch l {
+	case Critical:
+		return 9.5
+	case High:
+		return 8.0
+	case Medium:
+		return 5.5
+	default:
+		return 2.0
+	}
+}
+
+// String returns the capitalized severity name, as displayed in the reports.
+func (l Level) String() string {
+	if l == "" {
+		return ""
+	}
+	return strings.ToUpper(string(l[:1])) + string(l[1:])
+}
+
+// rank orders the severities, an unset level ranking as the lowest.
+func (l Level) rank() int {
+	for i, level := range levels {
+		if level == l {
+			return i
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether l is at or above other.
+func (l Level) AtLeast(other Level) bool {
+	return l.rank() >= other.rank()
+}
+
+// Validate normalizes the configured severities and checks the thresholds.
+func (c *Config) Validate() error {
+	for ruleID, level := range c.RuleSeverity {
+		parsed, err := Parse(string(level))
+		if err != nil {
+			return fmt.Errorf("rule_severity for %s: %w", ruleID, err)
+		}
+		c.RuleSeverity[ruleID] = parsed
+	}
+	for _, threshold := range []*Level{&c.BlockAt, &c.WarnAt} {
+		if *threshold == "" {
+			continue
+		}
+		parsed, err := Parse(string(*threshold))
+		if err != nil {
+			return err
+		}
+		*threshold = parsed
+	}
+	if c.WarnAt != "" && c.BlockAt != "" && !c.BlockAt.AtLeast(c.WarnAt) {
+		return fmt.Errorf("warn_at (%s) must not be above block_at (%s)", c.WarnAt, c.BlockAt)
+	}
+	return nil
+}
+
+// Of returns the severity of the secret: the configured rule severity when present, otherwise the
+// severity derived from its CVSS score.
+func (c Config) Of(secret *secrets.Secret) Level {
+	if level, ok := c.RuleSeverity[secret.RuleID]; ok {
+		return level
+	}
+	return FromScore(secret.CvssScore)
+}
+
+// blocks reports whether findings of the severity block.
+func (c Config) blocks(level Level) bool {
+	if c.BlockAt == "" && c.WarnAt != "" {
+		return level.rank() > c.WarnAt.rank()
+	}
+	return level.AtLeast(c.BlockAt)
+}
+
+// Apply removes the findings below the warning threshold from the report and returns the decision
+// for the remaining ones. The CVSS score of the findings whose rule severity is configured is set to
+// the score of that severity, so that the reports agree with it.
+func (c Config) Apply(report *reporting.Report) Decision {
+	decision := Pass
+	total := 0
+	for id, list := range report.Results {
+		kept := list[:0]
+		for _, secret := range list {
+			level := c.Of(secret)
+			if !level.AtLeast(c.WarnAt) {
+				continue
+			}
+			if _, ok := c.RuleSeverity[secret.RuleID]; ok && FromScore(secret.CvssScore) != level {
+				secret.CvssScore = level.Score()
+			}
+			kept = append(kept, secret)
+			if c.blocks(level) {
+				decision = Block
+			} else if decision == Pass {
+				decision = Warn
+			}
+		}
+		if len(kept) == 0 {
+			delete(report.Results, id)
+			continue
+		}
+		report.Results[id] = kept
+		total += len(kept)
+	}
+	report.TotalSecretsFound = total
+	return decision
+}
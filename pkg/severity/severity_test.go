@@ -0,0 +1,117 @@
+package severity
+
+import (
+	"testing"
+
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromScore(t *testing.T) {
+	tests := []struct {
+		score    float64
+		expected Level
+	}{
+		{0, Low},
+		{3.9, Low},
+		{4.0, Medium},
+		{6.9, Medium},
+		{7.0, High},
+		{8.9, High},
+		{9.0, Critical},
+		{10, Critical},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.expected, FromScore(tc.score), "score %.1f", tc.score)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"empty", Config{}, false},
+		{"mixed case levels", Config{BlockAt: "HIGH", WarnAt: "Medium", RuleSeverity: map[string]Level{"github-pat": "Critical"}}, false},
+		{"unknown threshold", Config{BlockAt: "urgent"}, true},
+		{"unknown rule severity", Config{RuleSeverity: map[string]Level{"github-pat": "urgent"}}, true},
+		{"warn above block", Config{BlockAt: Medium, WarnAt: High}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	cfg := Config{BlockAt: "HIGH", RuleSeverity: map[string]Level{"github-pat": "Critical"}}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, High, cfg.BlockAt)
+	assert.Equal(t, Critical, cfg.RuleSeverity["github-pat"])
+}
+
+func TestConfigOf(t *testing.T) {
+	cfg := Config{RuleSeverity: map[string]Level{"generic-api-key": Low}}
+	assert.Equal(t, Low, cfg.Of(&secrets.Secret{RuleID: "generic-api-key", CvssScore: 9.5}))
+	assert.Equal(t, Critical, cfg.Of(&secrets.Secret{RuleID: "github-pat", CvssScore: 9.5}))
+}
+
+func TestConfigApply(t *testing.T) {
+	newReport := func() *reporting.Report {
+		return &reporting.Report{
+			TotalSecretsFound: 3,
+			Results: map[string][]*secrets.Secret{
+				"low":      {{ID: "low", RuleID: "r1", CvssScore: 2.0}},
+				"medium":   {{ID: "medium", RuleID: "r2", CvssScore: 5.0}},
+				"critical": {{ID: "critical", RuleID: "r3", CvssScore: 9.2}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		config       Config
+		wantDecision Decision
+		wantIDs      []string
+	}{
+		{"defaults block everything", Config{}, Block, []string{"low", "medium", "critical"}},
+		{"block high, warn medium", Config{BlockAt: High, WarnAt: Medium}, Block, []string{"medium", "critical"}},
+		{"critical downgraded to warn", Config{BlockAt: High, WarnAt: Medium, RuleSeverity: map[string]Level{"r3": Medium}}, Warn, []string{"medium", "critical"}},
+		{"everything below warn", Config{BlockAt: Critical, WarnAt: Critical, RuleSeverity: map[string]Level{"r3": Low}}, Pass, nil},
+		{"warn only blocks above it", Config{WarnAt: Medium}, Block, []string{"medium", "critical"}},
+		{"warn only at medium", Config{WarnAt: Medium, RuleSeverity: map[string]Level{"r3": Medium}}, Warn, []string{"medium", "critical"}},
+		{"warn only at critical never blocks", Config{WarnAt: Critical}, Warn, []string{"critical"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			report := newReport()
+			assert.Equal(t, tc.wantDecision, tc.config.Apply(report))
+			assert.Equal(t, len(tc.wantIDs), report.TotalSecretsFound)
+			assert.Len(t, report.Results, len(tc.wantIDs))
+			for _, id := range tc.wantIDs {
+				assert.Contains(t, report.Results, id)
+			}
+		})
+	}
+}
+
+func TestConfigApplyScore(t *testing.T) {
+	report := &reporting.Report{
+		TotalSecretsFound: 2,
+		Results: map[string][]*secrets.Secret{
+			"downgraded": {{ID: "downgraded", RuleID: "r1", CvssScore: 9.2}},
+			"scored":     {{ID: "scored", RuleID: "r2", CvssScore: 8.5}},
+		},
+	}
+	cfg := Config{RuleSeverity: map[string]Level{"r1": Medium, "r2": High}}
+	cfg.Apply(report)
+	assert.Equal(t, Medium.Score(), report.Results["downgraded"][0].CvssScore)
+	// A score that already matches the configured severity is kept.
+	assert.Equal(t, 8.5, report.Results["scored"][0].CvssScore)
+}
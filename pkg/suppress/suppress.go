@@ -0,0 +1,162 @@
+package suppress
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+)
+
+const (
+	// ignoreMarker suppresses every finding on the annotated line.
+	ignoreMarker = "cx:ignore-secret"
+	// allowMarker suppresses the findings on the annotated line, optionally only those of a rule:
+	// "cx-secret-allow rule=<rule id> reason=<free text>".
+	allowMarker = "cx-secret-allow"
+)
+
+var (
+	allowRuleRegex   = regexp.MustCompile(`\brule=["']?([^\s"']+)`)
+	allowReasonRegex = regexp.MustCompile(`\breason=(.*)$`)
+	// commentCloserRegex matches the end of block comments left after the reason.
+	commentCloserRegex = regexp.MustCompile(`\s*(\*/|-->|%>|#})\s*$`)
+)
+
+// Marker is an inline allow-comment found in the scanned content.
+type Marker struct {
+	// Rule restricts the marker to the findings of a rule; empty means any rule.
+	Rule   string
+	Reason string
+}
+
+// Allows reports whether the marker suppresses findings of the rule.
+func (m Marker) Allows(ruleID string) bool {
+	return m.Rule == "" || strings.EqualFold(m.Rule, ruleID)
+}
+
+// Parse returns the marker of the line, if it has one.
+func Parse(line string) (Marker, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	if strings.Contains(line, ignoreMarker) {
+		return Marker{}, true
+	}
+	idx := strings.Index(line, allowMarker)
+	if idx < 0 {
+		return Marker{}, false
+	}
+
+	var marker Marker
+	args := line[idx+len(allowMarker):]
+	if match := allowRuleRegex.FindStringSubmatch(args); match != nil {
+		marker.Rule = match[1]
+	}
+	if match := allowReasonRegex.FindStringSubmatch(args); match != nil {
+		reason := commentCloserRegex.ReplaceAllString(match[1], "")
+		marker.Reason = strings.Trim(strings.TrimSpace(reason), `"'`)
+	}
+	return marker, true
+}
+
+// Index records, for each scanned item, the markers that apply to its lines: those on the line and on
+// the line before it. Line numbers are the 0-based lines of the scanned content, as reported by the scanner.
+type Index map[string]map[int][]Marker
+
+// Add records that marker applies to the given line of the item.
+func (idx Index) Add(source string, line int, marker Marker) {
+	if idx[source] == nil {
+		idx[source] = make(map[int][]Marker)
+	}
+	idx[source][line] = append(idx[source][line], marker)
+}
+
+// Allows reports whether a marker of the line of the item suppresses findings of the rule.
+func (idx Index) Allows(source string, line int, ruleID string) bool {
+	for _, marker := range idx[source][line] {
+		if marker.Allows(ruleID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Merge adds the markers of another index, such as one filled by a concurrent parser.
+func (idx Index) Merge(other Index) {
+	for source, lines := range other {
+		idx[source] = lines
+	}
+}
+
+// Track records the markers of the scanned lines of an item while they are read in order.
+// A line is covered by a marker on itself or on the line right before it in the file, which
+// does not need to be scanned.
+type Track struct {
+	index  Index
+	source string
+	line   int
+	prev   *Marker
+}
+
+// NewTrack starts tracking the lines of the item. It returns nil, which tracks nothing, on a nil index.
+func (idx Index) NewTrack(source string) *Track {
+	if idx == nil {
+		return nil
+	}
+	return &Track{index: idx, source: source}
+}
+
+// Context reads a line of the file that is not scanned but can annotate the next scanned line.
+// An empty line breaks the adjacency, as is needed between two hunks.
+func (t *Track) Context(line string) {
+	if t == nil {
+		return
+	}
+	t.prev = nil
+	if marker, ok := Parse(line); ok {
+		t.prev = &marker
+	}
+}
+
+// Scanned reads the next scanned line of the item.
+func (t *Track) Scanned(line string) {
+	if t == nil {
+		return
+	}
+	if marker, ok := Parse(line); ok {
+		t.index.Add(t.source, t.line, marker)
+	}
+	if t.prev != nil {
+		t.index.Add(t.source, t.line, *t.prev)
+	}
+	t.Context(line)
+	t.line++
+}
+
+// Apply removes the findings suppressed by a marker from the report and returns how many were removed.
+func (idx Index) Apply(report *reporting.Report) int {
+	if len(idx) == 0 {
+		return 0
+	}
+	suppressed := 0
+	for id, list := range report.Results {
+		kept := list[:0]
+		for _, secret := range list {
+			if idx.suppresses(secret) {
+				suppressed++
+				continue
+			}
+			kept = append(kept, secret)
+		}
+		if len(kept) == 0 {
+			delete(report.Results, id)
+		} else {
+			report.Results[id] = kept
+		}
+	}
+	report.TotalSecretsFound -= suppressed
+	return suppressed
+}
+
+func (idx Index) suppresses(secret *secrets.Secret) bool {
+	return idx.Allows(secret.Source, secret.StartLine, secret.RuleID)
+}
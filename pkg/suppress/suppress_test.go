@@ -0,0 +1,108 @@
+package suppress
+
+import (
+	"testing"
+
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected Marker
+		found    bool
+	}{
+		{"no marker", `token = "abc"`, Marker{}, false},
+		{"ignore marker", `token = "abc" # cx:ignore-secret`, Marker{}, true},
+		{"allow marker", `// cx-secret-allow`, Marker{}, true},
+		{"allow rule", `// cx-secret-allow rule=github-pat`, Marker{Rule: "github-pat"}, true},
+		{"allow rule and reason", `// cx-secret-allow rule=github-pat reason=test fixture token` + "\n", Marker{Rule: "github-pat", Reason: "test fixture token"}, true},
+		{"quoted reason in block comment", `/* cx-secret-allow rule="jwt" reason="sample JWT" */`, Marker{Rule: "jwt", Reason: "sample JWT"}, true},
+		{"html comment", `<!-- cx-secret-allow reason=docs example -->`, Marker{Reason: "docs example"}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			marker, found := Parse(tc.line)
+			assert.Equal(t, tc.found, found)
+			assert.Equal(t, tc.expected, marker)
+		})
+	}
+}
+
+func TestMarkerAllows(t *testing.T) {
+	assert.True(t, Marker{}.Allows("github-pat"))
+	assert.True(t, Marker{Rule: "GitHub-PAT"}.Allows("github-pat"))
+	assert.False(t, Marker{Rule: "jwt"}.Allows("github-pat"))
+}
+
+func TestTrack(t *testing.T) {
+	index := Index{}
+	track := index.NewTrack("file.go")
+	track.Context("// cx:ignore-secret")
+	track.Scanned("secret on line 0\n")
+	track.Scanned("secret on line 1\n")
+	track.Scanned("secret on line 2 // cx-secret-allow rule=jwt\n")
+	track.Scanned("secret on line 3\n")
+	track.Context("")
+	track.Scanned("secret on line 4\n")
+
+	track.Scanned("secret on line 5 // cx-secret-allow rule=github-pat\n")
+	track.Scanned("secret on line 6 // cx-secret-allow rule=jwt\n")
+
+	assert.Equal(t, map[int][]Marker{
+		0: {{}},
+		2: {{Rule: "jwt"}},
+		3: {{Rule: "jwt"}},
+		5: {{Rule: "github-pat"}},
+		6: {{Rule: "jwt"}, {Rule: "github-pat"}},
+	}, index["file.go"], "the marker of the line before is kept with the one of the line")
+	assert.True(t, index.Allows("file.go", 6, "github-pat"))
+	assert.True(t, index.Allows("file.go", 6, "jwt"))
+	assert.False(t, index.Allows("file.go", 6, "aws"))
+	assert.False(t, index.Allows("file.go", 4, "aws"))
+
+	var disabled Index
+	disabled.NewTrack("file.go").Scanned("// cx:ignore-secret")
+	assert.Nil(t, disabled)
+}
+
+func TestIndexApply(t *testing.T) {
+	report := &reporting.Report{
+		TotalSecretsFound: 4,
+		Results: map[string][]*secrets.Secret{
+			"a": {{ID: "a", Source: "file.go", RuleID: "github-pat", StartLine: 0}},
+			"b": {{ID: "b", Source: "file.go", RuleID: "github-pat", StartLine: 2}},
+			"c": {{ID: "c", Source: "file.go", RuleID: "jwt", StartLine: 2}},
+			"d": {{ID: "d", Source: "other.go", RuleID: "jwt", StartLine: 0}},
+		},
+	}
+	index := Index{}
+	index.Add("file.go", 0, Marker{})
+	index.Add("file.go", 2, Marker{Rule: "jwt"})
+
+	assert.Equal(t, 2, index.Apply(report))
+	assert.Equal(t, 2, report.TotalSecretsFound)
+	assert.Len(t, report.Results, 2)
+	assert.Contains(t, report.Results, "b")
+	assert.Contains(t, report.Results, "d")
+
+	assert.Equal(t, 0, Index(nil).Apply(report))
+}
+
+func TestIndexMerge(t *testing.T) {
+	index := Index{}
+	index.Add("a.go", 1, Marker{})
+	other := Index{}
+	other.Add("b.go", 3, Marker{Rule: "jwt"})
+
+	index.Merge(other)
+	index.Merge(nil)
+	assert.Equal(t, Index{"a.go": {1: {{}}}, "b.go": {3: {{Rule: "jwt"}}}}, index)
+
+	var disabled Index
+	disabled.Merge(nil)
+	assert.Nil(t, disabled)
+}
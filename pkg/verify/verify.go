@@ -0,0 +1,314 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	"gopkg.in/yaml.v2"
+)
+
+// Status is the validity of a detected secret.
+type Status string
+
+const (
+	Live    Status = "live"
+	Revoked Status = "revoked"
+	Unknown Status = "unknown"
+)
+
+// String returns the display name of the status.
+func (s Status) String() string {
+	switch s {
+	case Live:
+		return "Confirmed live"
+	case Revoked:
+		return "Revoked"
+	case Unknown:
+		return "Unknown"
+	default:
+		return string(s)
+	}
+}
+
+const (
+	// secretPlaceholder is replaced with the secret value in the URL, headers and body of a check.
+	secretPlaceholder     = "{{secret}}"
+	defaultTimeoutSeconds = 5
+	maxConcurrentChecks   = 8
+	// userConfigDir and userConfigFile locate the validity settings of the user in the user
+	// configuration directory.
+	userConfigDir  = "checkmarx"
+	userConfigFile = "validity.yaml"
+)
+
+var (
+	defaultLiveStatus    = []int{http.StatusOK}
+	defaultRevokedStatus = []int{http.StatusUnauthorized, http.StatusForbidden}
+)
+
+// Verifier checks whether a detected secret is still live.
+type Verifier interface {
+	Verify(ctx context.Context, secret *secrets.Secret) Status
+}
+
+// Check describes the HTTP request that tells whether a secret of a rule is live, and how to read its response.
+type Check struct {
+	RuleID        string            `yaml:"rule_id"`
+	Method        string            `yaml:"method"`
+	BaseURL       string            `yaml:"base_url"`
+	Path          string            `yaml:"path"`
+	Headers       map[string]string `yaml:"headers"`
+	Body          string            `yaml:"body"`
+	LiveStatus    []int             `yaml:"live_status"`
+	RevokedStatus []int             `yaml:"revoked_status"`
+	// AllowHTTP lets the base_url use plain http, which sends the secret unencrypted; it is meant for
+	// verifiers on the local host or network only.
+	AllowHTTP bool `yaml:"allow_http"`
+}
+
+// Config configures secret validity checking for a hook.
+type Config struct {
+	Enabled        bool    `yaml:"enabled"`
+	TimeoutSeconds int     `yaml:"timeout_seconds"`
+	Checks         []Check `yaml:"checks"`
+	// BlockOnlyLive blocks only confirmed-live secrets, unless one of the updated refs is protected.
+	BlockOnlyLive bool     `yaml:"block_only_live"`
+	ProtectedRefs []string `yaml:"protected_refs"`
+}
+
+// Validate checks the configuration and fills in the defaults of the unset values.
+func (c *Config) Validate() error {
+	if c.TimeoutSeconds < 0 {
+		return fmt.Errorf("validity timeout_seconds must not be negative")
+	}
+	if c.TimeoutSeconds == 0 {
+		c.TimeoutSeconds = defaultTimeoutSeconds
+	}
+	for _, pattern := range c.ProtectedRefs {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid protected ref pattern %q: %w", pattern, err)
+		}
+	}
+
+	seen := make(map[string]struct{}, len(c.Checks))
+	for i := range c.Checks {
+		check := &c.Checks[i]
+		if check.RuleID == "" {
+			return fmt.Errorf("validity check #%d has no rule_id", i+1)
+		}
+		if _, exists := seen[check.RuleID]; exists {
+			return fmt.Errorf("validity check for rule %s is defined more than once", check.RuleID)
+		}
+		seen[check.RuleID] = struct{}{}
+
+		baseURL, err := url.Parse(check.BaseURL)
+		if err != nil || baseURL.Scheme == "" || baseURL.Host == "" {
+			return fmt.Errorf("validity check for rule %s has an invalid base_url %q", check.RuleID, check.BaseURL)
+		}
+		if baseURL.Scheme != "https" && !(baseURL.Scheme == "http" && check.AllowHTTP) {
+			return fmt.Errorf("validity check for rule %s must use an https base_url, or set allow_http to send the secret unencrypted", check.RuleID)
+		}
+		if check.Method == "" {
+			check.Method = http.MethodGet
+		}
+		check.Method = strings.ToUpper(check.Method)
+		if len(check.LiveStatus) == 0 {
+			check.LiveStatus = defaultLiveStatus
+		}
+		if len(check.RevokedStatus) == 0 {
+			check.RevokedStatus = defaultRevokedStatus
+		}
+	}
+	return nil
+}
+
+// UserConfigPath returns the path of the file holding the validity settings of the user:
+// checkmarx/validity.yaml in the user configuration directory, as $XDG_CONFIG_HOME or ~/.config on Linux.
+func UserConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, userConfigDir, userConfigFile), nil
+}
+
+// LoadUserConfig reads the "validity" key of the file at UserConfigPath. The hooks that read the
+// configuration of the repository take their validity settings from there only: a check sends the
+// secret to its base_url, which a cloned repository must not choose. A missing file disables the checks.
+func LoadUserConfig() (Config, error) {
+	configPath, err := UserConfigPath()
+	if err != nil {
+		return Config{}, nil
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Config{}, nil
+		}
+		return Config{}, fmt.Errorf("failed to read %s: %w", configPath, err)
+	}
+	var file struct {
+		Validity Config `yaml:"validity"`
+	}
+	if err = yaml.Unmarshal(data, &file); err != nil {
+		return Config{}, fmt.Errorf("configuration file at %s is misconfigured", configPath)
+	}
+	if err = file.Validity.Validate(); err != nil {
+		return Config{}, fmt.Errorf("configuration file at %s is misconfigured: %w", configPath, err)
+	}
+	return file.Validity, nil
+}
+
+// HTTPVerifier verifies secrets by sending the HTTP request configured for their rule.
+type HTTPVerifier struct {
+	client *http.Client
+	checks map[string]Check
+}
+
+// NewHTTPVerifier creates a verifier for a validated configuration. Redirects are not followed, so
+// that the secret and the headers of a check are only sent to its base_url; a redirect response is
+// read as any other status.
+func NewHTTPVerifier(config Config) *HTTPVerifier {
+	checks := make(map[string]Check, len(config.Checks))
+	for _, check := range config.Checks {
+		checks[check.RuleID] = check
+	}
+	return &HTTPVerifier{
+		client: &http.Client{
+			Timeout: time.Duration(config.TimeoutSeconds) * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		checks: checks,
+	}
+}
+
+// Verify sends the check of the secret's rule. Secrets without a check, and checks that fail or
+// return an unexpected status, are reported as Unknown.
+func (v *HTTPVerifier) Verify(ctx context.Context, secret *secrets.Secret) Status {
+	check, ok := v.checks[secret.RuleID]
+	if !ok {
+		return Unknown
+	}
+
+	target := strings.TrimRight(check.BaseURL, "/") + strings.ReplaceAll(check.Path, secretPlaceholder, url.PathEscape(secret.Value))
+	var body io.Reader
+	if check.Body != "" {
+		body = strings.NewReader(strings.ReplaceAll(check.Body, secretPlaceholder, secret.Value))
+	}
+	req, err := http.NewRequestWithContext(ctx, check.Method, target, body)
+	if err != nil {
+		return Unknown
+	}
+	for name, value := range check.Headers {
+		req.Header.Set(name, strings.ReplaceAll(value, secretPlaceholder, secret.Value))
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return Unknown
+	}
+	defer resp.Body.Close()               // nolint:errcheck
+	_, _ = io.Copy(io.Discard, resp.Body) // drain so the connection can be reused
+
+	switch {
+	case containsStatus(check.LiveStatus, resp.StatusCode):
+		return Live
+	case containsStatus(check.RevokedStatus, resp.StatusCode):
+		return Revoked
+	default:
+		return Unknown
+	}
+}
+
+// Apply verifies every finding of the report and records the result in its ValidationStatus.
+func Apply(ctx context.Context, verifier Verifier, report *reporting.Report) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentChecks)
+	for _, list := range report.Results {
+		for _, secret := range list {
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(secret *secrets.Secret) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				secret.ValidationStatus = toValidationResult(verifier.Verify(ctx, secret))
+			}(secret)
+		}
+	}
+	wg.Wait()
+}
+
+// Decide applies the BlockOnlyLive policy to the decision taken from the severity thresholds.
+// A block is turned into a warning when no updated ref is protected and no finding is confirmed live.
+func (c Config) Decide(decision severity.Decision, report *reporting.Report, refNames []string) severity.Decision {
+	if !c.Enabled || !c.BlockOnlyLive || decision != severity.Block || c.isProtected(refNames) {
+		return decision
+	}
+	for _, list := range report.Results {
+		for _, secret := range list {
+			if secret.ValidationStatus == secrets.ValidResult {
+				return decision
+			}
+		}
+	}
+	return severity.Warn
+}
+
+func (c Config) isProtected(refNames []string) bool {
+	for _, ref := range refNames {
+		for _, pattern := range c.ProtectedRefs {
+			if matched, _ := path.Match(pattern, ref); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FromValidationResult returns the validity recorded in a 2ms validation status, or "" when none is.
+func FromValidationResult(result secrets.ValidationResult) Status {
+	switch result {
+	case secrets.ValidResult:
+		return Live
+	case secrets.InvalidResult:
+		return Revoked
+	case secrets.UnknownResult:
+		return Unknown
+	default:
+		return ""
+	}
+}
+
+func toValidationResult(status Status) secrets.ValidationResult {
+	switch status {
+	case Live:
+		return secrets.ValidResult
+	case Revoked:
+		return secrets.InvalidResult
+	default:
+		return secrets.UnknownResult
+	}
+}
+
+func containsStatus(list []int, status int) bool {
+	for _, item := range list {
+		if item == status {
+			return true
+		}
+	}
+	return false
+}
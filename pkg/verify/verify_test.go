@@ -0,0 +1,203 @@
+package verify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/Checkmarx/secret-detection/pkg/severity"
+	"github.com/checkmarx/2ms/v3/lib/reporting"
+	"github.com/checkmarx/2ms/v3/lib/secrets"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStubServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "Bearer live-token":
+			w.WriteHeader(http.StatusOK)
+		case "Bearer revoked-token":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newVerifier(t *testing.T, baseURL string) *HTTPVerifier {
+	cfg := Config{
+		Enabled: true,
+		Checks: []Check{{
+			RuleID:    "acme-token",
+			BaseURL:   baseURL,
+			Path:      "/v1/whoami",
+			Headers:   map[string]string{"Authorization": "Bearer {{secret}}"},
+			AllowHTTP: true,
+		}},
+	}
+	assert.NoError(t, cfg.Validate())
+	return NewHTTPVerifier(cfg)
+}
+
+func TestHTTPVerifierVerify(t *testing.T) {
+	server := newStubServer(t)
+	verifier := newVerifier(t, server.URL)
+
+	tests := []struct {
+		name     string
+		secret   secrets.Secret
+		expected Status
+	}{
+		{"live", secrets.Secret{RuleID: "acme-token", Value: "live-token"}, Live},
+		{"revoked", secrets.Secret{RuleID: "acme-token", Value: "revoked-token"}, Revoked},
+		{"unexpected status", secrets.Secret{RuleID: "acme-token", Value: "other"}, Unknown},
+		{"no check for rule", secrets.Secret{RuleID: "github-pat", Value: "live-token"}, Unknown},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, verifier.Verify(context.Background(), &tc.secret))
+		})
+	}
+
+	t.Run("unreachable server", func(t *testing.T) {
+		unreachable := newVerifier(t, "http://127.0.0.1:1")
+		assert.Equal(t, Unknown, unreachable.Verify(context.Background(), &secrets.Secret{RuleID: "acme-token", Value: "live-token"}))
+	})
+}
+
+func TestHTTPVerifierEscapesPath(t *testing.T) {
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.EscapedPath()
+	}))
+	t.Cleanup(server.Close)
+	cfg := Config{Checks: []Check{{RuleID: "acme-token", BaseURL: server.URL, Path: "/v1/tokens/{{secret}}", AllowHTTP: true}}}
+	assert.NoError(t, cfg.Validate())
+
+	assert.Equal(t, Live, NewHTTPVerifier(cfg).Verify(context.Background(), &secrets.Secret{RuleID: "acme-token", Value: "a b/c+d"}))
+	assert.Equal(t, "/v1/tokens/a%20b%2Fc+d", path, "the secret stays a single path segment")
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"empty", Config{}, false},
+		{"valid check", Config{Checks: []Check{{RuleID: "acme-token", BaseURL: "https://api.acme.test"}}}, false},
+		{"missing rule id", Config{Checks: []Check{{BaseURL: "https://api.acme.test"}}}, true},
+		{"invalid base url", Config{Checks: []Check{{RuleID: "acme-token", BaseURL: "api.acme.test"}}}, true},
+		{"http base url", Config{Checks: []Check{{RuleID: "acme-token", BaseURL: "http://api.acme.test"}}}, true},
+		{"http base url allowed", Config{Checks: []Check{{RuleID: "acme-token", BaseURL: "http://localhost:8080", AllowHTTP: true}}}, false},
+		{"other scheme", Config{Checks: []Check{{RuleID: "acme-token", BaseURL: "ftp://api.acme.test", AllowHTTP: true}}}, true},
+		{"duplicate check", Config{Checks: []Check{{RuleID: "a", BaseURL: "https://x.test"}, {RuleID: "a", BaseURL: "https://y.test"}}}, true},
+		{"negative timeout", Config{TimeoutSeconds: -1}, true},
+		{"invalid protected ref", Config{ProtectedRefs: []string{"refs/heads/["}}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.config.Validate()
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	cfg := Config{Checks: []Check{{RuleID: "acme-token", BaseURL: "https://api.acme.test", Method: "post"}}}
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, defaultTimeoutSeconds, cfg.TimeoutSeconds)
+	assert.Equal(t, http.MethodPost, cfg.Checks[0].Method)
+	assert.Equal(t, defaultLiveStatus, cfg.Checks[0].LiveStatus)
+	assert.Equal(t, defaultRevokedStatus, cfg.Checks[0].RevokedStatus)
+}
+
+func TestApply(t *testing.T) {
+	server := newStubServer(t)
+	report := &reporting.Report{Results: map[string][]*secrets.Secret{
+		"1": {{ID: "1", RuleID: "acme-token", Value: "live-token"}},
+		"2": {{ID: "2", RuleID: "acme-token", Value: "revoked-token"}},
+		"3": {{ID: "3", RuleID: "github-pat", Value: "ghp_x"}},
+	}}
+
+	Apply(context.Background(), newVerifier(t, server.URL), report)
+	assert.Equal(t, secrets.ValidResult, report.Results["1"][0].ValidationStatus)
+	assert.Equal(t, secrets.InvalidResult, report.Results["2"][0].ValidationStatus)
+	assert.Equal(t, secrets.UnknownResult, report.Results["3"][0].ValidationStatus)
+}
+
+func TestConfigDecide(t *testing.T) {
+	withStatus := func(status secrets.ValidationResult) *reporting.Report {
+		return &reporting.Report{Results: map[string][]*secrets.Secret{"1": {{ID: "1", ValidationStatus: status}}}}
+	}
+	policy := Config{Enabled: true, BlockOnlyLive: true, ProtectedRefs: []string{"refs/heads/main", "refs/heads/release/*"}}
+
+	tests := []struct {
+		name     string
+		config   Config
+		decision severity.Decision
+		report   *reporting.Report
+		refs     []string
+		expected severity.Decision
+	}{
+		{"live secret blocks", policy, severity.Block, withStatus(secrets.ValidResult), []string{"refs/heads/feature"}, severity.Block},
+		{"unverified secret warns", policy, severity.Block, withStatus(secrets.UnknownResult), []string{"refs/heads/feature"}, severity.Warn},
+		{"protected ref blocks", policy, severity.Block, withStatus(secrets.InvalidResult), []string{"refs/heads/release/1.2"}, severity.Block},
+		{"pass stays pass", policy, severity.Pass, withStatus(secrets.ValidResult), nil, severity.Pass},
+		{"policy disabled", Config{Enabled: true}, severity.Block, withStatus(secrets.InvalidResult), nil, severity.Block},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.config.Decide(tc.decision, tc.report, tc.refs))
+		})
+	}
+}
+
+func TestHTTPVerifierRedirect(t *testing.T) {
+	var forwarded atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		forwarded.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(target.Close)
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	t.Cleanup(redirect.Close)
+
+	status := newVerifier(t, redirect.URL).Verify(context.Background(), &secrets.Secret{RuleID: "acme-token", Value: "live-token"})
+	assert.Equal(t, Unknown, status)
+	assert.Zero(t, forwarded.Load(), "the secret must not be forwarded to the redirect target")
+}
+
+func TestLoadUserConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	configPath, err := UserConfigPath()
+	assert.NoError(t, err)
+
+	cfg, err := LoadUserConfig()
+	assert.NoError(t, err)
+	assert.False(t, cfg.Enabled, "a missing file disables the checks")
+
+	assert.NoError(t, os.MkdirAll(filepath.Dir(configPath), 0o755))
+	content := "validity:\n  enabled: true\n  checks:\n    - rule_id: acme-token\n      base_url: https://api.acme.test\n"
+	assert.NoError(t, os.WriteFile(configPath, []byte(content), 0o644))
+	cfg, err = LoadUserConfig()
+	assert.NoError(t, err)
+	assert.True(t, cfg.Enabled)
+	assert.Equal(t, defaultTimeoutSeconds, cfg.TimeoutSeconds)
+	if assert.Len(t, cfg.Checks, 1) {
+		assert.Equal(t, http.MethodGet, cfg.Checks[0].Method)
+	}
+
+	assert.NoError(t, os.WriteFile(configPath, []byte("validity:\n  checks:\n    - base_url: https://api.acme.test\n"), 0o644))
+	_, err = LoadUserConfig()
+	assert.ErrorContains(t, err, "no rule_id")
+}